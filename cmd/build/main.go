@@ -1,6 +1,8 @@
 package main
 
 import (
+	"bytes"
+	"database/sql"
 	"encoding/json"
 	"errors"
 	"flag"
@@ -13,8 +15,11 @@ import (
 
 	"github.com/benfb/oaamonitor/config"
 	"github.com/benfb/oaamonitor/database"
+	"github.com/benfb/oaamonitor/export"
 	"github.com/benfb/oaamonitor/models"
 	"github.com/benfb/oaamonitor/site"
+	"github.com/benfb/oaamonitor/storage"
+	"github.com/benfb/oaamonitor/teams"
 )
 
 type buildConfig struct {
@@ -106,21 +111,10 @@ func validateOutputDir(path string) error {
 	return nil
 }
 
-func buildSearchIndex(outputDir string, players []models.Player) error {
-	type searchEntry struct {
-		ID   int    `json:"id"`
-		Name string `json:"name"`
-	}
-
-	entries := make([]searchEntry, 0, len(players))
-	for _, player := range players {
-		entries = append(entries, searchEntry{
-			ID:   player.ID,
-			Name: player.Name,
-		})
-	}
-
-	data, err := json.MarshalIndent(entries, "", "  ")
+// buildSearchIndex writes every player's summary, in rank order, for the
+// client-side search.
+func buildSearchIndex(outputDir string, players []models.PlayerSummary) error {
+	data, err := json.MarshalIndent(players, "", "  ")
 	if err != nil {
 		return err
 	}
@@ -132,6 +126,36 @@ func buildSearchIndex(outputDir string, players []models.Player) error {
 	return os.WriteFile(target, data, 0o644)
 }
 
+// buildExports writes every season's snapshots to downloads/oaa-<season> in
+// each export format.
+func buildExports(db *sql.DB, downloadDir string) error {
+	seasons, err := models.FetchSeasons(db)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(downloadDir, 0o755); err != nil {
+		return err
+	}
+
+	for _, season := range seasons {
+		stats, err := models.FetchFilteredStats(db, models.StatFilter{Season: season})
+		if err != nil {
+			return fmt.Errorf("season %d: %v", season, err)
+		}
+		for _, format := range export.Formats {
+			var buf bytes.Buffer
+			if err := export.Write(&buf, format, stats); err != nil {
+				return fmt.Errorf("season %d: %v", season, err)
+			}
+			target := filepath.Join(downloadDir, fmt.Sprintf("oaa-%d.%s", season, format.Extension()))
+			if err := os.WriteFile(target, buf.Bytes(), 0o644); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 func main() {
 	cfgFlags := parseFlags()
 
@@ -166,8 +190,14 @@ func main() {
 		log.Fatalf("failed to open database: %v", err)
 	}
 
-	if err := database.Checkpoint(db.DB); err != nil {
-		log.Printf("warning: unable to checkpoint WAL file: %v", err)
+	// A restored snapshot can predate the current schema, so bring it up to
+	// date before reading it. Databases newer than this binary are refused.
+	registry, err := teams.Load(cfg.TeamsFile)
+	if err != nil {
+		log.Fatalf("failed to load team registry: %v", err)
+	}
+	if err := database.EnsureSchema(db.DB, registry); err != nil {
+		log.Fatalf("refusing to build from %s: %v", cfg.DatabasePath, err)
 	}
 
 	siteBuilder, err := site.NewBuilder(db, cfg)
@@ -180,8 +210,21 @@ func main() {
 		log.Fatalf("failed to fetch players: %v", err)
 	}
 
+	// Publish the downloads first so the index can show their sizes.
+	downloadDir := filepath.Join(cfgFlags.outputDir, "downloads")
+	if err := buildExports(db.DB, downloadDir); err != nil {
+		log.Fatalf("failed to build exports: %v", err)
+	}
+	rawDatabase := filepath.Join(downloadDir, "oaamonitor.db")
+	if err := database.Backup(db.DB, rawDatabase); err != nil {
+		log.Fatalf("failed to back up database: %v", err)
+	}
+	if _, err := storage.CompressFile(rawDatabase, rawDatabase+".gz", storage.CompressionGzip); err != nil {
+		log.Fatalf("failed to compress database: %v", err)
+	}
+
 	// Render index page
-	indexHTML, err := siteBuilder.RenderIndex(players)
+	indexHTML, err := siteBuilder.RenderIndex(players, downloadDir)
 	if err != nil {
 		log.Fatalf("failed to render index: %v", err)
 	}
@@ -220,17 +263,16 @@ func main() {
 		log.Fatalf("failed to copy static assets: %v", err)
 	}
 
-	if err := db.Close(); err != nil {
-		log.Fatalf("failed to close database: %v", err)
+	summaries, err := models.FetchPlayerSummaries(db.DB)
+	if err != nil {
+		log.Fatalf("failed to fetch player summaries: %v", err)
 	}
-
-	downloadDir := filepath.Join(cfgFlags.outputDir, "downloads")
-	if err := copyFile(cfg.DatabasePath, filepath.Join(downloadDir, "oaamonitor.db")); err != nil {
-		log.Fatalf("failed to copy database file: %v", err)
+	if err := buildSearchIndex(cfgFlags.outputDir, summaries); err != nil {
+		log.Fatalf("failed to build search index: %v", err)
 	}
 
-	if err := buildSearchIndex(cfgFlags.outputDir, players); err != nil {
-		log.Fatalf("failed to build search index: %v", err)
+	if err := db.Close(); err != nil {
+		log.Fatalf("failed to close database: %v", err)
 	}
 
 	log.Println("Static site successfully generated in", cfgFlags.outputDir)
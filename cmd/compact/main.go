@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/benfb/oaamonitor/config"
+	"github.com/benfb/oaamonitor/database"
+	"github.com/benfb/oaamonitor/teams"
+)
+
+func main() {
+	databasePath := flag.String("database", "", "path to the SQLite database file (defaults to DATABASE_PATH env or ./data/oaamonitor.db)")
+	vacuum := flag.Bool("vacuum", true, "rewrite the database file afterwards so the freed space is returned to the filesystem")
+	flag.Parse()
+
+	cfg := config.NewConfig()
+	if *databasePath != "" {
+		cfg.DatabasePath = *databasePath
+	}
+
+	if _, err := os.Stat(cfg.DatabasePath); err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
+	sizeBefore, _ := database.GetDatabaseSize(cfg.DatabasePath)
+
+	db, err := database.New(cfg.DatabasePath)
+	if err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	registry, err := teams.Load(cfg.TeamsFile)
+	if err != nil {
+		log.Fatalf("Failed to load team registry: %v", err)
+	}
+	if err := database.EnsureSchema(db.DB, registry); err != nil {
+		log.Fatalf("Refusing to compact %s: %v", cfg.DatabasePath, err)
+	}
+
+	result, err := database.Compact(db.DB)
+	if err != nil {
+		log.Fatalf("Failed to compact %s: %v", cfg.DatabasePath, err)
+	}
+	log.Printf("Compacted %d snapshot rows into %d", result.RowsBefore, result.RowsAfter)
+
+	if *vacuum {
+		if err := database.Checkpoint(db.DB); err != nil {
+			log.Fatalf("Failed to checkpoint %s: %v", cfg.DatabasePath, err)
+		}
+		if _, err := db.Exec("VACUUM"); err != nil {
+			log.Fatalf("Failed to vacuum %s: %v", cfg.DatabasePath, err)
+		}
+		if err := database.Checkpoint(db.DB); err != nil {
+			log.Fatalf("Failed to checkpoint %s: %v", cfg.DatabasePath, err)
+		}
+		sizeAfter, _ := database.GetDatabaseSize(cfg.DatabasePath)
+		log.Printf("Database size: %s -> %s", sizeBefore, sizeAfter)
+	}
+}
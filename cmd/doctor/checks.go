@@ -0,0 +1,384 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/benfb/oaamonitor/database"
+	"github.com/benfb/oaamonitor/teams"
+)
+
+type status string
+
+const (
+	statusOK      status = "ok"
+	statusWarning status = "warning"
+	statusError   status = "error"
+	statusSkipped status = "skipped"
+)
+
+// maxFindings caps how many findings a check lists before summarizing the rest.
+const maxFindings = 25
+
+type check struct {
+	Name     string   `json:"name"`
+	Status   status   `json:"status"`
+	Summary  string   `json:"summary"`
+	Findings []string `json:"findings,omitempty"`
+}
+
+type report struct {
+	Database      string  `json:"database"`
+	SchemaVersion int     `json:"schema_version"`
+	Checks        []check `json:"checks"`
+	Errors        int     `json:"errors"`
+	Warnings      int     `json:"warnings"`
+}
+
+func (r *report) add(c check) {
+	switch c.Status {
+	case statusError:
+		r.Errors++
+	case statusWarning:
+		r.Warnings++
+	}
+	r.Checks = append(r.Checks, c)
+}
+
+// findings collects problems for one check and decides its status.
+type findings struct {
+	severity status
+	items    []string
+	total    int
+}
+
+func (f *findings) addf(format string, args ...any) {
+	f.total++
+	if len(f.items) < maxFindings {
+		f.items = append(f.items, fmt.Sprintf(format, args...))
+	}
+}
+
+func (f *findings) check(name, passed string) check {
+	if f.total == 0 {
+		return check{Name: name, Status: statusOK, Summary: passed}
+	}
+	items := f.items
+	if f.total > len(items) {
+		items = append(items, fmt.Sprintf("... and %d more", f.total-len(items)))
+	}
+	return check{Name: name, Status: f.severity, Summary: fmt.Sprintf("%d found", f.total), Findings: items}
+}
+
+// runChecks inspects a database without modifying it. Data checks need the
+// current schema, so they are skipped when the schema check fails.
+func runChecks(db *sql.DB, registry *teams.Registry, now time.Time) (report, error) {
+	var r report
+
+	integrity, err := checkIntegrity(db)
+	if err != nil {
+		return r, err
+	}
+	r.add(integrity)
+
+	schema, version, err := checkSchema(db)
+	if err != nil {
+		return r, err
+	}
+	r.SchemaVersion = version
+	r.add(schema)
+
+	dataChecks := []struct {
+		name string
+		run  func(*sql.DB) (check, error)
+	}{
+		{"snapshot_gaps", checkSnapshotGaps},
+		{"player_names", checkPlayerNames},
+		{"players", checkPlayers},
+		{"dates", func(db *sql.DB) (check, error) { return checkDates(db, now) }},
+		{"success_rates", checkSuccessRates},
+		{"teams", func(db *sql.DB) (check, error) { return checkTeams(db, registry) }},
+	}
+	for _, dc := range dataChecks {
+		if schema.Status != statusOK {
+			r.add(check{Name: dc.name, Status: statusSkipped, Summary: "schema is not current"})
+			continue
+		}
+		c, err := dc.run(db)
+		if err != nil {
+			return r, fmt.Errorf("%s check failed: %v", dc.name, err)
+		}
+		r.add(c)
+	}
+	return r, nil
+}
+
+func checkIntegrity(db *sql.DB) (check, error) {
+	rows, err := db.Query(`PRAGMA integrity_check`)
+	if err != nil {
+		return check{}, err
+	}
+	defer rows.Close()
+
+	f := findings{severity: statusError}
+	for rows.Next() {
+		var message string
+		if err := rows.Scan(&message); err != nil {
+			return check{}, err
+		}
+		if message != "ok" {
+			f.addf("%s", message)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return check{}, err
+	}
+	return f.check("integrity", "PRAGMA integrity_check passed"), nil
+}
+
+func checkSchema(db *sql.DB) (check, int, error) {
+	version, err := database.SchemaVersion(db)
+	if err != nil {
+		return check{}, 0, err
+	}
+	migrations, err := database.Migrations()
+	if err != nil {
+		return check{}, 0, err
+	}
+	latest := migrations[len(migrations)-1].Version
+
+	c := check{Name: "schema", Status: statusOK, Summary: fmt.Sprintf("version %d of %d", version, latest)}
+	switch {
+	case version > latest:
+		c.Status = statusError
+		c.Findings = []string{fmt.Sprintf("database is at version %d, newer than this binary's %d", version, latest)}
+	case version < latest:
+		c.Status = statusError
+		c.Findings = []string{fmt.Sprintf("%d migrations pending; run `migrate up` before publishing", latest-version)}
+	}
+	return c, version, nil
+}
+
+// checkSnapshotGaps reports days without a snapshot between the first and last
+// snapshot of each season. Seasons backfilled as a single end-of-season
+// snapshot have no gaps.
+func checkSnapshotGaps(db *sql.DB) (check, error) {
+	rows, err := db.Query(`SELECT strftime('%Y-%m-%d', date) FROM snapshot_dates ORDER BY date`)
+	if err != nil {
+		return check{}, err
+	}
+	defer rows.Close()
+
+	f := findings{severity: statusWarning}
+	var previous time.Time
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return check{}, err
+		}
+		date, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return check{}, fmt.Errorf("invalid snapshot date %q", value)
+		}
+		if !previous.IsZero() && previous.Year() == date.Year() {
+			if missing := int(date.Sub(previous).Hours()/24) - 1; missing > 0 {
+				first, last := previous.AddDate(0, 0, 1), date.AddDate(0, 0, -1)
+				if missing == 1 {
+					f.addf("no snapshot on %s", first.Format("2006-01-02"))
+				} else {
+					f.addf("no snapshots from %s to %s (%d days)", first.Format("2006-01-02"), last.Format("2006-01-02"), missing)
+				}
+			}
+		}
+		previous = date
+	}
+	if err := rows.Err(); err != nil {
+		return check{}, err
+	}
+	return f.check("snapshot_gaps", "no missing days within a season"), nil
+}
+
+// checkPlayerNames looks for player IDs that the leaderboards have given more
+// than one name, using the history in player_names. The players table keeps
+// only the latest OAA name, so it cannot show a conflict on its own.
+func checkPlayerNames(db *sql.DB) (check, error) {
+	rows, err := db.Query(`
+		SELECT player_id, GROUP_CONCAT(full_name || ' (' || sources || ')', ' / ' ORDER BY first_seen, full_name)
+		FROM (
+			SELECT player_id, full_name, GROUP_CONCAT(source, ', ' ORDER BY source) AS sources, MIN(first_seen) AS first_seen
+			FROM player_names
+			GROUP BY player_id, full_name
+		)
+		GROUP BY player_id
+		HAVING COUNT(*) > 1
+		ORDER BY player_id`)
+	if err != nil {
+		return check{}, err
+	}
+	defer rows.Close()
+
+	f := findings{severity: statusWarning}
+	for rows.Next() {
+		var playerID int
+		var names string
+		if err := rows.Scan(&playerID, &names); err != nil {
+			return check{}, err
+		}
+		f.addf("player %d is named %s", playerID, names)
+	}
+	if err := rows.Err(); err != nil {
+		return check{}, err
+	}
+	return f.check("player_names", "one name per player ID"), nil
+}
+
+// playerTables are the tables whose rows reference the players table.
+var playerTables = []string{"outs_above_average", "player_team_stints", "fielding_run_value", "arm_strength", "catcher_framing", "outfield_jump"}
+
+// checkPlayers looks for rows whose player has no row in the players table.
+// SQLite enforces foreign keys only on connections that enable them, so a
+// database edited with other tools can hold rows that every join drops.
+func checkPlayers(db *sql.DB) (check, error) {
+	sources := make([]string, len(playerTables))
+	for i, table := range playerTables {
+		sources[i] = fmt.Sprintf(`SELECT player_id, '%s' AS source FROM %s`, table, table)
+	}
+
+	rows, err := db.Query(fmt.Sprintf(`
+		SELECT player_id, GROUP_CONCAT(DISTINCT source)
+		FROM (%s)
+		WHERE player_id NOT IN (SELECT id FROM players)
+		GROUP BY player_id
+		ORDER BY player_id`, strings.Join(sources, " UNION ALL ")))
+	if err != nil {
+		return check{}, err
+	}
+	defer rows.Close()
+
+	f := findings{severity: statusError}
+	for rows.Next() {
+		var playerID int
+		var tables string
+		if err := rows.Scan(&playerID, &tables); err != nil {
+			return check{}, err
+		}
+		f.addf("player %d in %s has no players row", playerID, tables)
+	}
+	if err := rows.Err(); err != nil {
+		return check{}, err
+	}
+	return f.check("players", "every player ID is in the players table"), nil
+}
+
+// checkDates looks for dates that run backwards or into the future: compacted
+// ranges ending before they start, ranges that overlap so a player has two rows
+// for one date, stints ending before they start, and snapshots after today.
+func checkDates(db *sql.DB, now time.Time) (check, error) {
+	f := findings{severity: statusError}
+	queries := []struct {
+		query  string
+		format string
+		args   []any
+	}{
+		{`SELECT player_id, strftime('%Y-%m-%d', date) || ' > ' || strftime('%Y-%m-%d', valid_to) FROM outs_above_average WHERE valid_to < date`,
+			"player %d has a snapshot range that ends before it starts (%s)", nil},
+		{`SELECT player_id, strftime('%Y-%m-%d', date) FROM oaa_snapshots GROUP BY player_id, date HAVING COUNT(*) > 1`,
+			"player %d has more than one snapshot on %s", nil},
+		{`SELECT player_id, strftime('%Y-%m-%d', start_date) || ' > ' || strftime('%Y-%m-%d', end_date) FROM player_team_stints WHERE end_date < start_date`,
+			"player %d has a team stint that ends before it starts (%s)", nil},
+		{`SELECT player_id, strftime('%Y-%m-%d', date) FROM outs_above_average WHERE date > ?1 OR valid_to > ?1`,
+			"player %d has a snapshot dated in the future (%s)", []any{now.Format("2006-01-02")}},
+	}
+	for _, q := range queries {
+		rows, err := db.Query(q.query, q.args...)
+		if err != nil {
+			return check{}, err
+		}
+		for rows.Next() {
+			var playerID int
+			var detail string
+			if err := rows.Scan(&playerID, &detail); err != nil {
+				rows.Close()
+				return check{}, err
+			}
+			f.addf(q.format, playerID, detail)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return check{}, err
+		}
+	}
+	return f.check("dates", "dates are ordered and none are in the future"), nil
+}
+
+// checkSuccessRates flags success rates no fielder can have: actual and
+// estimated rates outside 0-1 and differences outside -1 to 1.
+func checkSuccessRates(db *sql.DB) (check, error) {
+	rows, err := db.Query(`
+		SELECT player_id, strftime('%Y-%m-%d', date), actual_success_rate, estimated_success_rate, diff_success_rate
+		FROM outs_above_average
+		WHERE actual_success_rate NOT BETWEEN 0 AND 1
+			OR estimated_success_rate NOT BETWEEN 0 AND 1
+			OR diff_success_rate NOT BETWEEN -1 AND 1
+		ORDER BY player_id, date`)
+	if err != nil {
+		return check{}, err
+	}
+	defer rows.Close()
+
+	f := findings{severity: statusError}
+	for rows.Next() {
+		var playerID int
+		var date string
+		var actual, estimated, diff float64
+		if err := rows.Scan(&playerID, &date, &actual, &estimated, &diff); err != nil {
+			return check{}, err
+		}
+		f.addf("player %d on %s: actual %.3f, estimated %.3f, difference %.3f", playerID, date, actual, estimated, diff)
+	}
+	if err := rows.Err(); err != nil {
+		return check{}, err
+	}
+	return f.check("success_rates", "every success rate is plausible"), nil
+}
+
+// checkTeams reports team IDs referenced by the database that the team
+// registry does not know.
+func checkTeams(db *sql.DB, registry *teams.Registry) (check, error) {
+	rows, err := db.Query(`
+		SELECT id FROM teams
+		UNION
+		SELECT DISTINCT team_id FROM outs_above_average WHERE team_id IS NOT NULL
+		UNION
+		SELECT DISTINCT team_id FROM player_team_stints
+		UNION
+		SELECT DISTINCT team_id FROM fielding_run_value WHERE team_id IS NOT NULL
+		UNION
+		SELECT DISTINCT team_id FROM arm_strength WHERE team_id IS NOT NULL
+		UNION
+		SELECT DISTINCT team_id FROM catcher_framing WHERE team_id IS NOT NULL
+		UNION
+		SELECT DISTINCT team_id FROM outfield_jump WHERE team_id IS NOT NULL
+		ORDER BY 1`)
+	if err != nil {
+		return check{}, err
+	}
+	defer rows.Close()
+
+	f := findings{severity: statusError}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return check{}, err
+		}
+		if _, ok := registry.Current(id); !ok {
+			f.addf("team %d is not in the team registry", id)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return check{}, err
+	}
+	return f.check("teams", "every team is in the team registry"), nil
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/benfb/oaamonitor/database"
+	"github.com/benfb/oaamonitor/teams"
+)
+
+func setupDoctorDB(t *testing.T) *sql.DB {
+	db, err := database.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := database.Migrate(db, teams.Default()); err != nil {
+		t.Fatalf("Migrate returned error: %v", err)
+	}
+	if _, err := db.Exec(`
+		INSERT INTO players (id, first_name, last_name, full_name, updated_on) VALUES
+		(1, 'John', 'Doe', 'John Doe', '2025-06-04'),
+		(2, 'Jane', 'Smith', 'Jane Smith', '2025-06-04');
+		INSERT INTO snapshot_dates (date) VALUES ('2024-09-29'), ('2025-06-01'), ('2025-06-02'), ('2025-06-04');
+		INSERT INTO outs_above_average (player_id, team_id, primary_position, oaa, actual_success_rate, estimated_success_rate, diff_success_rate, date) VALUES
+		(1, 147, 'SS', 2, 0.8, 0.7, 0.1, '2024-09-29'),
+		(1, 147, 'SS', 2, 0.8, 0.7, 0.1, '2025-06-01'),
+		(2, 111, 'CF', 1, 0.7, 0.7, 0.0, '2025-06-01'),
+		(1, 147, 'SS', 3, 0.81, 0.7, 0.11, '2025-06-02'),
+		(2, 111, 'CF', 1, 0.7, 0.7, 0.0, '2025-06-02'),
+		(1, 147, 'SS', 3, 0.81, 0.7, 0.11, '2025-06-04'),
+		(2, 111, 'CF', 1, 0.7, 0.7, 0.0, '2025-06-04')`); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func checkByName(t *testing.T, r report, name string) check {
+	t.Helper()
+	for _, c := range r.Checks {
+		if c.Name == name {
+			return c
+		}
+	}
+	t.Fatalf("report has no %s check", name)
+	return check{}
+}
+
+func TestRunChecks_HealthyDatabase(t *testing.T) {
+	db := setupDoctorDB(t)
+
+	r, err := runChecks(db, teams.Default(), time.Date(2025, 6, 5, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("runChecks returned error: %v", err)
+	}
+	if r.Errors != 0 {
+		t.Errorf("expected no errors, got %+v", r.Checks)
+	}
+	// 2025-06-03 is missing; the offseason between seasons is not a gap.
+	gaps := checkByName(t, r, "snapshot_gaps")
+	if gaps.Status != statusWarning || len(gaps.Findings) != 1 || gaps.Findings[0] != "no snapshot on 2025-06-03" {
+		t.Errorf("unexpected snapshot_gaps check: %+v", gaps)
+	}
+}
+
+func TestRunChecks_ReportsProblems(t *testing.T) {
+	db := setupDoctorDB(t)
+	if _, err := db.Exec(`
+		PRAGMA foreign_keys = OFF;
+		INSERT INTO teams (id, name, abbreviation) VALUES (999, 'Expos', 'MON');
+		INSERT INTO fielding_run_value (player_id, team_id, date) VALUES (3, 998, '2025-06-04');
+		INSERT INTO player_names (player_id, full_name, source, first_seen) VALUES
+		(1, 'John Doe', 'outs_above_average', '2025-06-01'),
+		(1, 'Johnny Doe', 'fielding_run_value', '2025-06-04'),
+		(1, 'Johnny Doe', 'arm_strength', '2025-06-04'),
+		(2, 'Jane Smith', 'outs_above_average', '2025-06-01');
+		UPDATE outs_above_average SET actual_success_rate = 1.4 WHERE player_id = 2 AND date = '2025-06-02';
+		UPDATE outs_above_average SET team_id = 999 WHERE player_id = 2 AND date = '2025-06-04';
+		UPDATE outs_above_average SET valid_to = '2025-06-01' WHERE player_id = 1 AND date = '2025-06-04'`); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := runChecks(db, teams.Default(), time.Date(2025, 6, 5, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("runChecks returned error: %v", err)
+	}
+
+	want := map[string]status{
+		"integrity":     statusOK,
+		"schema":        statusOK,
+		"player_names":  statusWarning,
+		"players":       statusError,
+		"dates":         statusError,
+		"success_rates": statusError,
+		"teams":         statusError,
+	}
+	for name, status := range want {
+		if c := checkByName(t, r, name); c.Status != status {
+			t.Errorf("%s check: expected %s, got %s (%v)", name, status, c.Status, c.Findings)
+		}
+	}
+	if r.Errors != 4 {
+		t.Errorf("expected 4 errors, got %d", r.Errors)
+	}
+	names := checkByName(t, r, "player_names")
+	if len(names.Findings) != 1 || names.Findings[0] != "player 1 is named John Doe (outs_above_average) / Johnny Doe (arm_strength, fielding_run_value)" {
+		t.Errorf("unexpected player_names findings %q", names.Findings)
+	}
+	if teamsCheck := checkByName(t, r, "teams"); len(teamsCheck.Findings) != 2 {
+		t.Errorf("expected the Expos and the metric row's team 998 to be reported, got %q", teamsCheck.Findings)
+	}
+}
+
+func TestRunChecks_SkipsDataChecksOnStaleSchema(t *testing.T) {
+	db := setupDoctorDB(t)
+	if _, err := db.Exec(`DELETE FROM schema_migrations WHERE version = (SELECT MAX(version) FROM schema_migrations)`); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := runChecks(db, teams.Default(), time.Now())
+	if err != nil {
+		t.Fatalf("runChecks returned error: %v", err)
+	}
+	if c := checkByName(t, r, "schema"); c.Status != statusError {
+		t.Errorf("expected schema error, got %+v", c)
+	}
+	if c := checkByName(t, r, "teams"); c.Status != statusSkipped {
+		t.Errorf("expected teams check to be skipped, got %+v", c)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/benfb/oaamonitor/config"
+	"github.com/benfb/oaamonitor/database"
+	"github.com/benfb/oaamonitor/teams"
+)
+
+func main() {
+	databasePath := flag.String("database", "", "path to the SQLite database file (defaults to DATABASE_PATH env or ./data/oaamonitor.db)")
+	jsonOutput := flag.Bool("json", false, "print the report as JSON instead of text")
+	flag.Parse()
+
+	cfg := config.NewConfig()
+	if *databasePath != "" {
+		cfg.DatabasePath = *databasePath
+	}
+
+	if _, err := os.Stat(cfg.DatabasePath); err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
+	registry, err := teams.Load(cfg.TeamsFile)
+	if err != nil {
+		log.Fatalf("Failed to load team registry: %v", err)
+	}
+
+	db, err := database.Open(cfg.DatabasePath)
+	if err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	r, err := runChecks(db, registry, time.Now())
+	if err != nil {
+		log.Fatalf("Failed to check %s: %v", cfg.DatabasePath, err)
+	}
+	r.Database = cfg.DatabasePath
+
+	if *jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(r); err != nil {
+			log.Fatalf("Failed to write report: %v", err)
+		}
+	} else {
+		printReport(os.Stdout, r)
+	}
+
+	if r.Errors > 0 {
+		db.Close()
+		os.Exit(1)
+	}
+}
+
+func printReport(w io.Writer, r report) {
+	fmt.Fprintf(w, "%s (schema version %d)\n\n", r.Database, r.SchemaVersion)
+	for _, c := range r.Checks {
+		fmt.Fprintf(w, "[%s] %s: %s\n", strings.ToUpper(string(c.Status)), c.Name, c.Summary)
+		for _, finding := range c.Findings {
+			fmt.Fprintf(w, "    %s\n", finding)
+		}
+	}
+	fmt.Fprintf(w, "\n%d errors, %d warnings\n", r.Errors, r.Warnings)
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/benfb/oaamonitor/config"
+	"github.com/benfb/oaamonitor/database"
+	"github.com/benfb/oaamonitor/export"
+	"github.com/benfb/oaamonitor/models"
+	"github.com/benfb/oaamonitor/teams"
+)
+
+func main() {
+	databasePath := flag.String("database", "", "path to the SQLite database file (defaults to DATABASE_PATH env or ./data/oaamonitor.db)")
+	format := flag.String("format", "csv", "output format: csv, jsonl or parquet")
+	output := flag.String("out", "-", "file to write, or - for stdout")
+	season := flag.Int("season", 0, "only export snapshots from this season")
+	team := flag.String("team", "", "only export snapshots with this team, by MLBAM ID, abbreviation or name")
+	player := flag.Int("player", 0, "only export snapshots of this MLBAM player ID")
+	from := flag.String("from", "", "only export snapshots on or after this date (YYYY-MM-DD)")
+	to := flag.String("to", "", "only export snapshots on or before this date (YYYY-MM-DD)")
+	flag.Parse()
+
+	exportFormat, err := export.ParseFormat(*format)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, date := range []string{*from, *to} {
+		if date == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			log.Fatalf("Invalid date %q: expected YYYY-MM-DD", date)
+		}
+	}
+
+	cfg := config.NewConfig()
+	if *databasePath != "" {
+		cfg.DatabasePath = *databasePath
+	}
+
+	filter := models.StatFilter{Season: *season, PlayerID: *player, From: *from, To: *to}
+	if *team != "" {
+		filter.TeamID, err = resolveTeam(cfg, *team, *season)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if _, err := os.Stat(cfg.DatabasePath); err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
+	db, err := database.Open(cfg.DatabasePath)
+	if err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	if err := database.CheckSchemaVersion(db); err != nil {
+		log.Fatalf("Refusing to export from %s: %v", cfg.DatabasePath, err)
+	}
+
+	stats, err := models.FetchFilteredStats(db, filter)
+	if err != nil {
+		log.Fatalf("Failed to fetch snapshots: %v", err)
+	}
+
+	if err := writeOutput(*output, exportFormat, stats); err != nil {
+		log.Fatalf("Failed to write export: %v", err)
+	}
+	if *output != "-" {
+		log.Printf("Exported %d rows to %s", len(stats), *output)
+	}
+}
+
+// resolveTeam accepts an MLBAM team ID or any name the team registry knows.
+func resolveTeam(cfg *config.Config, value string, season int) (int, error) {
+	if id, err := strconv.Atoi(value); err == nil {
+		return id, nil
+	}
+	registry, err := teams.Load(cfg.TeamsFile)
+	if err != nil {
+		return 0, err
+	}
+	if season == 0 {
+		season = time.Now().Year()
+	}
+	team, ok := registry.Resolve(value, season)
+	if !ok {
+		return 0, fmt.Errorf("unknown team %q", value)
+	}
+	return team.ID, nil
+}
+
+func writeOutput(path string, format export.Format, stats []models.Stat) error {
+	if path == "-" {
+		return writeStats(os.Stdout, format, stats)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err := writeStats(file, format, stats); err != nil {
+		return err
+	}
+	// A failed close can mean the export never reached the disk.
+	return file.Close()
+}
+
+func writeStats(w io.Writer, format export.Format, stats []models.Stat) error {
+	buffered := bufio.NewWriter(w)
+	if err := export.Write(buffered, format, stats); err != nil {
+		return err
+	}
+	return buffered.Flush()
+}
@@ -3,11 +3,16 @@ package main
 import (
 	"context"
 	"flag"
+	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/benfb/oaamonitor/config"
+	"github.com/benfb/oaamonitor/database"
 	"github.com/benfb/oaamonitor/refresher"
 	"github.com/benfb/oaamonitor/storage"
 )
@@ -15,7 +20,18 @@ import (
 func main() {
 	databasePath := flag.String("database", "", "path to the SQLite database file (defaults to DATABASE_PATH env or ./data/oaamonitor.db)")
 	enableUpload := flag.Bool("upload", false, "upload the refreshed database using configured storage credentials")
+	compression := flag.String("compression", "", "compress uploaded snapshots with zstd, gzip or none (defaults to SNAPSHOT_COMPRESSION env or zstd)")
 	fromStorage := flag.Bool("from-storage", false, "pull the latest database from object storage instead of downloading Baseball Savant data")
+	at := flag.String("at", "", "with -from-storage, restore the last snapshot uploaded on or before this UTC date (YYYY-MM-DD) instead of the latest")
+	storageURL := flag.String("storage", "", "object storage location as s3://bucket/prefix or file:///path (defaults to STORAGE_URL env or s3://oaamonitor)")
+	seasons := flag.String("season", "", "backfill end-of-season leaderboards for a season or range of seasons (e.g. 2024 or 2019..2024)")
+	csvPath := flag.String("csv", "", "import a local leaderboard CSV file, or every dated CSV in a directory, instead of downloading from Baseball Savant")
+	archiveDir := flag.String("archive-dir", "", "directory to archive compressed raw CSVs under raw/YYYY/MM/DD/HHMM.csv.gz (defaults to ARCHIVE_DIR env)")
+	archiveUpload := flag.Bool("archive-upload", false, "also archive compressed raw CSVs to object storage")
+	reingest := flag.Bool("reingest-archive", false, "rebuild a fresh database from every CSV in the archive directory, or under raw/ in object storage when no archive directory is set")
+	dryRun := flag.Bool("dry-run", false, "parse the leaderboard (or -csv file) and print a diff against the stored snapshot without writing anything")
+	leaderboards := flag.String("leaderboards", "", "comma-separated metric leaderboards to refresh alongside OAA, or \"none\" (defaults to LEADERBOARDS env or all of fielding_run_value,arm_strength,catcher_framing,outfield_jump,oaa_by_position)")
+	snapshotDate := flag.String("date", "", "snapshot date (YYYY-MM-DD) to record rows under (defaults to SNAPSHOT_DATE env, today in America/New_York, or the date in a -csv file name)")
 	flag.Parse()
 
 	cfg := config.NewConfig()
@@ -25,22 +41,117 @@ func main() {
 		cfg.DatabasePath = *databasePath
 	}
 
+	if *storageURL != "" {
+		cfg.StorageURL = *storageURL
+	}
+
 	if err := ensureDir(cfg.DatabasePath); err != nil {
 		log.Fatalf("Failed to prepare database directory: %v", err)
 	}
 
 	if *fromStorage {
-		log.Printf("Downloading database from object storage into %s", cfg.DatabasePath)
-		if err := storage.DownloadDatabase(context.Background(), cfg.DatabasePath); err != nil {
+		log.Printf("Downloading database from %s into %s", cfg.StorageURL, cfg.DatabasePath)
+		backend, err := storage.Open(cfg.StorageURL)
+		if err != nil {
+			log.Fatalf("Failed to open storage: %v", err)
+		}
+		if *at != "" {
+			date, err := time.Parse("2006-01-02", *at)
+			if err != nil {
+				log.Fatalf("Invalid -at date %q: expected YYYY-MM-DD", *at)
+			}
+			snapshot, err := storage.DownloadDatabaseAt(context.Background(), backend, cfg.DatabasePath, date)
+			if err != nil {
+				log.Fatalf("Failed to download database from storage: %v", err)
+			}
+			log.Printf("Restored snapshot %s", snapshot.Key)
+			return
+		}
+		if err := storage.DownloadDatabase(context.Background(), backend, cfg.DatabasePath); err != nil {
 			log.Fatalf("Failed to download database from storage: %v", err)
 		}
 		log.Println("Database downloaded successfully")
 		return
 	}
+	if *at != "" {
+		log.Fatalf("-at requires -from-storage")
+	}
+
+	if err := database.CheckSchema(cfg.DatabasePath); err != nil {
+		log.Fatalf("Refusing to refresh %s: %v", cfg.DatabasePath, err)
+	}
 
 	if *enableUpload {
 		cfg.UploadDatabase = true
 	}
+	if *compression != "" {
+		cfg.Compression = *compression
+	}
+	if *snapshotDate != "" {
+		cfg.SnapshotDate = *snapshotDate
+	}
+	if *archiveDir != "" {
+		cfg.ArchiveDir = *archiveDir
+	}
+	if *archiveUpload {
+		cfg.ArchiveToStorage = true
+	}
+	if *leaderboards != "" {
+		cfg.Leaderboards = *leaderboards
+	}
+
+	if *reingest {
+		source := cfg.ArchiveDir
+		if source == "" {
+			source = cfg.StorageURL
+		}
+		log.Printf("Re-ingesting archive %s into %s", source, cfg.DatabasePath)
+		if err := refresher.ReingestArchive(context.Background(), cfg); err != nil {
+			log.Fatalf("Failed to re-ingest archive: %v", err)
+		}
+		log.Println("Archive re-ingested successfully")
+		return
+	}
+
+	if *dryRun {
+		if err := refresher.DryRun(context.Background(), cfg, *csvPath, os.Stdout); err != nil {
+			log.Fatalf("Dry run failed: %v", err)
+		}
+		return
+	}
+
+	if *csvPath != "" {
+		info, err := os.Stat(*csvPath)
+		if err != nil {
+			log.Fatalf("Failed to read -csv path: %v", err)
+		}
+		if info.IsDir() {
+			if *snapshotDate != "" {
+				log.Fatalf("-date cannot be combined with a -csv directory; dates are taken from file names")
+			}
+			err = refresher.ImportCSVDir(context.Background(), cfg, *csvPath)
+		} else {
+			err = refresher.ImportCSV(context.Background(), cfg, *csvPath, cfg.SnapshotDate)
+		}
+		if err != nil {
+			log.Fatalf("Failed to import CSV data: %v", err)
+		}
+		log.Println("CSV data imported successfully")
+		return
+	}
+
+	if *seasons != "" {
+		startSeason, endSeason, err := parseSeasonRange(*seasons)
+		if err != nil {
+			log.Fatalf("Invalid -season value: %v", err)
+		}
+		log.Printf("Backfilling seasons %d through %d into %s", startSeason, endSeason, cfg.DatabasePath)
+		if err := refresher.BackfillSeasons(context.Background(), cfg, startSeason, endSeason); err != nil {
+			log.Fatalf("Failed to backfill seasons: %v", err)
+		}
+		log.Println("Seasons backfilled successfully")
+		return
+	}
 
 	log.Printf("Refreshing Outs Above Average data into %s", cfg.DatabasePath)
 	if err := refresher.GetLatestOAA(context.Background(), cfg); err != nil {
@@ -56,3 +167,24 @@ func ensureDir(path string) error {
 	}
 	return os.MkdirAll(dir, 0o755)
 }
+
+// parseSeasonRange parses either a single season ("2024") or an inclusive range ("2019..2024").
+func parseSeasonRange(value string) (int, int, error) {
+	startValue, endValue, isRange := strings.Cut(value, "..")
+	if !isRange {
+		endValue = startValue
+	}
+
+	start, err := strconv.Atoi(strings.TrimSpace(startValue))
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid season %q", startValue)
+	}
+	end, err := strconv.Atoi(strings.TrimSpace(endValue))
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid season %q", endValue)
+	}
+	if start > end {
+		return 0, 0, fmt.Errorf("season range %d..%d is reversed", start, end)
+	}
+	return start, end, nil
+}
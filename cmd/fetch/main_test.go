@@ -0,0 +1,29 @@
+package main
+
+import "testing"
+
+func TestParseSeasonRange(t *testing.T) {
+	tests := []struct {
+		value     string
+		wantStart int
+		wantEnd   int
+		wantErr   bool
+	}{
+		{"2024", 2024, 2024, false},
+		{"2019..2024", 2019, 2024, false},
+		{" 2020 .. 2021 ", 2020, 2021, false},
+		{"2024..2019", 0, 0, true},
+		{"twenty", 0, 0, true},
+		{"2019..", 0, 0, true},
+	}
+	for _, tt := range tests {
+		start, end, err := parseSeasonRange(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseSeasonRange(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if start != tt.wantStart || end != tt.wantEnd {
+			t.Errorf("parseSeasonRange(%q) = %d, %d; want %d, %d", tt.value, start, end, tt.wantStart, tt.wantEnd)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"github.com/benfb/oaamonitor/config"
+	"github.com/benfb/oaamonitor/database"
+	"github.com/benfb/oaamonitor/teams"
+)
+
+func main() {
+	databasePath := flag.String("database", "", "path to the SQLite database file (defaults to DATABASE_PATH env or ./data/oaamonitor.db)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [-database path] up|status\n\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	cfg := config.NewConfig()
+	if *databasePath != "" {
+		cfg.DatabasePath = *databasePath
+	}
+
+	switch flag.Arg(0) {
+	case "up":
+		db, err := database.New(cfg.DatabasePath)
+		if err != nil {
+			log.Fatalf("Failed to open database: %v", err)
+		}
+		defer db.Close()
+
+		registry, err := teams.Load(cfg.TeamsFile)
+		if err != nil {
+			log.Fatalf("Failed to load team registry: %v", err)
+		}
+		applied, err := database.Migrate(db.DB, registry)
+		for _, migration := range applied {
+			log.Printf("Applied migration %04d_%s", migration.Version, migration.Name)
+		}
+		if err != nil {
+			log.Fatalf("Failed to migrate %s: %v", cfg.DatabasePath, err)
+		}
+		if len(applied) == 0 {
+			log.Println("Database schema is up to date")
+		}
+	case "status":
+		if _, err := os.Stat(cfg.DatabasePath); err != nil {
+			log.Fatalf("Failed to open database: %v", err)
+		}
+		db, err := database.New(cfg.DatabasePath)
+		if err != nil {
+			log.Fatalf("Failed to open database: %v", err)
+		}
+		defer db.Close()
+
+		statuses, err := database.Status(db.DB)
+		if err != nil {
+			log.Fatalf("Failed to read migration status: %v", err)
+		}
+		printStatus(statuses)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+func printStatus(statuses []database.MigrationStatus) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED")
+	for _, status := range statuses {
+		applied := "pending"
+		if status.AppliedAt != nil {
+			applied = status.AppliedAt.UTC().Format("2006-01-02 15:04:05")
+		}
+		if !status.Known {
+			applied += " (unknown to this binary)"
+		}
+		fmt.Fprintf(w, "%04d\t%s\t%s\n", status.Version, status.Name, applied)
+	}
+	w.Flush()
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"time"
+
+	"github.com/benfb/oaamonitor/config"
+	"github.com/benfb/oaamonitor/storage"
+)
+
+func main() {
+	storageURL := flag.String("storage", "", "object storage location as s3://bucket/prefix or file:///path (defaults to STORAGE_URL env or s3://oaamonitor)")
+	dailyDays := flag.Int("daily-days", 0, "days to keep one snapshot per day, after the last 24 hours, before keeping one per week (defaults to SNAPSHOT_RETENTION_DAYS env or 30)")
+	dryRun := flag.Bool("dry-run", false, "list the snapshots that would be deleted without deleting them")
+	flag.Parse()
+
+	cfg := config.NewConfig()
+	if *storageURL != "" {
+		cfg.StorageURL = *storageURL
+	}
+	if *dailyDays > 0 {
+		cfg.SnapshotRetention = *dailyDays
+	}
+
+	backend, err := storage.Open(cfg.StorageURL)
+	if err != nil {
+		log.Fatalf("Failed to open storage: %v", err)
+	}
+
+	expired, err := storage.PruneSnapshots(context.Background(), backend, time.Now(), cfg.SnapshotRetention, *dryRun)
+	for _, snapshot := range expired {
+		if *dryRun {
+			log.Printf("Would delete %s", snapshot.Key)
+		} else {
+			log.Printf("Deleted %s", snapshot.Key)
+		}
+	}
+	if err != nil {
+		log.Fatalf("Failed to prune snapshots: %v", err)
+	}
+	log.Printf("Pruned %d snapshots from %s", len(expired), cfg.StorageURL)
+}
@@ -8,19 +8,43 @@ import (
 
 // Config is a struct that holds the configuration for the application.
 type Config struct {
-	DatabasePath     string
-	DownloadDatabase bool
-	UploadDatabase   bool
-	RequestTimeout   int
+	DatabasePath      string
+	DownloadDatabase  bool
+	UploadDatabase    bool
+	RequestTimeout    int
+	SnapshotDate      string
+	ArchiveDir        string
+	ArchiveToStorage  bool
+	MaxRowDropPercent int
+	MaxOAASumDrift    int
+	DownloadRetries   int
+	RetryBaseDelay    int
+	Leaderboards      string
+	TeamsFile         string
+	StorageURL        string
+	SnapshotRetention int
+	Compression       string
 }
 
 // NewConfig returns a new Config struct.
 func NewConfig() *Config {
 	return &Config{
-		DatabasePath:     GetEnvValue("DATABASE_PATH", "./data/oaamonitor.db"),
-		DownloadDatabase: GetEnvValue("DOWNLOAD_DATABASE", false),
-		UploadDatabase:   GetEnvValue("UPLOAD_DATABASE", false),
-		RequestTimeout:   GetEnvValue("REQUEST_TIMEOUT", 30),
+		DatabasePath:      GetEnvValue("DATABASE_PATH", "./data/oaamonitor.db"),
+		DownloadDatabase:  GetEnvValue("DOWNLOAD_DATABASE", false),
+		UploadDatabase:    GetEnvValue("UPLOAD_DATABASE", false),
+		RequestTimeout:    GetEnvValue("REQUEST_TIMEOUT", 30),
+		SnapshotDate:      GetEnvValue("SNAPSHOT_DATE", ""),
+		ArchiveDir:        GetEnvValue("ARCHIVE_DIR", ""),
+		ArchiveToStorage:  GetEnvValue("ARCHIVE_TO_STORAGE", false),
+		MaxRowDropPercent: GetEnvValue("MAX_ROW_DROP_PERCENT", 50),
+		MaxOAASumDrift:    GetEnvValue("MAX_OAA_SUM_DRIFT", 100),
+		DownloadRetries:   GetEnvValue("DOWNLOAD_RETRIES", 3),
+		RetryBaseDelay:    GetEnvValue("RETRY_BASE_DELAY", 2),
+		Leaderboards:      GetEnvValue("LEADERBOARDS", "fielding_run_value,arm_strength,catcher_framing,outfield_jump,oaa_by_position"),
+		TeamsFile:         GetEnvValue("TEAMS_FILE", ""),
+		StorageURL:        GetEnvValue("STORAGE_URL", "s3://oaamonitor"),
+		SnapshotRetention: GetEnvValue("SNAPSHOT_RETENTION_DAYS", 30),
+		Compression:       GetEnvValue("SNAPSHOT_COMPRESSION", "zstd"),
 	}
 }
 
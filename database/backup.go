@@ -0,0 +1,48 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Backup writes a consistent snapshot of db to dst with VACUUM INTO. The copy
+// is taken inside a single read transaction, so a concurrent writer cannot
+// leave it half-updated. The copy is vacuumed, read-only, and in rollback
+// journal mode, so it is one self-contained file. An existing file at dst is
+// replaced.
+func Backup(db *sql.DB, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return fmt.Errorf("failed to create backup directory: %v", err)
+	}
+
+	// VACUUM INTO refuses to overwrite, so write beside dst and rename.
+	tmpPath := dst + ".tmp"
+	if err := os.Remove(tmpPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	defer os.Remove(tmpPath)
+
+	if _, err := db.Exec(`VACUUM INTO ?`, tmpPath); err != nil {
+		return fmt.Errorf("failed to back up database: %v", err)
+	}
+	if err := os.Chmod(tmpPath, 0o444); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, dst)
+}
+
+// BackupFile is Backup for a database path.
+func BackupFile(dbPath, dst string) error {
+	if _, err := os.Stat(dbPath); err != nil {
+		return err
+	}
+
+	db, err := Open(dbPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return Backup(db, dst)
+}
@@ -0,0 +1,74 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/benfb/oaamonitor/teams"
+)
+
+func TestBackup(t *testing.T) {
+	dir := t.TempDir()
+	db, err := Open(filepath.Join(dir, "source.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err := Migrate(db, teams.Default()); err != nil {
+		t.Fatalf("Migrate returned error: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO players (id, full_name, updated_on) VALUES (1, 'John Doe', '2025-06-01')`); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := filepath.Join(dir, "downloads", "oaamonitor.db")
+	for i := 0; i < 2; i++ {
+		// The second backup replaces the read-only file the first one left.
+		if err := Backup(db, dst); err != nil {
+			t.Fatalf("Backup returned error: %v", err)
+		}
+	}
+
+	info, err := os.Stat(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm()&0o222 != 0 {
+		t.Errorf("expected a read-only backup, got mode %v", info.Mode())
+	}
+	if _, err := os.Stat(dst + "-wal"); !os.IsNotExist(err) {
+		t.Errorf("expected no WAL file beside the backup, got %v", err)
+	}
+
+	header := make([]byte, 20)
+	file, err := os.Open(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := file.Read(header); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+	// Bytes 18 and 19 of the header are 2 for WAL mode and 1 for rollback journals.
+	if header[18] != 1 || header[19] != 1 {
+		t.Errorf("expected a rollback journal database, got header versions %d/%d", header[18], header[19])
+	}
+
+	backup, err := Open(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backup.Close()
+	var name string
+	if err := backup.QueryRow(`SELECT full_name FROM players WHERE id = 1`).Scan(&name); err != nil {
+		t.Fatalf("failed to read backup: %v", err)
+	}
+	if name != "John Doe" {
+		t.Errorf("expected John Doe in the backup, got %q", name)
+	}
+	if version, err := SchemaVersion(backup); err != nil || version == 0 {
+		t.Errorf("expected the backup to keep its schema version, got %d, %v", version, err)
+	}
+}
@@ -0,0 +1,171 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// CompactionResult reports how many stored snapshot rows a compaction left behind.
+type CompactionResult struct {
+	RowsBefore int
+	RowsAfter  int
+}
+
+type snapshotValues struct {
+	teamID               sql.NullInt64
+	primaryPosition      sql.NullString
+	oaa                  sql.NullInt64
+	actualSuccessRate    sql.NullFloat64
+	estimatedSuccessRate sql.NullFloat64
+	diffSuccessRate      sql.NullFloat64
+}
+
+type snapshotRange struct {
+	id       int64
+	playerID int64
+	values   snapshotValues
+	date     string
+	validTo  string
+}
+
+// Compact collapses each run of identical rows a player has on consecutive
+// snapshot dates into a single row whose valid_to is the run's last date. The
+// oaa_snapshots view expands ranges again, so readers see the same rows.
+func Compact(db *sql.DB) (CompactionResult, error) {
+	var result CompactionResult
+
+	tx, err := db.Begin()
+	if err != nil {
+		return result, err
+	}
+	defer tx.Rollback()
+
+	ordinals, err := snapshotDateOrdinals(tx)
+	if err != nil {
+		return result, err
+	}
+
+	rows, err := tx.Query(`
+		SELECT id, player_id, team_id, primary_position, oaa,
+			actual_success_rate, estimated_success_rate, diff_success_rate,
+			strftime('%Y-%m-%d', date), strftime('%Y-%m-%d', COALESCE(valid_to, date))
+		FROM outs_above_average
+		ORDER BY player_id, date`)
+	if err != nil {
+		return result, err
+	}
+	var stored []snapshotRange
+	for rows.Next() {
+		var r snapshotRange
+		if err := rows.Scan(&r.id, &r.playerID, &r.values.teamID, &r.values.primaryPosition, &r.values.oaa,
+			&r.values.actualSuccessRate, &r.values.estimatedSuccessRate, &r.values.diffSuccessRate, &r.date, &r.validTo); err != nil {
+			rows.Close()
+			return result, err
+		}
+		stored = append(stored, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return result, err
+	}
+	result.RowsBefore = len(stored)
+
+	var merged []snapshotRange
+	var removed []int64
+	for _, r := range stored {
+		if n := len(merged); n > 0 && extends(merged[n-1], r, ordinals) {
+			merged[n-1].validTo = r.validTo
+			removed = append(removed, r.id)
+			continue
+		}
+		merged = append(merged, r)
+	}
+	result.RowsAfter = len(merged)
+
+	remove, err := tx.Prepare(`DELETE FROM outs_above_average WHERE id = ?`)
+	if err != nil {
+		return result, err
+	}
+	defer remove.Close()
+	for _, id := range removed {
+		if _, err := remove.Exec(id); err != nil {
+			return result, err
+		}
+	}
+
+	extend, err := tx.Prepare(`UPDATE outs_above_average SET valid_to = ? WHERE id = ?`)
+	if err != nil {
+		return result, err
+	}
+	defer extend.Close()
+	for _, r := range merged {
+		if r.validTo == r.date {
+			continue
+		}
+		if _, err := extend.Exec(r.validTo, r.id); err != nil {
+			return result, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return result, fmt.Errorf("failed to commit compaction: %v", err)
+	}
+	return result, nil
+}
+
+// extends reports whether next continues current unchanged on the snapshot date
+// right after current ends, so a player missing from a snapshot breaks the run.
+func extends(current, next snapshotRange, ordinals map[string]int) bool {
+	if current.playerID != next.playerID || current.values != next.values {
+		return false
+	}
+	end, ok := ordinals[current.validTo]
+	if !ok {
+		return false
+	}
+	start, ok := ordinals[next.date]
+	return ok && start == end+1
+}
+
+func snapshotDateOrdinals(tx *sql.Tx) (map[string]int, error) {
+	rows, err := tx.Query(`SELECT strftime('%Y-%m-%d', date) FROM snapshot_dates ORDER BY date`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ordinals := make(map[string]int)
+	for rows.Next() {
+		var date string
+		if err := rows.Scan(&date); err != nil {
+			return nil, err
+		}
+		ordinals[date] = len(ordinals)
+	}
+	return ordinals, rows.Err()
+}
+
+// PrepareSnapshotDate readies a snapshot date to be written: any compacted range
+// covering it is expanded back into one row per date, so the incoming rows
+// replace only that date, and the date is recorded in snapshot_dates.
+func PrepareSnapshotDate(db interface {
+	Exec(string, ...any) (sql.Result, error)
+}, date string) error {
+	if _, err := db.Exec(`
+		INSERT INTO outs_above_average (player_id, team_id, primary_position, oaa,
+			actual_success_rate, estimated_success_rate, diff_success_rate, date)
+		SELECT o.player_id, o.team_id, o.primary_position, o.oaa,
+			o.actual_success_rate, o.estimated_success_rate, o.diff_success_rate, s.date
+		FROM outs_above_average o
+		JOIN snapshot_dates s ON s.date > o.date AND s.date <= o.valid_to
+		WHERE o.valid_to IS NOT NULL AND o.date <= ?1 AND o.valid_to >= ?1`, date); err != nil {
+		return fmt.Errorf("failed to expand compacted snapshots: %v", err)
+	}
+	if _, err := db.Exec(`UPDATE outs_above_average SET valid_to = NULL WHERE valid_to IS NOT NULL AND date <= ?1 AND valid_to >= ?1`, date); err != nil {
+		return fmt.Errorf("failed to expand compacted snapshots: %v", err)
+	}
+	if _, err := db.Exec(`INSERT OR IGNORE INTO snapshot_dates (date) VALUES (?)`, date); err != nil {
+		return fmt.Errorf("failed to record snapshot date: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/benfb/oaamonitor/models"
+	"github.com/benfb/oaamonitor/teams"
+)
+
+// setupSnapshotDB migrates a fresh database and writes each snapshot the way an
+// ingest does: the date is prepared first, then its rows are inserted.
+func setupSnapshotDB(t *testing.T, snapshots map[string][]string) *sql.DB {
+	db, err := Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Migrate(db, teams.Default()); err != nil {
+		t.Fatalf("Migrate returned error: %v", err)
+	}
+	if _, err := db.Exec(`
+		INSERT INTO players (id, first_name, last_name, full_name, updated_on) VALUES
+		(1, 'John', 'Doe', 'John Doe', '2025-06-06'),
+		(2, 'Jane', 'Smith', 'Jane Smith', '2025-06-06'),
+		(3, 'Bob', 'Johnson', 'Bob Johnson', '2025-06-06'),
+		(4, 'Mike', 'Brown', 'Mike Brown', '2024-09-29')`); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, date := range []string{"2024-09-28", "2024-09-29", "2025-06-01", "2025-06-02", "2025-06-03", "2025-06-04", "2025-06-05", "2025-06-06"} {
+		if err := PrepareSnapshotDate(db, date); err != nil {
+			t.Fatalf("PrepareSnapshotDate(%s) returned error: %v", date, err)
+		}
+		for _, values := range snapshots[date] {
+			if _, err := db.Exec(fmt.Sprintf(`
+				INSERT INTO outs_above_average (player_id, team_id, primary_position, oaa, actual_success_rate, estimated_success_rate, diff_success_rate, date)
+				VALUES (%s, ?)`, values), date); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	return db
+}
+
+var compactionSnapshots = map[string][]string{
+	"2024-09-28": {"4, 135, '2B', 1, 0.6, 0.59, 0.01"},
+	"2024-09-29": {"4, 135, '2B', 1, 0.6, 0.59, 0.01"},
+	"2025-06-01": {"1, 147, 'SS', 2, 0.8, 0.7, 0.1", "2, 111, 'CF', 1, 0.7, 0.7, 0.0", "3, 121, NULL, 0, 0.5, 0.5, 0.0"},
+	"2025-06-02": {"1, 147, 'SS', 2, 0.8, 0.7, 0.1", "2, 111, 'CF', 1, 0.7, 0.7, 0.0", "3, 121, NULL, 0, 0.5, 0.5, 0.0"},
+	"2025-06-03": {"1, 147, 'SS', 2, 0.8, 0.7, 0.1", "3, 121, NULL, 0, 0.5, 0.5, 0.0"},
+	"2025-06-04": {"1, 147, 'SS', 3, 0.81, 0.7, 0.11", "2, 111, 'CF', 1, 0.7, 0.7, 0.0", "3, 121, NULL, 0, 0.5, 0.5, 0.0"},
+	"2025-06-05": {"1, 147, 'SS', 3, 0.81, 0.7, 0.11", "2, 111, 'CF', 1, 0.7, 0.7, 0.0", "3, 147, '1B', 0, 0.5, 0.5, 0.0"},
+	"2025-06-06": {"1, 147, 'SS', 3, 0.81, 0.7, 0.11", "2, 111, 'CF', 1, 0.7, 0.7, 0.0", "3, 147, '1B', 0, 0.5, 0.5, 0.0"},
+}
+
+// modelResults captures what the site reads, so compaction can be checked
+// against every query rather than the raw table.
+func modelResults(t *testing.T, db *sql.DB) map[string]any {
+	results := make(map[string]any)
+	record := func(name string, value any, err error) {
+		if err != nil {
+			t.Fatalf("%s returned error: %v", name, err)
+		}
+		results[name] = value
+	}
+
+	stats, err := models.FetchStats(db)
+	record("FetchStats", stats, err)
+	players, err := models.FetchPlayers(db)
+	record("FetchPlayers", players, err)
+	playerStats, name, position, err := models.FetchPlayerStats(db, 1, 2025)
+	record("FetchPlayerStats", []any{playerStats, name, position}, err)
+	playerSeasons, err := models.FetchPlayerSeasons(db, 4)
+	record("FetchPlayerSeasons", playerSeasons, err)
+	teamStats, teamName, err := models.FetchTeamStats(db, 147, 2025)
+	record("FetchTeamStats", []any{teamStats, teamName}, err)
+	teamSeasons, err := models.FetchTeamSeasons(db, 135)
+	record("FetchTeamSeasons", teamSeasons, err)
+	teams, err := models.FetchTeams(db)
+	record("FetchTeams", teams, err)
+	seasons, err := models.FetchSeasons(db)
+	record("FetchSeasons", seasons, err)
+	differences, err := models.FetchPlayerDifferences(db, 10)
+	record("FetchPlayerDifferences", differences, err)
+	trends, err := models.FetchNDayTrends(db, 7, 0)
+	record("FetchNDayTrends", trends, err)
+	latest, err := models.FetchLatestSnapshotDate(db)
+	record("FetchLatestSnapshotDate", latest, err)
+	return results
+}
+
+func TestCompact_PreservesModelQueries(t *testing.T) {
+	db := setupSnapshotDB(t, compactionSnapshots)
+	defer db.Close()
+
+	before := modelResults(t, db)
+
+	result, err := Compact(db)
+	if err != nil {
+		t.Fatalf("Compact returned error: %v", err)
+	}
+	// Player 1: two runs. Player 2: broken by the missing 06-03 snapshot. Player 3:
+	// broken by the trade. Player 4: one run.
+	if result.RowsBefore != 19 || result.RowsAfter != 7 {
+		t.Errorf("expected 19 rows compacted to 7, got %+v", result)
+	}
+
+	after := modelResults(t, db)
+	for name, want := range before {
+		if got := after[name]; !reflect.DeepEqual(got, want) {
+			t.Errorf("%s changed after compaction:\nbefore %+v\nafter  %+v", name, want, got)
+		}
+	}
+
+	again, err := Compact(db)
+	if err != nil {
+		t.Fatalf("second Compact returned error: %v", err)
+	}
+	if again.RowsBefore != 7 || again.RowsAfter != 7 {
+		t.Errorf("expected a second compaction to be a no-op, got %+v", again)
+	}
+}
+
+func TestPrepareSnapshotDate_ExpandsCompactedRanges(t *testing.T) {
+	db := setupSnapshotDB(t, compactionSnapshots)
+	defer db.Close()
+
+	if _, err := Compact(db); err != nil {
+		t.Fatalf("Compact returned error: %v", err)
+	}
+
+	// Re-ingest the middle of player 1's first run with a corrected value.
+	if err := PrepareSnapshotDate(db, "2025-06-02"); err != nil {
+		t.Fatalf("PrepareSnapshotDate returned error: %v", err)
+	}
+	if _, err := db.Exec(`UPDATE outs_above_average SET oaa = 9 WHERE player_id = 1 AND date = '2025-06-02'`); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := db.Query(`SELECT oaa FROM oaa_snapshots WHERE player_id = 1 ORDER BY date`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var got []int
+	for rows.Next() {
+		var oaa int
+		if err := rows.Scan(&oaa); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, oaa)
+	}
+	if want := []int{2, 9, 2, 3, 3, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected player 1 OAA %v after the rewrite, got %v", want, got)
+	}
+}
@@ -7,9 +7,17 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/ncruces/go-sqlite3"
 	_ "github.com/ncruces/go-sqlite3/driver"
+	"github.com/ncruces/go-sqlite3/ext/fts5"
 )
 
+// The player search index is an FTS5 table, so every connection needs the
+// extension, including ones opened with sql.Open directly.
+func init() {
+	sqlite3.AutoExtension(fts5.Register)
+}
+
 // DB wraps the sql.DB connection and provides methods for database operations
 type DB struct {
 	*sql.DB
@@ -47,54 +55,6 @@ func New(dbPath string) (*DB, error) {
 	return &DB{db}, nil
 }
 
-// EnsureSchema creates the tables and indexes required by OAA Monitor.
-func EnsureSchema(db *sql.DB) error {
-	createTableSQL := `
-	CREATE TABLE IF NOT EXISTS outs_above_average (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		player_id INTEGER,
-		first_name TEXT,
-		last_name TEXT,
-		full_name TEXT,
-		team TEXT,
-		primary_position TEXT,
-		oaa INTEGER,
-		actual_success_rate REAL,
-		estimated_success_rate REAL,
-		diff_success_rate REAL,
-		date DATE DEFAULT CURRENT_DATE,
-		UNIQUE(player_id, date)
-	);`
-	if _, err := db.Exec(createTableSQL); err != nil {
-		return err
-	}
-
-	indexes := []string{
-		`CREATE INDEX IF NOT EXISTS idx_date ON outs_above_average(date)`,
-		`CREATE INDEX IF NOT EXISTS idx_team_lower ON outs_above_average(LOWER(team))`,
-		`CREATE INDEX IF NOT EXISTS idx_name ON outs_above_average(last_name, first_name)`,
-		`CREATE INDEX IF NOT EXISTS idx_team_date ON outs_above_average(LOWER(team), date)`,
-	}
-
-	for _, indexSQL := range indexes {
-		if _, err := db.Exec(indexSQL); err != nil {
-			return fmt.Errorf("failed to create index: %v", err)
-		}
-	}
-
-	return nil
-}
-
-// CheckpointWAL checkpoints pending WAL pages into the main database file.
-func CheckpointWAL(dbPath string) error {
-	db, err := Open(dbPath)
-	if err != nil {
-		return err
-	}
-	defer db.Close()
-	return Checkpoint(db)
-}
-
 // Checkpoint checkpoints pending WAL pages on an open database connection.
 func Checkpoint(db *sql.DB) error {
 	_, err := db.Exec("PRAGMA wal_checkpoint(FULL)")
@@ -0,0 +1,435 @@
+package database
+
+import (
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/benfb/oaamonitor/teams"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// ErrSchemaTooNew is returned when a database has migrations applied that this
+// binary does not know about, so it could misread or damage the data.
+var ErrSchemaTooNew = errors.New("database schema is newer than this binary supports")
+
+// Migration is an embedded SQL file named NNNN_description.sql.
+type Migration struct {
+	Version int
+	Name    string
+	SQL     string
+}
+
+// MigrationStatus pairs a migration with when it was applied, if it has been.
+// Known is false for versions recorded in the database but not embedded here.
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time
+	Known     bool
+}
+
+// Migrations returns the embedded migrations in version order.
+func Migrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	var migrations []Migration
+	seen := make(map[int]string)
+	for _, entry := range entries {
+		versionText, name, ok := strings.Cut(strings.TrimSuffix(entry.Name(), ".sql"), "_")
+		version, err := strconv.Atoi(versionText)
+		if !ok || err != nil || version <= 0 {
+			return nil, fmt.Errorf("invalid migration file name %q", entry.Name())
+		}
+		if previous, ok := seen[version]; ok {
+			return nil, fmt.Errorf("migrations %q and %q share version %d", previous, entry.Name(), version)
+		}
+		seen[version] = entry.Name()
+
+		contents, err := migrationFiles.ReadFile(path.Join("migrations", entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, Migration{Version: version, Name: name, SQL: string(contents)})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// EnsureSchema refuses databases with a newer schema and applies any pending
+// migrations, resolving stored team names through registry.
+func EnsureSchema(db *sql.DB, registry *teams.Registry) error {
+	applied, err := Migrate(db, registry)
+	if err != nil {
+		return err
+	}
+	for _, migration := range applied {
+		log.Printf("Applied migration %04d_%s", migration.Version, migration.Name)
+	}
+	return nil
+}
+
+// Migrate applies every pending migration, each in its own transaction, and
+// returns the ones it applied. Migrations that move team names onto team IDs
+// resolve them through registry, which should be the one the deployment
+// ingests with (TEAMS_FILE).
+func Migrate(db *sql.DB, registry *teams.Registry) ([]Migration, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	legacy, err := isLegacySchema(db)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at TIMESTAMP NOT NULL
+		)`); err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations: %v", err)
+	}
+	if err := checkSchemaVersion(db, migrations); err != nil {
+		return nil, err
+	}
+
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var ran []Migration
+	for _, migration := range migrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+		if err := applyMigration(db, migration, legacy, registry); err != nil {
+			return ran, fmt.Errorf("migration %04d_%s failed: %v", migration.Version, migration.Name, err)
+		}
+		ran = append(ran, migration)
+	}
+	return ran, nil
+}
+
+func applyMigration(db *sql.DB, migration Migration, legacy bool, registry *teams.Registry) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if prepare, ok := prepareMigration[migration.Version]; ok {
+		if err := prepare(tx, registry); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(migration.SQL); err != nil {
+		return err
+	}
+	if migration.Version == 1 && legacy {
+		if err := adoptLegacySchema(tx); err != nil {
+			return err
+		}
+	}
+	if finish, ok := finishMigration[migration.Version]; ok {
+		if err := finish(tx, registry); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(`INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`,
+		migration.Version, migration.Name, time.Now().UTC()); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// prepareMigration and finishMigration hold the Go steps a migration's SQL
+// cannot express on its own. They run in the migration's transaction, before
+// and after its SQL.
+var (
+	prepareMigration = map[int]func(*sql.Tx, *teams.Registry) error{
+		7: resolveMetricTeams,
+	}
+	finishMigration = map[int]func(*sql.Tx, *teams.Registry) error{
+		2: checkUnmatchedTeams,
+	}
+)
+
+// checkUnmatchedTeams fails 0002_dimensions when a snapshot or stint names a
+// team with no alias, which would otherwise lose its team.
+func checkUnmatchedTeams(tx *sql.Tx, _ *teams.Registry) error {
+	names, err := queryStrings(tx, `SELECT team FROM temp.unmatched_team_names ORDER BY team`)
+	if err != nil {
+		return err
+	}
+	if len(names) > 0 {
+		return fmt.Errorf("no team alias for %s", strings.Join(names, ", "))
+	}
+	_, err = tx.Exec(`DROP TABLE temp.unmatched_team_names`)
+	return err
+}
+
+// metricTables are the metric leaderboard tables that 0007_metric_dimensions
+// moves onto the player and team dimensions.
+var metricTables = []string{"fielding_run_value", "arm_strength", "catcher_framing", "outfield_jump"}
+
+// resolveMetricTeams resolves every team name stored by the metric
+// leaderboards through registry into temp.metric_team_ids for
+// 0007_metric_dimensions. A name the registry does not know fails the
+// migration rather than losing its team.
+func resolveMetricTeams(tx *sql.Tx, registry *teams.Registry) error {
+	if _, err := tx.Exec(`
+		CREATE TEMP TABLE metric_team_ids (
+			team TEXT NOT NULL,
+			season INTEGER NOT NULL,
+			team_id INTEGER NOT NULL,
+			PRIMARY KEY (team, season)
+		)`); err != nil {
+		return err
+	}
+
+	queries := make([]string, len(metricTables))
+	for i, table := range metricTables {
+		queries[i] = fmt.Sprintf(`SELECT team, CAST(strftime('%%Y', date) AS INTEGER) AS season FROM %s WHERE COALESCE(team, '') != ''`, table)
+	}
+	rows, err := tx.Query(`SELECT DISTINCT team, season FROM (` + strings.Join(queries, " UNION ") + `) ORDER BY team, season`)
+	if err != nil {
+		return err
+	}
+	type teamSeason struct {
+		name   string
+		season int
+	}
+	var names []teamSeason
+	for rows.Next() {
+		var name teamSeason
+		if err := rows.Scan(&name.name, &name.season); err != nil {
+			rows.Close()
+			return err
+		}
+		names = append(names, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	var unknown []string
+	for _, name := range names {
+		team, ok := registry.Resolve(name.name, name.season)
+		if !ok {
+			unknown = append(unknown, fmt.Sprintf("%s (%d)", name.name, name.season))
+			continue
+		}
+		current, _ := registry.Current(team.ID)
+		if _, err := tx.Exec(`INSERT INTO teams (id, name, abbreviation) VALUES (?, ?, ?) ON CONFLICT(id) DO NOTHING`,
+			current.ID, current.ShortName, current.Abbreviation); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`INSERT INTO temp.metric_team_ids (team, season, team_id) VALUES (?, ?, ?)`,
+			name.name, name.season, team.ID); err != nil {
+			return err
+		}
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("unknown team %s: add it to the team registry", strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+func queryStrings(tx *sql.Tx, query string) ([]string, error) {
+	rows, err := tx.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var values []string
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, rows.Err()
+}
+
+// isLegacySchema reports whether the database was created by a release that
+// predates migrations: it has tables but no schema_migrations.
+func isLegacySchema(db *sql.DB) (bool, error) {
+	var migrationsTable, dataTable int
+	err := db.QueryRow(`
+		SELECT
+			COUNT(*) FILTER (WHERE name = 'schema_migrations'),
+			COUNT(*) FILTER (WHERE name = 'outs_above_average')
+		FROM sqlite_master WHERE type = 'table'`).Scan(&migrationsTable, &dataTable)
+	if err != nil {
+		return false, err
+	}
+	return migrationsTable == 0 && dataTable > 0, nil
+}
+
+// adoptLegacySchema adds the columns that releases before migrations added to
+// existing tables with ALTER TABLE, which the idempotent baseline cannot express.
+func adoptLegacySchema(tx *sql.Tx) error {
+	for _, column := range []string{"etag", "last_modified"} {
+		if err := addColumnIfMissing(tx, "ingest_runs", column, "TEXT"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addColumnIfMissing adds a column to a table created by an older release.
+func addColumnIfMissing(db interface {
+	QueryRow(string, ...any) *sql.Row
+	Exec(string, ...any) (sql.Result, error)
+}, table, column, definition string) error {
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`, table, column).Scan(&count); err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	if _, err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)); err != nil {
+		return fmt.Errorf("failed to add column %s.%s: %v", table, column, err)
+	}
+	return nil
+}
+
+// SchemaVersion returns the highest applied migration, or 0 for a database that
+// has never been migrated.
+func SchemaVersion(db *sql.DB) (int, error) {
+	var tables int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations'`).Scan(&tables); err != nil {
+		return 0, err
+	}
+	if tables == 0 {
+		return 0, nil
+	}
+
+	var version sql.NullInt64
+	if err := db.QueryRow(`SELECT MAX(version) FROM schema_migrations`).Scan(&version); err != nil {
+		return 0, err
+	}
+	return int(version.Int64), nil
+}
+
+// CheckSchemaVersion returns ErrSchemaTooNew when the database has migrations
+// applied beyond the newest one embedded in this binary.
+func CheckSchemaVersion(db *sql.DB) error {
+	migrations, err := Migrations()
+	if err != nil {
+		return err
+	}
+	return checkSchemaVersion(db, migrations)
+}
+
+// CheckSchema is CheckSchemaVersion for a database path. A missing file passes.
+func CheckSchema(dbPath string) error {
+	if _, err := os.Stat(dbPath); errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	db, err := Open(dbPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return CheckSchemaVersion(db)
+}
+
+func checkSchemaVersion(db *sql.DB, migrations []Migration) error {
+	version, err := SchemaVersion(db)
+	if err != nil {
+		return err
+	}
+	latest := 0
+	if len(migrations) > 0 {
+		latest = migrations[len(migrations)-1].Version
+	}
+	if version > latest {
+		return fmt.Errorf("%w: database is at version %d, this binary knows up to %d", ErrSchemaTooNew, version, latest)
+	}
+	return nil
+}
+
+// Status lists every embedded migration with its applied time, followed by any
+// applied versions this binary does not know.
+func Status(db *sql.DB) ([]MigrationStatus, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	applied := make(map[int]appliedMigration)
+	if version, err := SchemaVersion(db); err != nil {
+		return nil, err
+	} else if version > 0 {
+		if applied, err = appliedMigrations(db); err != nil {
+			return nil, err
+		}
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, migration := range migrations {
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name, Known: true}
+		if row, ok := applied[migration.Version]; ok {
+			status.AppliedAt = &row.appliedAt
+			delete(applied, migration.Version)
+		}
+		statuses = append(statuses, status)
+	}
+
+	var unknown []MigrationStatus
+	for version, row := range applied {
+		unknown = append(unknown, MigrationStatus{Version: version, Name: row.name, AppliedAt: &row.appliedAt})
+	}
+	sort.Slice(unknown, func(i, j int) bool { return unknown[i].Version < unknown[j].Version })
+	return append(statuses, unknown...), nil
+}
+
+type appliedMigration struct {
+	name      string
+	appliedAt time.Time
+}
+
+func appliedMigrations(db *sql.DB) (map[int]appliedMigration, error) {
+	rows, err := db.Query(`SELECT version, name, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]appliedMigration)
+	for rows.Next() {
+		var version int
+		var row appliedMigration
+		if err := rows.Scan(&version, &row.name, &row.appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = row
+	}
+	return applied, rows.Err()
+}
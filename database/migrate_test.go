@@ -0,0 +1,278 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/benfb/oaamonitor/teams"
+)
+
+func TestMigrate_FreshDatabase(t *testing.T) {
+	db, err := Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	migrations, err := Migrations()
+	if err != nil {
+		t.Fatalf("Migrations returned error: %v", err)
+	}
+
+	applied, err := Migrate(db, teams.Default())
+	if err != nil {
+		t.Fatalf("Migrate returned error: %v", err)
+	}
+	if len(applied) != len(migrations) {
+		t.Fatalf("expected %d migrations applied, got %d", len(migrations), len(applied))
+	}
+
+	version, err := SchemaVersion(db)
+	if err != nil {
+		t.Fatalf("SchemaVersion returned error: %v", err)
+	}
+	if version != migrations[len(migrations)-1].Version {
+		t.Errorf("expected schema version %d, got %d", migrations[len(migrations)-1].Version, version)
+	}
+
+	applied, err = Migrate(db, teams.Default())
+	if err != nil {
+		t.Fatalf("second Migrate returned error: %v", err)
+	}
+	if len(applied) != 0 {
+		t.Errorf("expected no migrations on the second run, got %d", len(applied))
+	}
+}
+
+func TestMigrate_AdoptsLegacyDatabase(t *testing.T) {
+	db, err := Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// The shape EnsureSchema created before the ledger gained cache validators.
+	if _, err := db.Exec(legacyOAATable + `
+		CREATE TABLE ingest_runs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT, started_at TIMESTAMP NOT NULL, finished_at TIMESTAMP NOT NULL,
+			source TEXT NOT NULL, snapshot_date DATE, row_count INTEGER NOT NULL DEFAULT 0,
+			inserted_count INTEGER NOT NULL DEFAULT 0, updated_count INTEGER NOT NULL DEFAULT 0,
+			unchanged_count INTEGER NOT NULL DEFAULT 0, csv_sha256 TEXT, error TEXT
+		);
+		INSERT INTO outs_above_average (player_id, first_name, last_name, full_name, team, oaa, date) VALUES
+			(1, 'Jon', 'Doe', 'Jon Doe', 'Indians', 3, '2021-06-01'),
+			(1, 'John', 'Doe', 'John Doe', 'Guardians', 5, '2024-06-01');`); err != nil {
+		t.Fatalf("failed to create legacy schema: %v", err)
+	}
+
+	if _, err := Migrate(db, teams.Default()); err != nil {
+		t.Fatalf("Migrate returned error: %v", err)
+	}
+
+	var columns int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info('ingest_runs') WHERE name IN ('etag', 'last_modified')`).Scan(&columns); err != nil {
+		t.Fatal(err)
+	}
+	if columns != 2 {
+		t.Errorf("expected the legacy ingest_runs table to gain etag and last_modified, found %d", columns)
+	}
+
+	var oaa int
+	if err := db.QueryRow(`SELECT oaa FROM outs_above_average WHERE player_id = 1 AND date = '2024-06-01'`).Scan(&oaa); err != nil || oaa != 5 {
+		t.Errorf("expected existing rows to survive, got %d, %v", oaa, err)
+	}
+
+	var snapshots int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM outs_above_average WHERE team_id = 114`).Scan(&snapshots); err != nil || snapshots != 2 {
+		t.Errorf("expected Indians and Guardians snapshots to share team 114, got %d, %v", snapshots, err)
+	}
+
+	var name string
+	if err := db.QueryRow(`SELECT full_name FROM players WHERE id = 1`).Scan(&name); err != nil || name != "John Doe" {
+		t.Errorf("expected the player dimension to hold the latest name, got %q, %v", name, err)
+	}
+}
+
+// legacyOAATable is outs_above_average as releases before migrations created it.
+const legacyOAATable = `
+	CREATE TABLE outs_above_average (
+		id INTEGER PRIMARY KEY AUTOINCREMENT, player_id INTEGER, first_name TEXT, last_name TEXT,
+		full_name TEXT, team TEXT, primary_position TEXT, oaa INTEGER, actual_success_rate REAL,
+		estimated_success_rate REAL, diff_success_rate REAL, date DATE DEFAULT CURRENT_DATE,
+		UNIQUE(player_id, date)
+	);`
+
+func TestMigrate_FailsOnUnmatchedTeams(t *testing.T) {
+	db, err := Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(legacyOAATable + `
+		INSERT INTO outs_above_average (player_id, full_name, team, oaa, date) VALUES
+			(1, 'John Doe', 'Guardians', 5, '2024-06-01'),
+			(2, 'Jane Roe', 'Montreal', 2, '2024-06-01');`); err != nil {
+		t.Fatalf("failed to create legacy schema: %v", err)
+	}
+
+	_, err = Migrate(db, teams.Default())
+	if err == nil || !strings.Contains(err.Error(), "no team alias for Montreal") {
+		t.Fatalf("expected the unmatched team to fail the migration, got %v", err)
+	}
+	if version, err := SchemaVersion(db); err != nil || version != 1 {
+		t.Errorf("expected the database to stay at version 1, got %d, %v", version, err)
+	}
+	var team string
+	if err := db.QueryRow(`SELECT team FROM outs_above_average WHERE player_id = 2`).Scan(&team); err != nil || team != "Montreal" {
+		t.Errorf("expected the snapshot to keep its team, got %q, %v", team, err)
+	}
+}
+
+func TestMigrate_MovesMetricTablesOntoDimensions(t *testing.T) {
+	db, err := Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(legacyOAATable + `
+		CREATE TABLE catcher_framing (
+			id INTEGER PRIMARY KEY AUTOINCREMENT, player_id INTEGER NOT NULL, full_name TEXT, team TEXT,
+			framing_runs REAL, strike_rate REAL, date DATE NOT NULL, UNIQUE(player_id, date)
+		);
+		INSERT INTO outs_above_average (player_id, first_name, last_name, full_name, team, oaa, date) VALUES
+			(1, 'John', 'Doe', 'John Doe', 'Guardians', 5, '2024-06-01');
+		INSERT INTO catcher_framing (player_id, full_name, team, framing_runs, date) VALUES
+			(1, 'Johnny Doe', 'Guardians', 1.5, '2024-06-01'),
+			(2, 'Cal Catcher', 'Diamondbacks', 3.5, '2024-06-01'),
+			(3, 'Free Agent', NULL, 0.5, '2024-06-01');`); err != nil {
+		t.Fatalf("failed to create legacy schema: %v", err)
+	}
+
+	if _, err := Migrate(db, teams.Default()); err != nil {
+		t.Fatalf("Migrate returned error: %v", err)
+	}
+
+	var columns int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info('catcher_framing') WHERE name IN ('team', 'full_name')`).Scan(&columns); err != nil || columns != 0 {
+		t.Errorf("expected catcher_framing to drop its team and name copies, found %d, %v", columns, err)
+	}
+
+	rows, err := db.Query(`
+		SELECT c.player_id, p.full_name, COALESCE(c.team_id, 0), c.framing_runs
+		FROM catcher_framing c
+		JOIN players p ON p.id = c.player_id
+		ORDER BY c.player_id`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var got []string
+	for rows.Next() {
+		var id, teamID int
+		var name string
+		var runs float64
+		if err := rows.Scan(&id, &name, &teamID, &runs); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, fmt.Sprintf("%d %s %d %.1f", id, name, teamID, runs))
+	}
+	// The OAA name wins for a player it knows; the rest join the players table.
+	want := []string{"1 John Doe 114 1.5", "2 Cal Catcher 109 3.5", "3 Free Agent 0 0.5"}
+	if strings.Join(got, ", ") != strings.Join(want, ", ") {
+		t.Errorf("expected rows %v, got %v", want, got)
+	}
+
+	var metricName string
+	if err := db.QueryRow(`SELECT full_name FROM player_names WHERE player_id = 1 AND source = 'catcher_framing'`).Scan(&metricName); err != nil || metricName != "Johnny Doe" {
+		t.Errorf("expected the framing name to survive in player_names, got %q, %v", metricName, err)
+	}
+}
+
+func TestMigrate_ResolvesMetricTeamsWithGivenRegistry(t *testing.T) {
+	db, err := Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(legacyOAATable + `
+		CREATE TABLE arm_strength (
+			id INTEGER PRIMARY KEY AUTOINCREMENT, player_id INTEGER NOT NULL, full_name TEXT, team TEXT,
+			arm_overall REAL, max_arm_strength REAL, date DATE NOT NULL, UNIQUE(player_id, date)
+		);
+		INSERT INTO arm_strength (player_id, full_name, team, arm_overall, date) VALUES
+			(1, 'Andre Dawson', 'Expos', 90.5, '2004-06-01');`); err != nil {
+		t.Fatalf("failed to create legacy schema: %v", err)
+	}
+
+	if _, err := Migrate(db, teams.Default()); err == nil || !strings.Contains(err.Error(), "unknown team Expos (2004)") {
+		t.Fatalf("expected the embedded registry to reject Expos, got %v", err)
+	}
+
+	registry, err := teams.Parse(strings.NewReader(`[
+		{"id": 120, "abbreviation": "MON", "name": "Montreal Expos", "short_name": "Expos", "league": "NL", "division": "East", "valid_to": 2004},
+		{"id": 120, "abbreviation": "WSH", "name": "Washington Nationals", "short_name": "Nationals", "league": "NL", "division": "East", "valid_from": 2005}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Migrate(db, registry); err != nil {
+		t.Fatalf("Migrate returned error: %v", err)
+	}
+	var teamID int
+	if err := db.QueryRow(`SELECT team_id FROM arm_strength WHERE player_id = 1`).Scan(&teamID); err != nil || teamID != 120 {
+		t.Errorf("expected Expos to resolve to team 120 through the given registry, got %d, %v", teamID, err)
+	}
+}
+
+func TestMigrate_RefusesNewerSchema(t *testing.T) {
+	db, err := Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err := Migrate(db, teams.Default()); err != nil {
+		t.Fatalf("Migrate returned error: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO schema_migrations (version, name, applied_at) VALUES (9999, 'from_the_future', CURRENT_TIMESTAMP)`); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := CheckSchemaVersion(db); !errors.Is(err, ErrSchemaTooNew) {
+		t.Errorf("expected ErrSchemaTooNew from CheckSchemaVersion, got %v", err)
+	}
+	if err := EnsureSchema(db, teams.Default()); !errors.Is(err, ErrSchemaTooNew) {
+		t.Errorf("expected ErrSchemaTooNew from EnsureSchema, got %v", err)
+	}
+
+	statuses, err := Status(db)
+	if err != nil {
+		t.Fatalf("Status returned error: %v", err)
+	}
+	last := statuses[len(statuses)-1]
+	if last.Version != 9999 || last.Known || last.AppliedAt == nil {
+		t.Errorf("expected the unknown migration last in the status, got %+v", last)
+	}
+}
+
+func TestStatus_Pending(t *testing.T) {
+	db, err := Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	statuses, err := Status(db)
+	if err != nil {
+		t.Fatalf("Status returned error: %v", err)
+	}
+	if len(statuses) == 0 || statuses[0].Version != 1 || statuses[0].AppliedAt != nil {
+		t.Errorf("expected the baseline to be pending, got %+v", statuses)
+	}
+}
@@ -0,0 +1,120 @@
+-- Baseline: the schema EnsureSchema created before migrations existed. Every
+-- statement is idempotent so databases created by earlier releases adopt it.
+
+CREATE TABLE IF NOT EXISTS outs_above_average (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	player_id INTEGER,
+	first_name TEXT,
+	last_name TEXT,
+	full_name TEXT,
+	team TEXT,
+	primary_position TEXT,
+	oaa INTEGER,
+	actual_success_rate REAL,
+	estimated_success_rate REAL,
+	diff_success_rate REAL,
+	date DATE DEFAULT CURRENT_DATE,
+	UNIQUE(player_id, date)
+);
+
+CREATE TABLE IF NOT EXISTS player_team_stints (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	player_id INTEGER NOT NULL,
+	team TEXT NOT NULL,
+	season INTEGER NOT NULL,
+	start_date DATE NOT NULL,
+	end_date DATE NOT NULL,
+	oaa_before INTEGER NOT NULL,
+	oaa_after INTEGER NOT NULL,
+	UNIQUE(player_id, start_date)
+);
+
+CREATE TABLE IF NOT EXISTS ingest_runs (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	started_at TIMESTAMP NOT NULL,
+	finished_at TIMESTAMP NOT NULL,
+	source TEXT NOT NULL,
+	snapshot_date DATE,
+	row_count INTEGER NOT NULL DEFAULT 0,
+	inserted_count INTEGER NOT NULL DEFAULT 0,
+	updated_count INTEGER NOT NULL DEFAULT 0,
+	unchanged_count INTEGER NOT NULL DEFAULT 0,
+	csv_sha256 TEXT,
+	etag TEXT,
+	last_modified TEXT,
+	error TEXT
+);
+
+CREATE TABLE IF NOT EXISTS fielding_run_value (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	player_id INTEGER NOT NULL,
+	full_name TEXT,
+	team TEXT,
+	total_runs REAL,
+	arm_runs REAL,
+	range_runs REAL,
+	date DATE NOT NULL,
+	UNIQUE(player_id, date)
+);
+
+CREATE TABLE IF NOT EXISTS arm_strength (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	player_id INTEGER NOT NULL,
+	full_name TEXT,
+	team TEXT,
+	arm_overall REAL,
+	max_arm_strength REAL,
+	date DATE NOT NULL,
+	UNIQUE(player_id, date)
+);
+
+CREATE TABLE IF NOT EXISTS catcher_framing (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	player_id INTEGER NOT NULL,
+	full_name TEXT,
+	team TEXT,
+	framing_runs REAL,
+	strike_rate REAL,
+	date DATE NOT NULL,
+	UNIQUE(player_id, date)
+);
+
+CREATE TABLE IF NOT EXISTS outfield_jump (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	player_id INTEGER NOT NULL,
+	full_name TEXT,
+	team TEXT,
+	jump_feet REAL,
+	reaction_feet REAL,
+	burst_feet REAL,
+	route_feet REAL,
+	date DATE NOT NULL,
+	UNIQUE(player_id, date)
+);
+
+CREATE TABLE IF NOT EXISTS oaa_directional (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	player_id INTEGER NOT NULL,
+	in_front INTEGER,
+	toward_third_base INTEGER,
+	toward_first_base INTEGER,
+	behind INTEGER,
+	date DATE NOT NULL,
+	UNIQUE(player_id, date)
+);
+
+CREATE TABLE IF NOT EXISTS oaa_by_position (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	player_id INTEGER NOT NULL,
+	position TEXT NOT NULL,
+	oaa INTEGER,
+	date DATE NOT NULL,
+	UNIQUE(player_id, position, date)
+);
+
+CREATE INDEX IF NOT EXISTS idx_date ON outs_above_average(date);
+CREATE INDEX IF NOT EXISTS idx_team_lower ON outs_above_average(LOWER(team));
+CREATE INDEX IF NOT EXISTS idx_name ON outs_above_average(last_name, first_name);
+CREATE INDEX IF NOT EXISTS idx_team_date ON outs_above_average(LOWER(team), date);
+CREATE INDEX IF NOT EXISTS idx_ingest_runs_finished ON ingest_runs(finished_at);
+CREATE INDEX IF NOT EXISTS idx_stints_team_season ON player_team_stints(LOWER(team), season);
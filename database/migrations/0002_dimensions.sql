@@ -0,0 +1,128 @@
+-- Players and teams become dimension tables keyed by MLBAM ID. Snapshots and
+-- stints reference them by ID, so a rename or relocation is a row in teams or
+-- team_aliases rather than a rewrite of every snapshot.
+
+CREATE TABLE teams (
+	id INTEGER PRIMARY KEY,
+	name TEXT NOT NULL,
+	abbreviation TEXT NOT NULL
+);
+
+INSERT INTO teams (id, name, abbreviation) VALUES
+	(108, 'Angels', 'LAA'),
+	(109, 'D-backs', 'ARI'),
+	(110, 'Orioles', 'BAL'),
+	(111, 'Red Sox', 'BOS'),
+	(112, 'Cubs', 'CHC'),
+	(113, 'Reds', 'CIN'),
+	(114, 'Guardians', 'CLE'),
+	(115, 'Rockies', 'COL'),
+	(116, 'Tigers', 'DET'),
+	(117, 'Astros', 'HOU'),
+	(118, 'Royals', 'KC'),
+	(119, 'Dodgers', 'LAD'),
+	(120, 'Nationals', 'WSH'),
+	(121, 'Mets', 'NYM'),
+	(133, 'Athletics', 'OAK'),
+	(134, 'Pirates', 'PIT'),
+	(135, 'Padres', 'SD'),
+	(136, 'Mariners', 'SEA'),
+	(137, 'Giants', 'SF'),
+	(138, 'Cardinals', 'STL'),
+	(139, 'Rays', 'TB'),
+	(140, 'Rangers', 'TEX'),
+	(141, 'Blue Jays', 'TOR'),
+	(142, 'Twins', 'MIN'),
+	(143, 'Phillies', 'PHI'),
+	(144, 'Braves', 'ATL'),
+	(145, 'White Sox', 'CWS'),
+	(146, 'Marlins', 'MIA'),
+	(147, 'Yankees', 'NYY'),
+	(158, 'Brewers', 'MIL');
+
+-- team_aliases maps every name Baseball Savant has used for a club to its ID.
+CREATE TABLE team_aliases (
+	alias TEXT PRIMARY KEY COLLATE NOCASE,
+	team_id INTEGER NOT NULL REFERENCES teams(id)
+);
+
+INSERT INTO team_aliases (alias, team_id) SELECT name, id FROM teams;
+INSERT INTO team_aliases (alias, team_id) VALUES
+	('Diamondbacks', 109),
+	('Dbacks', 109),
+	('Indians', 114),
+	('A''s', 133),
+	('Devil Rays', 139);
+
+-- A snapshot or stint whose team has no alias would lose its team. Those names
+-- are collected here, and migrate.go fails the migration listing them.
+CREATE TEMP TABLE unmatched_team_names AS
+SELECT team FROM outs_above_average o
+WHERE o.player_id IS NOT NULL AND COALESCE(o.team, '') != ''
+	AND NOT EXISTS (SELECT 1 FROM team_aliases a WHERE a.alias = o.team)
+UNION
+SELECT team FROM player_team_stints s
+WHERE NOT EXISTS (SELECT 1 FROM team_aliases a WHERE a.alias = s.team);
+
+-- updated_on is the snapshot date the name came from, so backfilling an old
+-- season never overwrites a later name correction.
+CREATE TABLE players (
+	id INTEGER PRIMARY KEY,
+	first_name TEXT,
+	last_name TEXT,
+	full_name TEXT NOT NULL,
+	updated_on DATE NOT NULL
+);
+
+INSERT INTO players (id, first_name, last_name, full_name, updated_on)
+SELECT player_id, first_name, last_name, COALESCE(full_name, ''), date
+FROM outs_above_average
+WHERE player_id IS NOT NULL
+	AND (player_id, date) IN (SELECT player_id, MAX(date) FROM outs_above_average GROUP BY player_id);
+
+CREATE TABLE outs_above_average_new (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	player_id INTEGER NOT NULL REFERENCES players(id),
+	team_id INTEGER REFERENCES teams(id),
+	primary_position TEXT,
+	oaa INTEGER,
+	actual_success_rate REAL,
+	estimated_success_rate REAL,
+	diff_success_rate REAL,
+	date DATE DEFAULT CURRENT_DATE,
+	UNIQUE(player_id, date)
+);
+
+INSERT INTO outs_above_average_new (id, player_id, team_id, primary_position, oaa, actual_success_rate, estimated_success_rate, diff_success_rate, date)
+SELECT o.id, o.player_id, a.team_id, o.primary_position, o.oaa, o.actual_success_rate, o.estimated_success_rate, o.diff_success_rate, o.date
+FROM outs_above_average o
+LEFT JOIN team_aliases a ON a.alias = o.team
+WHERE o.player_id IS NOT NULL;
+
+DROP TABLE outs_above_average;
+ALTER TABLE outs_above_average_new RENAME TO outs_above_average;
+
+CREATE INDEX idx_date ON outs_above_average(date);
+CREATE INDEX idx_team_date ON outs_above_average(team_id, date);
+
+CREATE TABLE player_team_stints_new (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	player_id INTEGER NOT NULL REFERENCES players(id),
+	team_id INTEGER NOT NULL REFERENCES teams(id),
+	season INTEGER NOT NULL,
+	start_date DATE NOT NULL,
+	end_date DATE NOT NULL,
+	oaa_before INTEGER NOT NULL,
+	oaa_after INTEGER NOT NULL,
+	UNIQUE(player_id, start_date)
+);
+
+INSERT INTO player_team_stints_new (id, player_id, team_id, season, start_date, end_date, oaa_before, oaa_after)
+SELECT s.id, s.player_id, a.team_id, s.season, s.start_date, s.end_date, s.oaa_before, s.oaa_after
+FROM player_team_stints s
+JOIN team_aliases a ON a.alias = s.team;
+
+DROP TABLE player_team_stints;
+ALTER TABLE player_team_stints_new RENAME TO player_team_stints;
+
+CREATE INDEX idx_stints_team_season ON player_team_stints(team_id, season);
@@ -0,0 +1,4 @@
+-- Team names are resolved by the team registry in the teams package, which also
+-- keeps the teams table current, so the alias table is no longer read.
+
+DROP TABLE team_aliases;
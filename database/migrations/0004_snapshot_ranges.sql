@@ -0,0 +1,24 @@
+-- Snapshot rows may cover a range of snapshot dates: a row with valid_to set
+-- stands for every date in snapshot_dates from its date through valid_to, which
+-- is how compaction collapses runs of unchanged rows. Readers use the
+-- oaa_snapshots view, which expands ranges back into one row per date.
+
+ALTER TABLE outs_above_average ADD COLUMN valid_to DATE;
+
+CREATE TABLE snapshot_dates (
+	date DATE PRIMARY KEY
+);
+
+INSERT INTO snapshot_dates (date) SELECT DISTINCT date FROM outs_above_average;
+
+CREATE VIEW oaa_snapshots AS
+SELECT o.player_id, o.team_id, o.primary_position, o.oaa,
+	o.actual_success_rate, o.estimated_success_rate, o.diff_success_rate, o.date
+FROM outs_above_average o
+WHERE o.valid_to IS NULL
+UNION ALL
+SELECT o.player_id, o.team_id, o.primary_position, o.oaa,
+	o.actual_success_rate, o.estimated_success_rate, o.diff_success_rate, s.date
+FROM outs_above_average o
+JOIN snapshot_dates s ON s.date >= o.date AND s.date <= o.valid_to
+WHERE o.valid_to IS NOT NULL;
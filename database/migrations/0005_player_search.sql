@@ -0,0 +1,91 @@
+-- player_search is a full-text index over player names. The unicode61
+-- tokenizer folds accents, so "Jose Ramirez" finds "José Ramírez", and the
+-- aliases column holds the common variants of a player's first name, so "Mike"
+-- finds a Michael and "Michael" finds a Mike. Triggers on players keep it
+-- current; the rowid is the player ID.
+
+CREATE TABLE given_name_variants (
+	name TEXT NOT NULL COLLATE NOCASE,
+	variant TEXT NOT NULL COLLATE NOCASE,
+	PRIMARY KEY (name, variant)
+);
+
+INSERT INTO given_name_variants (name, variant) VALUES
+	('Alexander', 'Alex'),
+	('Alejandro', 'Alex'),
+	('Andrew', 'Andy'),
+	('Anthony', 'Tony'),
+	('Antonio', 'Tony'),
+	('Benjamin', 'Ben'),
+	('Bradley', 'Brad'),
+	('Cameron', 'Cam'),
+	('Christopher', 'Chris'),
+	('Daniel', 'Dan'),
+	('Daniel', 'Danny'),
+	('Edward', 'Ed'),
+	('Edward', 'Eddie'),
+	('Eduardo', 'Eddie'),
+	('Enrique', 'Kike'),
+	('Francisco', 'Frankie'),
+	('Gregory', 'Greg'),
+	('Jacob', 'Jake'),
+	('James', 'Jim'),
+	('James', 'Jimmy'),
+	('Jeffrey', 'Jeff'),
+	('Jonathan', 'Jon'),
+	('Joseph', 'Joe'),
+	('Joshua', 'Josh'),
+	('Kenneth', 'Ken'),
+	('Matthew', 'Matt'),
+	('Michael', 'Mike'),
+	('Nathan', 'Nate'),
+	('Nathaniel', 'Nate'),
+	('Nicholas', 'Nick'),
+	('Patrick', 'Pat'),
+	('Richard', 'Rich'),
+	('Richard', 'Ricky'),
+	('Ricardo', 'Ricky'),
+	('Robert', 'Bob'),
+	('Robert', 'Bobby'),
+	('Robert', 'Rob'),
+	('Samuel', 'Sam'),
+	('Stephen', 'Steve'),
+	('Steven', 'Steve'),
+	('Thomas', 'Tom'),
+	('Thomas', 'Tommy'),
+	('Timothy', 'Tim'),
+	('William', 'Bill'),
+	('William', 'Will'),
+	('William', 'Willie'),
+	('Zachary', 'Zach');
+
+INSERT INTO given_name_variants (name, variant)
+SELECT variant, name FROM given_name_variants;
+
+CREATE VIRTUAL TABLE player_search USING fts5(
+	name,
+	aliases,
+	tokenize = 'unicode61 remove_diacritics 2'
+);
+
+INSERT INTO player_search (rowid, name, aliases)
+SELECT p.id, p.full_name,
+	(SELECT COALESCE(GROUP_CONCAT(v.variant, ' '), '') FROM given_name_variants v WHERE v.name = p.first_name)
+FROM players p;
+
+CREATE TRIGGER players_search_insert AFTER INSERT ON players BEGIN
+	INSERT INTO player_search (rowid, name, aliases)
+	VALUES (new.id, new.full_name,
+		(SELECT COALESCE(GROUP_CONCAT(v.variant, ' '), '') FROM given_name_variants v WHERE v.name = new.first_name));
+END;
+
+CREATE TRIGGER players_search_update AFTER UPDATE OF first_name, full_name ON players BEGIN
+	DELETE FROM player_search WHERE rowid = old.id;
+	INSERT INTO player_search (rowid, name, aliases)
+	VALUES (new.id, new.full_name,
+		(SELECT COALESCE(GROUP_CONCAT(v.variant, ' '), '') FROM given_name_variants v WHERE v.name = new.first_name));
+END;
+
+CREATE TRIGGER players_search_delete AFTER DELETE ON players BEGIN
+	DELETE FROM player_search WHERE rowid = old.id;
+END;
@@ -0,0 +1,32 @@
+-- player_names records every name a leaderboard has given a player ID, with
+-- the first snapshot date it appeared on. players keeps only the latest OAA
+-- name, so this history is where conflicting names show up.
+
+CREATE TABLE player_names (
+	player_id INTEGER NOT NULL,
+	full_name TEXT NOT NULL,
+	source TEXT NOT NULL,
+	first_seen DATE NOT NULL,
+	PRIMARY KEY (player_id, full_name, source)
+);
+
+-- Earlier OAA names were overwritten in players, so only the current one is
+-- known; updated_on is the earliest date it can be vouched for.
+INSERT INTO player_names (player_id, full_name, source, first_seen)
+SELECT id, full_name, 'outs_above_average', updated_on
+FROM players
+WHERE full_name != '';
+
+INSERT INTO player_names (player_id, full_name, source, first_seen)
+SELECT player_id, full_name, source, MIN(date)
+FROM (
+	SELECT player_id, full_name, 'fielding_run_value' AS source, date FROM fielding_run_value
+	UNION ALL
+	SELECT player_id, full_name, 'arm_strength', date FROM arm_strength
+	UNION ALL
+	SELECT player_id, full_name, 'catcher_framing', date FROM catcher_framing
+	UNION ALL
+	SELECT player_id, full_name, 'outfield_jump', date FROM outfield_jump
+)
+WHERE COALESCE(full_name, '') != ''
+GROUP BY player_id, full_name, source;
@@ -0,0 +1,96 @@
+-- Metric leaderboards reference the player and team dimensions like OAA
+-- snapshots do, instead of keeping their own copies of names. Players seen only
+-- on a metric leaderboard, such as most catchers, join the players table, and
+-- the names the metric tables held are already in player_names.
+-- migrate.go resolves the stored team names through the team registry into
+-- temp.metric_team_ids before this runs.
+
+INSERT OR IGNORE INTO players (id, full_name, updated_on)
+SELECT player_id, COALESCE(full_name, ''), MAX(date)
+FROM (
+	SELECT player_id, full_name, date FROM fielding_run_value
+	UNION ALL
+	SELECT player_id, full_name, date FROM arm_strength
+	UNION ALL
+	SELECT player_id, full_name, date FROM catcher_framing
+	UNION ALL
+	SELECT player_id, full_name, date FROM outfield_jump
+)
+GROUP BY player_id;
+
+CREATE TABLE fielding_run_value_new (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	player_id INTEGER NOT NULL REFERENCES players(id),
+	team_id INTEGER REFERENCES teams(id),
+	total_runs REAL,
+	arm_runs REAL,
+	range_runs REAL,
+	date DATE NOT NULL,
+	UNIQUE(player_id, date)
+);
+
+INSERT INTO fielding_run_value_new (id, player_id, team_id, total_runs, arm_runs, range_runs, date)
+SELECT f.id, f.player_id, m.team_id, f.total_runs, f.arm_runs, f.range_runs, f.date
+FROM fielding_run_value f
+LEFT JOIN temp.metric_team_ids m ON m.team = f.team AND m.season = CAST(strftime('%Y', f.date) AS INTEGER);
+
+DROP TABLE fielding_run_value;
+ALTER TABLE fielding_run_value_new RENAME TO fielding_run_value;
+
+CREATE TABLE arm_strength_new (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	player_id INTEGER NOT NULL REFERENCES players(id),
+	team_id INTEGER REFERENCES teams(id),
+	arm_overall REAL,
+	max_arm_strength REAL,
+	date DATE NOT NULL,
+	UNIQUE(player_id, date)
+);
+
+INSERT INTO arm_strength_new (id, player_id, team_id, arm_overall, max_arm_strength, date)
+SELECT a.id, a.player_id, m.team_id, a.arm_overall, a.max_arm_strength, a.date
+FROM arm_strength a
+LEFT JOIN temp.metric_team_ids m ON m.team = a.team AND m.season = CAST(strftime('%Y', a.date) AS INTEGER);
+
+DROP TABLE arm_strength;
+ALTER TABLE arm_strength_new RENAME TO arm_strength;
+
+CREATE TABLE catcher_framing_new (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	player_id INTEGER NOT NULL REFERENCES players(id),
+	team_id INTEGER REFERENCES teams(id),
+	framing_runs REAL,
+	strike_rate REAL,
+	date DATE NOT NULL,
+	UNIQUE(player_id, date)
+);
+
+INSERT INTO catcher_framing_new (id, player_id, team_id, framing_runs, strike_rate, date)
+SELECT c.id, c.player_id, m.team_id, c.framing_runs, c.strike_rate, c.date
+FROM catcher_framing c
+LEFT JOIN temp.metric_team_ids m ON m.team = c.team AND m.season = CAST(strftime('%Y', c.date) AS INTEGER);
+
+DROP TABLE catcher_framing;
+ALTER TABLE catcher_framing_new RENAME TO catcher_framing;
+
+CREATE TABLE outfield_jump_new (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	player_id INTEGER NOT NULL REFERENCES players(id),
+	team_id INTEGER REFERENCES teams(id),
+	jump_feet REAL,
+	reaction_feet REAL,
+	burst_feet REAL,
+	route_feet REAL,
+	date DATE NOT NULL,
+	UNIQUE(player_id, date)
+);
+
+INSERT INTO outfield_jump_new (id, player_id, team_id, jump_feet, reaction_feet, burst_feet, route_feet, date)
+SELECT j.id, j.player_id, m.team_id, j.jump_feet, j.reaction_feet, j.burst_feet, j.route_feet, j.date
+FROM outfield_jump j
+LEFT JOIN temp.metric_team_ids m ON m.team = j.team AND m.season = CAST(strftime('%Y', j.date) AS INTEGER);
+
+DROP TABLE outfield_jump;
+ALTER TABLE outfield_jump_new RENAME TO outfield_jump;
+
+DROP TABLE temp.metric_team_ids;
@@ -0,0 +1,128 @@
+// Package export writes OAA snapshots as CSV, JSON Lines or Parquet files for
+// analysis outside the site.
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/benfb/oaamonitor/models"
+)
+
+// Format is an export file format.
+type Format string
+
+const (
+	CSV     Format = "csv"
+	JSONL   Format = "jsonl"
+	Parquet Format = "parquet"
+)
+
+// Formats lists every supported format.
+var Formats = []Format{CSV, JSONL, Parquet}
+
+// ParseFormat returns the format named by value.
+func ParseFormat(value string) (Format, error) {
+	for _, format := range Formats {
+		if strings.EqualFold(value, string(format)) {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("unknown export format %q (want csv, jsonl or parquet)", value)
+}
+
+// Extension returns the file extension for the format, without the dot.
+func (f Format) Extension() string {
+	return string(f)
+}
+
+type kind int
+
+const (
+	kindInt kind = iota
+	kindString
+	kindDate
+	kindFloat
+)
+
+// column is one field of models.Stat. CSV and Parquet columns are named after
+// the Stat JSON tags, so every format has the same shape.
+type column struct {
+	name  string
+	kind  kind
+	value func(models.Stat) any
+}
+
+var columns = []column{
+	{"player_id", kindInt, func(s models.Stat) any { return s.PlayerID }},
+	{"name", kindString, func(s models.Stat) any { return s.Name }},
+	{"team_id", kindInt, func(s models.Stat) any { return s.TeamID }},
+	{"team", kindString, func(s models.Stat) any { return s.Team }},
+	{"position", kindString, func(s models.Stat) any { return s.Position }},
+	{"oaa", kindInt, func(s models.Stat) any { return s.OAA }},
+	{"date", kindDate, func(s models.Stat) any { return s.Date }},
+	{"actual_success_rate", kindFloat, func(s models.Stat) any { return s.ActualSuccessRate }},
+	{"estimated_success_rate", kindFloat, func(s models.Stat) any { return s.EstimatedSuccessRate }},
+	{"diff_success_rate", kindFloat, func(s models.Stat) any { return s.DiffSuccessRate }},
+}
+
+// Write encodes stats to w in the given format.
+func Write(w io.Writer, format Format, stats []models.Stat) error {
+	switch format {
+	case CSV:
+		return writeCSV(w, stats)
+	case JSONL:
+		return writeJSONL(w, stats)
+	case Parquet:
+		return writeParquet(w, stats)
+	default:
+		return fmt.Errorf("unknown export format %q", format)
+	}
+}
+
+func writeCSV(w io.Writer, stats []models.Stat) error {
+	writer := csv.NewWriter(w)
+	header := make([]string, len(columns))
+	for i, col := range columns {
+		header[i] = col.name
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	record := make([]string, len(columns))
+	for _, stat := range stats {
+		for i, col := range columns {
+			switch value := col.value(stat).(type) {
+			case int:
+				record[i] = strconv.Itoa(value)
+			case string:
+				record[i] = value
+			case time.Time:
+				record[i] = value.Format("2006-01-02")
+			case float64:
+				record[i] = strconv.FormatFloat(value, 'f', -1, 64)
+			}
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func writeJSONL(w io.Writer, stats []models.Stat) error {
+	enc := json.NewEncoder(w)
+	for _, stat := range stats {
+		if err := enc.Encode(stat); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,640 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/benfb/oaamonitor/models"
+)
+
+var testStats = []models.Stat{
+	{PlayerID: 1, Name: "John Doe", TeamID: 147, Team: "Yankees", Position: "SS", OAA: 5, Date: time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC), ActualSuccessRate: 0.8, EstimatedSuccessRate: 0.75, DiffSuccessRate: 0.05},
+	{PlayerID: 2, Name: "Jane \"JJ\" Smith", TeamID: 111, Team: "Red Sox", Position: "CF", OAA: -2, Date: time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC), ActualSuccessRate: 0.7, EstimatedSuccessRate: 0.72, DiffSuccessRate: -0.02},
+}
+
+func TestParseFormat(t *testing.T) {
+	if format, err := ParseFormat("JSONL"); err != nil || format != JSONL {
+		t.Errorf("ParseFormat(JSONL) = %q, %v", format, err)
+	}
+	if _, err := ParseFormat("xlsx"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
+
+func TestWrite_CSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, CSV, testStats); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	want := `player_id,name,team_id,team,position,oaa,date,actual_success_rate,estimated_success_rate,diff_success_rate
+1,John Doe,147,Yankees,SS,5,2025-06-01,0.8,0.75,0.05
+2,"Jane ""JJ"" Smith",111,Red Sox,CF,-2,2025-06-02,0.7,0.72,-0.02
+`
+	if buf.String() != want {
+		t.Errorf("unexpected CSV:\n%s", buf.String())
+	}
+}
+
+func TestWrite_JSONL(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, JSONL, testStats); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+
+	scanner := bufio.NewScanner(&buf)
+	var got []models.Stat
+	for scanner.Scan() {
+		var stat models.Stat
+		if err := json.Unmarshal(scanner.Bytes(), &stat); err != nil {
+			t.Fatalf("line %q is not a Stat: %v", scanner.Text(), err)
+		}
+		got = append(got, stat)
+	}
+	if len(got) != len(testStats) || got[1] != testStats[1] {
+		t.Errorf("unexpected JSON Lines round trip: %+v", got)
+	}
+}
+
+func TestWrite_Parquet(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, Parquet, testStats); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+
+	file, err := readParquetFile(buf.Bytes())
+	if err != nil {
+		t.Fatalf("reading the file back: %v", err)
+	}
+
+	wantSchema := []parquetTestColumn{
+		{"player_id", specInt32, specConvertedNone},
+		{"name", specByteArray, specConvertedUTF8},
+		{"team_id", specInt32, specConvertedNone},
+		{"team", specByteArray, specConvertedUTF8},
+		{"position", specByteArray, specConvertedUTF8},
+		{"oaa", specInt32, specConvertedNone},
+		{"date", specInt32, specConvertedDate},
+		{"actual_success_rate", specDouble, specConvertedNone},
+		{"estimated_success_rate", specDouble, specConvertedNone},
+		{"diff_success_rate", specDouble, specConvertedNone},
+	}
+	if !reflect.DeepEqual(file.schema, wantSchema) {
+		t.Errorf("unexpected schema:\n got %v\nwant %v", file.schema, wantSchema)
+	}
+	if file.numRows != int64(len(testStats)) {
+		t.Errorf("expected %d rows, got %d", len(testStats), file.numRows)
+	}
+
+	// Dates are days since the Unix epoch: 2025-06-01 is day 20240.
+	wantValues := map[string][]any{
+		"player_id":              {int32(1), int32(2)},
+		"name":                   {"John Doe", `Jane "JJ" Smith`},
+		"team_id":                {int32(147), int32(111)},
+		"team":                   {"Yankees", "Red Sox"},
+		"position":               {"SS", "CF"},
+		"oaa":                    {int32(5), int32(-2)},
+		"date":                   {int32(20240), int32(20241)},
+		"actual_success_rate":    {0.8, 0.7},
+		"estimated_success_rate": {0.75, 0.72},
+		"diff_success_rate":      {0.05, -0.02},
+	}
+	if !reflect.DeepEqual(file.values, wantValues) {
+		t.Errorf("unexpected values:\n got %v\nwant %v", file.values, wantValues)
+	}
+}
+
+// readParquet prints a Parquet file's schema and rows as JSON using pyarrow,
+// a reader that shares no code or assumptions with the writer.
+const readParquet = `
+import json, sys
+import pyarrow.parquet as pq
+
+table = pq.read_table(sys.argv[1])
+json.dump({
+    "schema": [[f.name, str(f.type), f.nullable] for f in table.schema],
+    "rows": table.to_pylist(),
+}, sys.stdout, default=str)
+`
+
+func TestWrite_ParquetReadsInPyArrow(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, Parquet, testStats); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	data := buf.Bytes()
+
+	if err := exec.Command("python3", "-c", "import pyarrow.parquet").Run(); err != nil {
+		// CI installs pyarrow, so a missing reader there is a failure rather
+		// than a reason to skip the check.
+		if os.Getenv("CI") != "" {
+			t.Fatalf("pyarrow is required in CI: %v", err)
+		}
+		t.Skipf("pyarrow is not available: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "oaa.parquet")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out, err := exec.Command("python3", "-c", readParquet, path).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			t.Fatalf("pyarrow could not read the file: %v\n%s", err, exitErr.Stderr)
+		}
+		t.Fatal(err)
+	}
+
+	var got struct {
+		Schema [][]any          `json:"schema"`
+		Rows   []map[string]any `json:"rows"`
+	}
+	if err := json.Unmarshal(out, &got); err != nil {
+		t.Fatalf("decoding pyarrow output %q: %v", out, err)
+	}
+
+	wantSchema := [][]any{
+		{"player_id", "int32", false},
+		{"name", "string", false},
+		{"team_id", "int32", false},
+		{"team", "string", false},
+		{"position", "string", false},
+		{"oaa", "int32", false},
+		{"date", "date32[day]", false},
+		{"actual_success_rate", "double", false},
+		{"estimated_success_rate", "double", false},
+		{"diff_success_rate", "double", false},
+	}
+	if !reflect.DeepEqual(got.Schema, wantSchema) {
+		t.Errorf("unexpected schema %v", got.Schema)
+	}
+
+	wantRows := []map[string]any{
+		{"player_id": 1.0, "name": "John Doe", "team_id": 147.0, "team": "Yankees", "position": "SS", "oaa": 5.0, "date": "2025-06-01", "actual_success_rate": 0.8, "estimated_success_rate": 0.75, "diff_success_rate": 0.05},
+		{"player_id": 2.0, "name": "Jane \"JJ\" Smith", "team_id": 111.0, "team": "Red Sox", "position": "CF", "oaa": -2.0, "date": "2025-06-02", "actual_success_rate": 0.7, "estimated_success_rate": 0.72, "diff_success_rate": -0.02},
+	}
+	if !reflect.DeepEqual(got.Rows, wantRows) {
+		t.Errorf("unexpected rows read by pyarrow:\n got %v\nwant %v", got.Rows, wantRows)
+	}
+}
+
+// Values from parquet.thrift in the Parquet format specification. They are
+// spelled out here rather than borrowed from the writer so that a wrong enum
+// or field ID in parquet.go fails the test instead of agreeing with it.
+const (
+	specInt32     = 1
+	specDouble    = 5
+	specByteArray = 6
+
+	specRequired = 0
+
+	specConvertedNone = -1
+	specConvertedUTF8 = 0
+	specConvertedDate = 6
+
+	specPlain        = 0
+	specRLE          = 3
+	specUncompressed = 0
+	specDataPage     = 0
+)
+
+type parquetTestColumn struct {
+	name      string
+	physical  int32
+	converted int32
+}
+
+type parquetTestFile struct {
+	schema  []parquetTestColumn
+	numRows int64
+	values  map[string][]any
+}
+
+// readParquetFile decodes a single row group file of required, PLAIN encoded,
+// uncompressed columns, checking every field parquet.thrift marks required.
+func readParquetFile(data []byte) (parquetTestFile, error) {
+	var file parquetTestFile
+	if len(data) < 12 || string(data[:4]) != "PAR1" || string(data[len(data)-4:]) != "PAR1" {
+		return file, fmt.Errorf("file is not framed by PAR1")
+	}
+	footerLen := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	footerStart := len(data) - 8 - footerLen
+	if footerLen <= 0 || footerStart < 4 {
+		return file, fmt.Errorf("footer length %d out of range for a %d byte file", footerLen, len(data))
+	}
+
+	footerReader := &compactReader{data: data[footerStart : len(data)-8]}
+	meta := footerReader.readStruct()
+	if footerReader.err == nil && footerReader.pos != footerLen {
+		return file, fmt.Errorf("FileMetaData is %d bytes, footer length says %d", footerReader.pos, footerLen)
+	}
+	if footerReader.err != nil {
+		return file, fmt.Errorf("FileMetaData: %v", footerReader.err)
+	}
+
+	// FileMetaData: 1 version, 2 schema, 3 num_rows, 4 row_groups.
+	if _, err := meta.i32(1); err != nil {
+		return file, err
+	}
+	var err error
+	if file.numRows, err = meta.i64(3); err != nil {
+		return file, err
+	}
+	schema, err := meta.structs(2)
+	if err != nil {
+		return file, err
+	}
+	if len(schema) == 0 {
+		return file, fmt.Errorf("schema has no root element")
+	}
+	// SchemaElement: 1 type, 3 repetition_type, 4 name, 5 num_children,
+	// 6 converted_type. The root is a group, so it has no type.
+	if _, ok := schema[0][1]; ok {
+		return file, fmt.Errorf("schema root has a physical type")
+	}
+	children, err := schema[0].i32(5)
+	if err != nil {
+		return file, err
+	}
+	if int(children) != len(schema)-1 {
+		return file, fmt.Errorf("schema root has %d children, %d elements follow", children, len(schema)-1)
+	}
+	for _, element := range schema[1:] {
+		var column parquetTestColumn
+		if column.name, err = element.str(4); err != nil {
+			return file, err
+		}
+		if column.physical, err = element.i32(1); err != nil {
+			return file, err
+		}
+		if repetition, err := element.i32(3); err != nil || repetition != specRequired {
+			return file, fmt.Errorf("column %s: repetition %d, %v", column.name, repetition, err)
+		}
+		column.converted = specConvertedNone
+		if _, ok := element[6]; ok {
+			if column.converted, err = element.i32(6); err != nil {
+				return file, err
+			}
+		}
+		file.schema = append(file.schema, column)
+	}
+
+	rowGroups, err := meta.structs(4)
+	if err != nil {
+		return file, err
+	}
+	if len(rowGroups) != 1 {
+		return file, fmt.Errorf("expected one row group, got %d", len(rowGroups))
+	}
+	// RowGroup: 1 columns, 2 total_byte_size, 3 num_rows.
+	group := rowGroups[0]
+	if rows, err := group.i64(3); err != nil || rows != file.numRows {
+		return file, fmt.Errorf("row group has %d rows, file has %d: %v", rows, file.numRows, err)
+	}
+	totalSize, err := group.i64(2)
+	if err != nil {
+		return file, err
+	}
+	chunks, err := group.structs(1)
+	if err != nil {
+		return file, err
+	}
+	if len(chunks) != len(file.schema) {
+		return file, fmt.Errorf("row group has %d column chunks for %d columns", len(chunks), len(file.schema))
+	}
+
+	file.values = make(map[string][]any)
+	var chunkTotal int64
+	next := int64(4)
+	for i, chunk := range chunks {
+		column := file.schema[i]
+		values, size, err := readColumnChunk(data[:footerStart], chunk, column, file.numRows)
+		if err != nil {
+			return file, fmt.Errorf("column %s: %v", column.name, err)
+		}
+		offset, _ := chunk[3].(compactStruct).i64(9)
+		if offset != next {
+			return file, fmt.Errorf("column %s starts at %d, expected %d", column.name, offset, next)
+		}
+		next += size
+		chunkTotal += size
+		file.values[column.name] = values
+	}
+	if next != int64(footerStart) {
+		return file, fmt.Errorf("column chunks end at %d, footer starts at %d", next, footerStart)
+	}
+	if chunkTotal != totalSize {
+		return file, fmt.Errorf("row group total_byte_size %d, chunks add up to %d", totalSize, chunkTotal)
+	}
+	return file, nil
+}
+
+// readColumnChunk decodes a column chunk holding one data page and returns its
+// values and size in bytes.
+func readColumnChunk(data []byte, chunk compactStruct, column parquetTestColumn, numRows int64) ([]any, int64, error) {
+	// ColumnChunk: 2 file_offset, 3 meta_data.
+	if _, err := chunk.i64(2); err != nil {
+		return nil, 0, err
+	}
+	meta, ok := chunk[3].(compactStruct)
+	if !ok {
+		return nil, 0, fmt.Errorf("missing ColumnMetaData")
+	}
+	// ColumnMetaData: 1 type, 2 encodings, 3 path_in_schema, 4 codec,
+	// 5 num_values, 6 total_uncompressed_size, 7 total_compressed_size,
+	// 9 data_page_offset.
+	if physical, err := meta.i32(1); err != nil || physical != column.physical {
+		return nil, 0, fmt.Errorf("chunk type %d, schema type %d: %v", physical, column.physical, err)
+	}
+	if encodings, ok := meta[2].([]any); !ok || len(encodings) == 0 {
+		return nil, 0, fmt.Errorf("missing encodings")
+	}
+	if path, ok := meta[3].([]any); !ok || len(path) != 1 || path[0] != column.name {
+		return nil, 0, fmt.Errorf("path_in_schema %v", meta[3])
+	}
+	if codec, err := meta.i32(4); err != nil || codec != specUncompressed {
+		return nil, 0, fmt.Errorf("codec %d: %v", codec, err)
+	}
+	if count, err := meta.i64(5); err != nil || count != numRows {
+		return nil, 0, fmt.Errorf("num_values %d for %d rows: %v", count, numRows, err)
+	}
+	uncompressed, err := meta.i64(6)
+	if err != nil {
+		return nil, 0, err
+	}
+	compressed, err := meta.i64(7)
+	if err != nil {
+		return nil, 0, err
+	}
+	if uncompressed != compressed {
+		return nil, 0, fmt.Errorf("uncompressed chunk sizes differ: %d and %d", uncompressed, compressed)
+	}
+	offset, err := meta.i64(9)
+	if err != nil {
+		return nil, 0, err
+	}
+	if offset < 4 || offset+compressed > int64(len(data)) {
+		return nil, 0, fmt.Errorf("chunk %d+%d runs past the column data", offset, compressed)
+	}
+
+	// PageHeader: 1 type, 2 uncompressed_page_size, 3 compressed_page_size,
+	// 5 data_page_header. DataPageHeader: 1 num_values, 2 encoding,
+	// 3 definition_level_encoding, 4 repetition_level_encoding.
+	reader := &compactReader{data: data[offset : offset+compressed]}
+	header := reader.readStruct()
+	if reader.err != nil {
+		return nil, 0, fmt.Errorf("PageHeader: %v", reader.err)
+	}
+	if pageType, err := header.i32(1); err != nil || pageType != specDataPage {
+		return nil, 0, fmt.Errorf("page type %d: %v", pageType, err)
+	}
+	pageSize, err := header.i32(3)
+	if err != nil {
+		return nil, 0, err
+	}
+	if size, err := header.i32(2); err != nil || size != pageSize {
+		return nil, 0, fmt.Errorf("uncompressed page size %d, compressed %d: %v", size, pageSize, err)
+	}
+	if int64(reader.pos)+int64(pageSize) != compressed {
+		return nil, 0, fmt.Errorf("page header and data are %d bytes, chunk is %d", reader.pos+int(pageSize), compressed)
+	}
+	pageHeader, ok := header[5].(compactStruct)
+	if !ok {
+		return nil, 0, fmt.Errorf("missing DataPageHeader")
+	}
+	if count, err := pageHeader.i32(1); err != nil || int64(count) != numRows {
+		return nil, 0, fmt.Errorf("page holds %d values for %d rows: %v", count, numRows, err)
+	}
+	for id, want := range map[int16]int32{2: specPlain, 3: specRLE, 4: specRLE} {
+		if encoding, err := pageHeader.i32(id); err != nil || encoding != want {
+			return nil, 0, fmt.Errorf("DataPageHeader field %d is %d, want %d: %v", id, encoding, want, err)
+		}
+	}
+
+	// Required columns carry no definition or repetition levels, so the page
+	// is only PLAIN values.
+	page := reader.data[reader.pos:]
+	var values []any
+	for range numRows {
+		switch column.physical {
+		case specInt32:
+			if len(page) < 4 {
+				return nil, 0, fmt.Errorf("page ends mid INT32")
+			}
+			values = append(values, int32(binary.LittleEndian.Uint32(page)))
+			page = page[4:]
+		case specDouble:
+			if len(page) < 8 {
+				return nil, 0, fmt.Errorf("page ends mid DOUBLE")
+			}
+			values = append(values, math.Float64frombits(binary.LittleEndian.Uint64(page)))
+			page = page[8:]
+		case specByteArray:
+			if len(page) < 4 {
+				return nil, 0, fmt.Errorf("page ends mid BYTE_ARRAY length")
+			}
+			n := int(binary.LittleEndian.Uint32(page))
+			if len(page) < 4+n {
+				return nil, 0, fmt.Errorf("page ends mid BYTE_ARRAY")
+			}
+			values = append(values, string(page[4:4+n]))
+			page = page[4+n:]
+		default:
+			return nil, 0, fmt.Errorf("unexpected physical type %d", column.physical)
+		}
+	}
+	if len(page) != 0 {
+		return nil, 0, fmt.Errorf("%d bytes left after %d values", len(page), numRows)
+	}
+	return values, compressed, nil
+}
+
+// compactStruct is a decoded Thrift struct keyed by field ID.
+type compactStruct map[int16]any
+
+func (s compactStruct) i32(id int16) (int32, error) {
+	v, ok := s[id].(int32)
+	if !ok {
+		return 0, fmt.Errorf("field %d is %T, want i32", id, s[id])
+	}
+	return v, nil
+}
+
+func (s compactStruct) i64(id int16) (int64, error) {
+	v, ok := s[id].(int64)
+	if !ok {
+		return 0, fmt.Errorf("field %d is %T, want i64", id, s[id])
+	}
+	return v, nil
+}
+
+func (s compactStruct) str(id int16) (string, error) {
+	v, ok := s[id].(string)
+	if !ok {
+		return "", fmt.Errorf("field %d is %T, want binary", id, s[id])
+	}
+	return v, nil
+}
+
+func (s compactStruct) structs(id int16) ([]compactStruct, error) {
+	list, ok := s[id].([]any)
+	if !ok {
+		return nil, fmt.Errorf("field %d is %T, want list", id, s[id])
+	}
+	structs := make([]compactStruct, len(list))
+	for i, element := range list {
+		if structs[i], ok = element.(compactStruct); !ok {
+			return nil, fmt.Errorf("field %d element %d is %T, want struct", id, i, element)
+		}
+	}
+	return structs, nil
+}
+
+// compactReader decodes the Thrift compact protocol, including the types the
+// writer never emits, so an unexpected field is decoded rather than misread.
+// The first error stops decoding and is kept in err.
+type compactReader struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func (r *compactReader) fail(format string, args ...any) {
+	if r.err == nil {
+		r.err = fmt.Errorf("at byte %d: "+format, append([]any{r.pos}, args...)...)
+	}
+}
+
+func (r *compactReader) byte() byte {
+	if r.err != nil {
+		return 0
+	}
+	if r.pos >= len(r.data) {
+		r.fail("unexpected end of data")
+		return 0
+	}
+	b := r.data[r.pos]
+	r.pos++
+	return b
+}
+
+func (r *compactReader) varint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(r.data[r.pos:])
+	if n <= 0 {
+		r.fail("bad varint")
+		return 0
+	}
+	r.pos += n
+	return v
+}
+
+func (r *compactReader) zigzag() int64 {
+	v := r.varint()
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func (r *compactReader) bytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || r.pos+n > len(r.data) {
+		r.fail("%d bytes run past the end", n)
+		return nil
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b
+}
+
+func (r *compactReader) value(typ byte) any {
+	switch typ {
+	case 1:
+		return true
+	case 2:
+		return false
+	case 3:
+		return int8(r.byte())
+	case 4:
+		return int16(r.zigzag())
+	case 5:
+		return int32(r.zigzag())
+	case 6:
+		return r.zigzag()
+	case 7:
+		return math.Float64frombits(binary.LittleEndian.Uint64(append(r.bytes(8), make([]byte, 8)...)))
+	case 8:
+		return string(r.bytes(int(r.varint())))
+	case 9, 10:
+		header := r.byte()
+		size := int(header >> 4)
+		if size == 15 {
+			size = int(r.varint())
+		}
+		elemType := header & 0x0f
+		values := make([]any, 0, min(size, len(r.data)))
+		for range size {
+			if r.err != nil {
+				break
+			}
+			if elemType == 1 || elemType == 2 {
+				values = append(values, r.byte() == 1)
+				continue
+			}
+			values = append(values, r.value(elemType))
+		}
+		return values
+	case 11:
+		size := int(r.varint())
+		values := make(map[any]any)
+		if size == 0 {
+			return values
+		}
+		types := r.byte()
+		for range size {
+			if r.err != nil {
+				break
+			}
+			key := r.value(types >> 4)
+			values[key] = r.value(types & 0x0f)
+		}
+		return values
+	case 12:
+		return r.readStruct()
+	}
+	r.fail("unknown compact type %d", typ)
+	return nil
+}
+
+func (r *compactReader) readStruct() compactStruct {
+	fields := make(compactStruct)
+	var last int16
+	for r.err == nil {
+		header := r.byte()
+		if header == 0 {
+			return fields
+		}
+		id := last + int16(header>>4)
+		if header>>4 == 0 {
+			id = int16(r.zigzag())
+		}
+		if _, ok := fields[id]; ok {
+			r.fail("field %d appears twice", id)
+		}
+		fields[id] = r.value(header & 0x0f)
+		last = id
+	}
+	return fields
+}
@@ -0,0 +1,282 @@
+package export
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"time"
+
+	"github.com/benfb/oaamonitor/models"
+)
+
+// Parquet files are written as a single row group of uncompressed, PLAIN
+// encoded, required columns: one data page per column, then the file metadata.
+// That is the smallest subset of the format every reader accepts. The tests
+// decode the output with their own reader built from parquet.thrift, and CI and
+// the deploy workflow also read it back with pyarrow.
+
+const parquetMagic = "PAR1"
+
+// Parquet enum values from parquet.thrift.
+const (
+	parquetInt32     = 1
+	parquetDouble    = 5
+	parquetByteArray = 6
+
+	parquetRequired = 0
+
+	parquetConvertedUTF8 = 0
+	parquetConvertedDate = 6
+
+	parquetPlain = 0
+	parquetRLE   = 3
+
+	parquetUncompressed = 0
+	parquetDataPage     = 0
+)
+
+type columnChunk struct {
+	column    column
+	offset    int64
+	size      int64
+	numValues int64
+	physical  int32
+}
+
+func parquetType(k kind) (physical int32, converted int32, hasConverted bool) {
+	switch k {
+	case kindString:
+		return parquetByteArray, parquetConvertedUTF8, true
+	case kindDate:
+		return parquetInt32, parquetConvertedDate, true
+	case kindFloat:
+		return parquetDouble, 0, false
+	default:
+		return parquetInt32, 0, false
+	}
+}
+
+// encodePlain returns a column's values in PLAIN encoding.
+func encodePlain(col column, stats []models.Stat) []byte {
+	var buf bytes.Buffer
+	var scratch [8]byte
+	for _, stat := range stats {
+		switch value := col.value(stat).(type) {
+		case int:
+			binary.LittleEndian.PutUint32(scratch[:4], uint32(int32(value)))
+			buf.Write(scratch[:4])
+		case string:
+			binary.LittleEndian.PutUint32(scratch[:4], uint32(len(value)))
+			buf.Write(scratch[:4])
+			buf.WriteString(value)
+		case time.Time:
+			days := time.Date(value.Year(), value.Month(), value.Day(), 0, 0, 0, 0, time.UTC).Unix() / 86400
+			binary.LittleEndian.PutUint32(scratch[:4], uint32(int32(days)))
+			buf.Write(scratch[:4])
+		case float64:
+			binary.LittleEndian.PutUint64(scratch[:], math.Float64bits(value))
+			buf.Write(scratch[:])
+		}
+	}
+	return buf.Bytes()
+}
+
+func writeParquet(w io.Writer, stats []models.Stat) error {
+	var file bytes.Buffer
+	file.WriteString(parquetMagic)
+
+	chunks := make([]columnChunk, 0, len(columns))
+	for _, col := range columns {
+		data := encodePlain(col, stats)
+
+		var header thriftWriter
+		header.i32(1, parquetDataPage)
+		header.i32(2, int32(len(data)))
+		header.i32(3, int32(len(data)))
+		header.beginStruct(5)
+		header.i32(1, int32(len(stats)))
+		header.i32(2, parquetPlain)
+		header.i32(3, parquetRLE)
+		header.i32(4, parquetRLE)
+		header.endStruct()
+		header.endStruct()
+
+		physical, _, _ := parquetType(col.kind)
+		chunks = append(chunks, columnChunk{
+			column:    col,
+			offset:    int64(file.Len()),
+			size:      int64(header.buf.Len() + len(data)),
+			numValues: int64(len(stats)),
+			physical:  physical,
+		})
+		file.Write(header.buf.Bytes())
+		file.Write(data)
+	}
+
+	footer := parquetFooter(chunks, int64(len(stats)))
+	file.Write(footer)
+	var length [4]byte
+	binary.LittleEndian.PutUint32(length[:], uint32(len(footer)))
+	file.Write(length[:])
+	file.WriteString(parquetMagic)
+
+	_, err := w.Write(file.Bytes())
+	return err
+}
+
+// parquetFooter encodes the FileMetaData struct.
+func parquetFooter(chunks []columnChunk, numRows int64) []byte {
+	var t thriftWriter
+	t.i32(1, 1)
+
+	t.listBegin(2, thriftStruct, len(columns)+1)
+	t.beginElement()
+	t.binary(4, "schema")
+	t.i32(5, int32(len(columns)))
+	t.endStruct()
+	for _, col := range columns {
+		physical, converted, hasConverted := parquetType(col.kind)
+		t.beginElement()
+		t.i32(1, physical)
+		t.i32(3, parquetRequired)
+		t.binary(4, col.name)
+		if hasConverted {
+			t.i32(6, converted)
+		}
+		t.endStruct()
+	}
+
+	t.i64(3, numRows)
+
+	var totalSize int64
+	for _, chunk := range chunks {
+		totalSize += chunk.size
+	}
+	t.listBegin(4, thriftStruct, 1)
+	t.beginElement()
+	t.listBegin(1, thriftStruct, len(chunks))
+	for _, chunk := range chunks {
+		t.beginElement()
+		t.i64(2, chunk.offset)
+		t.beginStruct(3)
+		t.i32(1, chunk.physical)
+		t.listBegin(2, thriftI32, 2)
+		t.listI32(parquetPlain)
+		t.listI32(parquetRLE)
+		t.listBegin(3, thriftBinary, 1)
+		t.listBinary(chunk.column.name)
+		t.i32(4, parquetUncompressed)
+		t.i64(5, chunk.numValues)
+		t.i64(6, chunk.size)
+		t.i64(7, chunk.size)
+		t.i64(9, chunk.offset)
+		t.endStruct()
+		t.endStruct()
+	}
+	t.i64(2, totalSize)
+	t.i64(3, numRows)
+	t.endStruct()
+
+	t.binary(6, "oaamonitor")
+	t.endStruct()
+	return t.buf.Bytes()
+}
+
+// Thrift compact protocol type IDs.
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter encodes structs with the Thrift compact protocol, which is how
+// Parquet serializes page headers and file metadata. The zero value is ready
+// to write the fields of a top-level struct.
+type thriftWriter struct {
+	buf bytes.Buffer
+	// lastField holds the previous field ID of each open struct, since field
+	// headers are delta encoded.
+	lastField []int16
+}
+
+func (t *thriftWriter) field(id int16, typ byte) {
+	if len(t.lastField) == 0 {
+		t.lastField = append(t.lastField, 0)
+	}
+	last := &t.lastField[len(t.lastField)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		t.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		t.buf.WriteByte(typ)
+		t.varint(uint64(zigzag(int64(id))))
+	}
+	*last = id
+}
+
+func (t *thriftWriter) varint(v uint64) {
+	var scratch [binary.MaxVarintLen64]byte
+	t.buf.Write(scratch[:binary.PutUvarint(scratch[:], v)])
+}
+
+func zigzag(v int64) uint64 {
+	return uint64(v<<1) ^ uint64(v>>63)
+}
+
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.field(id, thriftI32)
+	t.varint(zigzag(int64(v)))
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.field(id, thriftI64)
+	t.varint(zigzag(v))
+}
+
+func (t *thriftWriter) binary(id int16, v string) {
+	t.field(id, thriftBinary)
+	t.listBinary(v)
+}
+
+func (t *thriftWriter) listBegin(id int16, elemType byte, size int) {
+	t.field(id, thriftList)
+	if size < 15 {
+		t.buf.WriteByte(byte(size)<<4 | elemType)
+	} else {
+		t.buf.WriteByte(0xf0 | elemType)
+		t.varint(uint64(size))
+	}
+}
+
+func (t *thriftWriter) listI32(v int32) {
+	t.varint(zigzag(int64(v)))
+}
+
+func (t *thriftWriter) listBinary(v string) {
+	t.varint(uint64(len(v)))
+	t.buf.WriteString(v)
+}
+
+// beginStruct opens a struct-valued field.
+func (t *thriftWriter) beginStruct(id int16) {
+	t.field(id, thriftStruct)
+	t.lastField = append(t.lastField, 0)
+}
+
+// beginElement opens a struct inside a list.
+func (t *thriftWriter) beginElement() {
+	if len(t.lastField) == 0 {
+		t.lastField = append(t.lastField, 0)
+	}
+	t.lastField = append(t.lastField, 0)
+}
+
+// endStruct closes the innermost open struct, including the top-level one.
+func (t *thriftWriter) endStruct() {
+	t.buf.WriteByte(0)
+	if len(t.lastField) > 0 {
+		t.lastField = t.lastField[:len(t.lastField)-1]
+	}
+}
@@ -2,7 +2,10 @@ module github.com/benfb/oaamonitor
 
 go 1.26.3
 
-require github.com/ncruces/go-sqlite3 v0.35.1
+require (
+	github.com/klauspost/compress v1.18.0
+	github.com/ncruces/go-sqlite3 v0.35.1
+)
 
 require (
 	github.com/ncruces/go-sqlite3-wasm/v3 v3.1.35302 // indirect
@@ -0,0 +1,173 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// FieldingRunValue represents a row in the fielding_run_value table.
+type FieldingRunValue struct {
+	PlayerID  int       `json:"player_id"`
+	Team      string    `json:"team"`
+	Date      time.Time `json:"date"`
+	TotalRuns *float64  `json:"total_runs"`
+	ArmRuns   *float64  `json:"arm_runs"`
+	RangeRuns *float64  `json:"range_runs"`
+}
+
+// ArmStrength represents a row in the arm_strength table.
+type ArmStrength struct {
+	PlayerID       int       `json:"player_id"`
+	Team           string    `json:"team"`
+	Date           time.Time `json:"date"`
+	ArmOverall     *float64  `json:"arm_overall"`
+	MaxArmStrength *float64  `json:"max_arm_strength"`
+}
+
+// CatcherFraming represents a row in the catcher_framing table.
+type CatcherFraming struct {
+	PlayerID    int       `json:"player_id"`
+	Team        string    `json:"team"`
+	Date        time.Time `json:"date"`
+	FramingRuns *float64  `json:"framing_runs"`
+	StrikeRate  *float64  `json:"strike_rate"`
+}
+
+// OutfieldJump represents a row in the outfield_jump table. Distances are feet
+// relative to the league average.
+type OutfieldJump struct {
+	PlayerID     int       `json:"player_id"`
+	Team         string    `json:"team"`
+	Date         time.Time `json:"date"`
+	JumpFeet     *float64  `json:"jump_feet"`
+	ReactionFeet *float64  `json:"reaction_feet"`
+	BurstFeet    *float64  `json:"burst_feet"`
+	RouteFeet    *float64  `json:"route_feet"`
+}
+
+// OAADirectional represents a row in the oaa_directional table: a player's OAA
+// split by the direction of the fielding attempt.
+type OAADirectional struct {
+	PlayerID        int       `json:"player_id"`
+	Date            time.Time `json:"date"`
+	InFront         *int      `json:"in_front"`
+	TowardThirdBase *int      `json:"toward_third_base"`
+	TowardFirstBase *int      `json:"toward_first_base"`
+	Behind          *int      `json:"behind"`
+}
+
+// OAAByPosition represents a row in the oaa_by_position table.
+type OAAByPosition struct {
+	PlayerID int       `json:"player_id"`
+	Position string    `json:"position"`
+	Date     time.Time `json:"date"`
+	OAA      int       `json:"oaa"`
+}
+
+// FetchOAADirectional retrieves all directional OAA snapshots ordered for build-time grouping.
+func FetchOAADirectional(db *sql.DB) ([]OAADirectional, error) {
+	var values []OAADirectional
+	err := queryMetric(db, "oaa_directional", "in_front, toward_third_base, toward_first_base, behind", func(scan func(...any) error) error {
+		var value OAADirectional
+		if err := scan(&value.PlayerID, &value.Date, &value.InFront, &value.TowardThirdBase, &value.TowardFirstBase, &value.Behind); err != nil {
+			return err
+		}
+		values = append(values, value)
+		return nil
+	})
+	return values, err
+}
+
+// FetchOAAByPosition retrieves all per-position OAA snapshots ordered for build-time grouping.
+func FetchOAAByPosition(db *sql.DB) ([]OAAByPosition, error) {
+	var values []OAAByPosition
+	err := queryMetric(db, "oaa_by_position", "position, COALESCE(oaa, 0)", func(scan func(...any) error) error {
+		var value OAAByPosition
+		if err := scan(&value.PlayerID, &value.Date, &value.Position, &value.OAA); err != nil {
+			return err
+		}
+		values = append(values, value)
+		return nil
+	})
+	return values, err
+}
+
+// FetchFieldingRunValues retrieves all Fielding Run Value snapshots ordered for build-time grouping.
+func FetchFieldingRunValues(db *sql.DB) ([]FieldingRunValue, error) {
+	var values []FieldingRunValue
+	err := queryMetric(db, "fielding_run_value", metricTeamName+", total_runs, arm_runs, range_runs", func(scan func(...any) error) error {
+		var value FieldingRunValue
+		if err := scan(&value.PlayerID, &value.Date, &value.Team, &value.TotalRuns, &value.ArmRuns, &value.RangeRuns); err != nil {
+			return err
+		}
+		values = append(values, value)
+		return nil
+	})
+	return values, err
+}
+
+// FetchArmStrengths retrieves all arm strength snapshots ordered for build-time grouping.
+func FetchArmStrengths(db *sql.DB) ([]ArmStrength, error) {
+	var values []ArmStrength
+	err := queryMetric(db, "arm_strength", metricTeamName+", arm_overall, max_arm_strength", func(scan func(...any) error) error {
+		var value ArmStrength
+		if err := scan(&value.PlayerID, &value.Date, &value.Team, &value.ArmOverall, &value.MaxArmStrength); err != nil {
+			return err
+		}
+		values = append(values, value)
+		return nil
+	})
+	return values, err
+}
+
+// FetchCatcherFraming retrieves all catcher framing snapshots ordered for build-time grouping.
+func FetchCatcherFraming(db *sql.DB) ([]CatcherFraming, error) {
+	var values []CatcherFraming
+	err := queryMetric(db, "catcher_framing", metricTeamName+", framing_runs, strike_rate", func(scan func(...any) error) error {
+		var value CatcherFraming
+		if err := scan(&value.PlayerID, &value.Date, &value.Team, &value.FramingRuns, &value.StrikeRate); err != nil {
+			return err
+		}
+		values = append(values, value)
+		return nil
+	})
+	return values, err
+}
+
+// FetchOutfieldJumps retrieves all outfield jump snapshots ordered for build-time grouping.
+func FetchOutfieldJumps(db *sql.DB) ([]OutfieldJump, error) {
+	var values []OutfieldJump
+	err := queryMetric(db, "outfield_jump", metricTeamName+", jump_feet, reaction_feet, burst_feet, route_feet", func(scan func(...any) error) error {
+		var value OutfieldJump
+		if err := scan(&value.PlayerID, &value.Date, &value.Team, &value.JumpFeet, &value.ReactionFeet, &value.BurstFeet, &value.RouteFeet); err != nil {
+			return err
+		}
+		values = append(values, value)
+		return nil
+	})
+	return values, err
+}
+
+// metricTeamName selects the name of a metric row's team, or "" without one.
+const metricTeamName = `COALESCE((SELECT name FROM teams WHERE teams.id = team_id), '')`
+
+// queryMetric selects player_id, date and the given columns from a metric table
+// and hands each row to scanRow. A missing table yields no rows.
+func queryMetric(db *sql.DB, table, columns string, scanRow func(scan func(...any) error) error) error {
+	if exists, err := tableExists(db, table); err != nil || !exists {
+		return err
+	}
+
+	rows, err := db.Query(`SELECT player_id, date, ` + columns + ` FROM ` + table + ` ORDER BY player_id, date`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := scanRow(rows.Scan); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
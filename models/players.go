@@ -8,8 +8,8 @@ import (
 // FetchPlayers retrieves distinct player IDs and names from the database in alphabetical order.
 func FetchPlayers(db *sql.DB) ([]Player, error) {
 	rows, err := db.Query(`
-		SELECT player_id, full_name FROM outs_above_average
-		WHERE (player_id, date) IN (SELECT player_id, MAX(date) FROM outs_above_average GROUP BY player_id)
+		SELECT id, full_name FROM players
+		WHERE id IN (SELECT player_id FROM oaa_snapshots)
 		ORDER BY last_name, first_name`)
 	if err != nil {
 		return nil, err
@@ -40,18 +40,21 @@ func FetchPlayerStats(db *sql.DB, playerID int, season int) ([]Stat, string, str
 	seasonStart, nextSeasonStart := seasonDateRange(season)
 	rows, err := db.Query(`
 	SELECT
-		player_id,
-		full_name,
-		team,
-		COALESCE(primary_position, 'N/A') as position,
-		oaa,
-		date,
-		actual_success_rate,
-		estimated_success_rate,
-		diff_success_rate
-	FROM outs_above_average
-	WHERE player_id = ? AND date >= ? AND date < ?
-	ORDER BY player_id, date;`, playerID, seasonStart, nextSeasonStart)
+		o.player_id,
+		p.full_name,
+		COALESCE(o.team_id, 0),
+		COALESCE(t.name, ''),
+		COALESCE(o.primary_position, 'N/A') as position,
+		o.oaa,
+		o.date,
+		o.actual_success_rate,
+		o.estimated_success_rate,
+		o.diff_success_rate
+	FROM oaa_snapshots o
+	JOIN players p ON p.id = o.player_id
+	LEFT JOIN teams t ON t.id = o.team_id
+	WHERE o.player_id = ? AND o.date >= ? AND o.date < ?
+	ORDER BY o.player_id, o.date;`, playerID, seasonStart, nextSeasonStart)
 	if err != nil {
 		return nil, "", "", err
 	}
@@ -62,7 +65,7 @@ func FetchPlayerStats(db *sql.DB, playerID int, season int) ([]Stat, string, str
 	var playerPosition string
 	for rows.Next() {
 		var stat Stat
-		if err := rows.Scan(&stat.PlayerID, &stat.Name, &stat.Team, &stat.Position, &stat.OAA, &stat.Date, &stat.ActualSuccessRate, &stat.EstimatedSuccessRate, &stat.DiffSuccessRate); err != nil {
+		if err := rows.Scan(&stat.PlayerID, &stat.Name, &stat.TeamID, &stat.Team, &stat.Position, &stat.OAA, &stat.Date, &stat.ActualSuccessRate, &stat.EstimatedSuccessRate, &stat.DiffSuccessRate); err != nil {
 			return nil, "", "", err
 		}
 		stats = append(stats, stat)
@@ -78,10 +81,11 @@ func FetchPlayerStats(db *sql.DB, playerID int, season int) ([]Stat, string, str
 	if len(stats) == 0 {
 		var name, position string
 		err := db.QueryRow(`
-			SELECT full_name, COALESCE(primary_position, 'N/A')
-			FROM outs_above_average
-			WHERE player_id = ?
-			ORDER BY date DESC LIMIT 1
+			SELECT p.full_name, COALESCE(o.primary_position, 'N/A')
+			FROM oaa_snapshots o
+			JOIN players p ON p.id = o.player_id
+			WHERE o.player_id = ?
+			ORDER BY o.date DESC LIMIT 1
 		`, playerID).Scan(&name, &position)
 		if err != nil && err != sql.ErrNoRows {
 			return nil, "", "", err
@@ -103,7 +107,7 @@ func FetchPlayerStats(db *sql.DB, playerID int, season int) ([]Stat, string, str
 func FetchPlayerSeasons(db *sql.DB, playerID int) ([]int, error) {
 	rows, err := db.Query(`
 		SELECT DISTINCT strftime('%Y', date) as year
-		FROM outs_above_average
+		FROM oaa_snapshots
 		WHERE player_id = ?
 		ORDER BY year DESC
 	`, playerID)
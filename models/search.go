@@ -0,0 +1,106 @@
+package models
+
+import (
+	"database/sql"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// FetchPlayerSummaries retrieves every player with snapshots, ranked by their
+// most recent season and then by latest OAA.
+func FetchPlayerSummaries(db *sql.DB) ([]PlayerSummary, error) {
+	return queryPlayerSummaries(db, "")
+}
+
+// SearchPlayers finds players whose name or first-name variants start with
+// every word of query, ignoring case and accents, best matches first. A match
+// on the name itself outranks one on a first-name variant.
+func SearchPlayers(db *sql.DB, query string) ([]PlayerSummary, error) {
+	match := searchMatchExpression(query)
+	if match == "" {
+		return nil, nil
+	}
+	return queryPlayerSummaries(db, match)
+}
+
+// searchMatchExpression turns free text into an FTS5 query that requires a
+// prefix match on every word. Quoting each word keeps FTS5 operators and
+// punctuation in the input from being interpreted.
+func searchMatchExpression(query string) string {
+	words := strings.FieldsFunc(query, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	terms := make([]string, 0, len(words))
+	for _, word := range words {
+		terms = append(terms, `"`+word+`"*`)
+	}
+	return strings.Join(terms, " ")
+}
+
+func queryPlayerSummaries(db *sql.DB, match string) ([]PlayerSummary, error) {
+	where, order := "", ""
+	var args []any
+	if match != "" {
+		where = "WHERE player_search MATCH ?"
+		order = "bm25(player_search, 10.0, 1.0),"
+		args = append(args, match)
+	}
+
+	rows, err := db.Query(`
+	WITH latest AS (
+		SELECT player_id, team_id, primary_position, oaa,
+			ROW_NUMBER() OVER (PARTITION BY player_id ORDER BY date DESC) as rn
+		FROM oaa_snapshots
+	),
+	seasons AS (
+		SELECT player_id, GROUP_CONCAT(year, ',') as years, MAX(year) as latest_season
+		FROM (SELECT DISTINCT player_id, strftime('%Y', date) as year FROM oaa_snapshots)
+		GROUP BY player_id
+	)
+	SELECT
+		p.id,
+		p.full_name,
+		player_search.aliases,
+		COALESCE(l.team_id, 0),
+		COALESCE(t.name, ''),
+		COALESCE(l.primary_position, 'N/A'),
+		l.oaa,
+		s.years
+	FROM players p
+	JOIN player_search ON player_search.rowid = p.id
+	JOIN latest l ON l.player_id = p.id AND l.rn = 1
+	JOIN seasons s ON s.player_id = p.id
+	LEFT JOIN teams t ON t.id = l.team_id
+	`+where+`
+	ORDER BY `+order+` s.latest_season DESC, l.oaa DESC, p.last_name, p.first_name`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var players []PlayerSummary
+	for rows.Next() {
+		var player PlayerSummary
+		var aliases, years string
+		if err := rows.Scan(&player.ID, &player.Name, &aliases, &player.TeamID, &player.Team, &player.Position, &player.LatestOAA, &years); err != nil {
+			return nil, err
+		}
+		player.Aliases = strings.Fields(aliases)
+		for _, year := range strings.Split(years, ",") {
+			season, err := strconv.Atoi(year)
+			if err != nil {
+				return nil, err
+			}
+			player.Seasons = append(player.Seasons, season)
+		}
+		sort.Sort(sort.Reverse(sort.IntSlice(player.Seasons)))
+		players = append(players, player)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return players, nil
+}
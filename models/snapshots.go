@@ -5,7 +5,7 @@ import "database/sql"
 // FetchLatestSnapshotDate returns the latest OAA snapshot date in YYYY-MM-DD format.
 func FetchLatestSnapshotDate(db *sql.DB) (string, error) {
 	var latest sql.NullString
-	if err := db.QueryRow("SELECT MAX(date) FROM outs_above_average").Scan(&latest); err != nil {
+	if err := db.QueryRow("SELECT MAX(date) FROM oaa_snapshots").Scan(&latest); err != nil {
 		return "", err
 	}
 	if !latest.Valid {
@@ -13,3 +13,40 @@ func FetchLatestSnapshotDate(db *sql.DB) (string, error) {
 	}
 	return latest.String, nil
 }
+
+// FetchLastSuccessfulIngestRun returns the most recently finished ingest run that
+// completed without error, or nil if none has been recorded.
+func FetchLastSuccessfulIngestRun(db *sql.DB) (*IngestRun, error) {
+	// Databases created before the ledger existed have no ingest_runs table.
+	if exists, err := tableExists(db, "ingest_runs"); err != nil || !exists {
+		return nil, err
+	}
+
+	var run IngestRun
+	err := db.QueryRow(`
+		SELECT id, started_at, finished_at, source, COALESCE(strftime('%Y-%m-%d', snapshot_date), ''), row_count,
+			inserted_count, updated_count, unchanged_count, COALESCE(csv_sha256, '')
+		FROM ingest_runs
+		WHERE error IS NULL
+		ORDER BY finished_at DESC
+		LIMIT 1
+	`).Scan(&run.ID, &run.StartedAt, &run.FinishedAt, &run.Source, &run.SnapshotDate, &run.RowCount,
+		&run.InsertedCount, &run.UpdatedCount, &run.UnchangedCount, &run.SHA256)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// tableExists reports whether a table is present, so fetchers can tolerate
+// databases built before the table was added.
+func tableExists(db *sql.DB, name string) (bool, error) {
+	var tables int
+	if err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", name).Scan(&tables); err != nil {
+		return false, err
+	}
+	return tables > 0, nil
+}
@@ -1,22 +1,73 @@
 package models
 
-import "database/sql"
+import (
+	"database/sql"
+	"strings"
+)
+
+// StatFilter narrows FetchFilteredStats. Zero fields match everything; From and
+// To are inclusive YYYY-MM-DD dates.
+type StatFilter struct {
+	Season   int
+	TeamID   int
+	PlayerID int
+	From     string
+	To       string
+}
 
 // FetchStats retrieves all OAA snapshots ordered for build-time grouping.
 func FetchStats(db *sql.DB) ([]Stat, error) {
+	return FetchFilteredStats(db, StatFilter{})
+}
+
+// FetchFilteredStats retrieves the OAA snapshots matching filter, ordered by
+// player and date.
+func FetchFilteredStats(db *sql.DB, filter StatFilter) ([]Stat, error) {
+	var conditions []string
+	var args []any
+	if filter.Season != 0 {
+		seasonStart, nextSeasonStart := seasonDateRange(filter.Season)
+		conditions = append(conditions, "o.date >= ? AND o.date < ?")
+		args = append(args, seasonStart, nextSeasonStart)
+	}
+	if filter.TeamID != 0 {
+		conditions = append(conditions, "o.team_id = ?")
+		args = append(args, filter.TeamID)
+	}
+	if filter.PlayerID != 0 {
+		conditions = append(conditions, "o.player_id = ?")
+		args = append(args, filter.PlayerID)
+	}
+	if filter.From != "" {
+		conditions = append(conditions, "o.date >= ?")
+		args = append(args, filter.From)
+	}
+	if filter.To != "" {
+		conditions = append(conditions, "o.date <= ?")
+		args = append(args, filter.To)
+	}
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
 	rows, err := db.Query(`
 	SELECT
-		player_id,
-		full_name,
-		team,
-		COALESCE(primary_position, 'N/A') as position,
-		oaa,
-		date,
-		actual_success_rate,
-		estimated_success_rate,
-		diff_success_rate
-	FROM outs_above_average
-	ORDER BY player_id, date;`)
+		o.player_id,
+		p.full_name,
+		COALESCE(o.team_id, 0),
+		COALESCE(t.name, ''),
+		COALESCE(o.primary_position, 'N/A') as position,
+		o.oaa,
+		o.date,
+		o.actual_success_rate,
+		o.estimated_success_rate,
+		o.diff_success_rate
+	FROM oaa_snapshots o
+	JOIN players p ON p.id = o.player_id
+	LEFT JOIN teams t ON t.id = o.team_id
+	`+where+`
+	ORDER BY o.player_id, o.date;`, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -25,7 +76,7 @@ func FetchStats(db *sql.DB) ([]Stat, error) {
 	var stats []Stat
 	for rows.Next() {
 		var stat Stat
-		if err := rows.Scan(&stat.PlayerID, &stat.Name, &stat.Team, &stat.Position, &stat.OAA, &stat.Date, &stat.ActualSuccessRate, &stat.EstimatedSuccessRate, &stat.DiffSuccessRate); err != nil {
+		if err := rows.Scan(&stat.PlayerID, &stat.Name, &stat.TeamID, &stat.Team, &stat.Position, &stat.OAA, &stat.Date, &stat.ActualSuccessRate, &stat.EstimatedSuccessRate, &stat.DiffSuccessRate); err != nil {
 			return nil, err
 		}
 		stats = append(stats, stat)
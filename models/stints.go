@@ -0,0 +1,98 @@
+package models
+
+import (
+	"database/sql"
+	"sort"
+	"time"
+)
+
+// TeamStint is a run of consecutive snapshots in which a player was listed with
+// the same team during one season. OAA on the leaderboard is cumulative, so the
+// stint's contribution is the change in OAA from the last snapshot before the
+// stint (zero for a player's first stint of the season) to its last snapshot.
+type TeamStint struct {
+	PlayerID  int       `json:"player_id"`
+	TeamID    int       `json:"team_id"`
+	Team      string    `json:"team"`
+	Season    int       `json:"season"`
+	StartDate time.Time `json:"start_date"`
+	EndDate   time.Time `json:"end_date"`
+	OAABefore int       `json:"oaa_before"`
+	OAAAfter  int       `json:"oaa_after"`
+}
+
+// OAA returns the outs above average accrued during the stint.
+func (s TeamStint) OAA() int {
+	return s.OAAAfter - s.OAABefore
+}
+
+// BuildTeamStints derives team stints from snapshot rows in any order. The
+// result is ordered by player, then date.
+func BuildTeamStints(stats []Stat) []TeamStint {
+	sorted := make([]Stat, len(stats))
+	copy(sorted, stats)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].PlayerID != sorted[j].PlayerID {
+			return sorted[i].PlayerID < sorted[j].PlayerID
+		}
+		return sorted[i].Date.Before(sorted[j].Date)
+	})
+
+	var stints []TeamStint
+	for i, stat := range sorted {
+		season := stat.Date.Year()
+		continues := i > 0 && sorted[i-1].PlayerID == stat.PlayerID && sorted[i-1].Date.Year() == season
+		if continues && sorted[i-1].TeamID == stat.TeamID {
+			current := &stints[len(stints)-1]
+			current.EndDate = stat.Date
+			current.OAAAfter = stat.OAA
+			continue
+		}
+
+		before := 0
+		if continues {
+			before = sorted[i-1].OAA
+		}
+		stints = append(stints, TeamStint{
+			PlayerID:  stat.PlayerID,
+			TeamID:    stat.TeamID,
+			Team:      stat.Team,
+			Season:    season,
+			StartDate: stat.Date,
+			EndDate:   stat.Date,
+			OAABefore: before,
+			OAAAfter:  stat.OAA,
+		})
+	}
+	return stints
+}
+
+// FetchTeamStints retrieves every stored team stint ordered by player and start date.
+func FetchTeamStints(db *sql.DB) ([]TeamStint, error) {
+	if exists, err := tableExists(db, "player_team_stints"); err != nil || !exists {
+		return nil, err
+	}
+
+	rows, err := db.Query(`
+		SELECT s.player_id, s.team_id, t.name, s.season, s.start_date, s.end_date, s.oaa_before, s.oaa_after
+		FROM player_team_stints s
+		JOIN teams t ON t.id = s.team_id
+		ORDER BY s.player_id, s.start_date`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stints []TeamStint
+	for rows.Next() {
+		var stint TeamStint
+		if err := rows.Scan(&stint.PlayerID, &stint.TeamID, &stint.Team, &stint.Season, &stint.StartDate, &stint.EndDate, &stint.OAABefore, &stint.OAAAfter); err != nil {
+			return nil, err
+		}
+		stints = append(stints, stint)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return stints, nil
+}
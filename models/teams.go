@@ -4,68 +4,66 @@ import (
 	"database/sql"
 	"sort"
 	"strconv"
-	"strings"
 	"time"
 )
 
-func FetchTeamStats(db *sql.DB, teamName string, season int) ([]Stat, string, error) {
+// Team is a row of the teams dimension table, keyed by MLBAM team ID.
+type Team struct {
+	ID           int    `json:"id"`
+	Name         string `json:"name"`
+	Abbreviation string `json:"abbreviation"`
+}
+
+// FetchTeamStats retrieves a team's snapshots for a season along with the team's name.
+func FetchTeamStats(db *sql.DB, teamID int, season int) ([]Stat, string, error) {
 	seasonStart, nextSeasonStart := seasonDateRange(season)
 	rows, err := db.Query(`
 	WITH latest_positions AS (
 		SELECT player_id, primary_position,
 			ROW_NUMBER() OVER (PARTITION BY player_id ORDER BY date DESC) as rn
-		FROM outs_above_average
-		WHERE LOWER(team) = ? AND primary_position IS NOT NULL AND date >= ? AND date < ?
+		FROM oaa_snapshots
+		WHERE team_id = ? AND primary_position IS NOT NULL AND date >= ? AND date < ?
 	)
 	SELECT
 		o.player_id,
-		o.full_name,
-		o.team,
+		p.full_name,
+		o.team_id,
+		t.name,
 		COALESCE(lp.primary_position, 'N/A') as position,
 		o.oaa,
 		o.date,
 		o.actual_success_rate,
 		o.estimated_success_rate,
 		o.diff_success_rate
-	FROM outs_above_average o
+	FROM oaa_snapshots o
+	JOIN players p ON p.id = o.player_id
+	JOIN teams t ON t.id = o.team_id
 	LEFT JOIN latest_positions lp ON o.player_id = lp.player_id AND lp.rn = 1
-	WHERE LOWER(o.team) = ? AND o.date >= ? AND o.date < ?
-	ORDER BY o.last_name, o.date;`, teamName, seasonStart, nextSeasonStart, teamName, seasonStart, nextSeasonStart)
+	WHERE o.team_id = ? AND o.date >= ? AND o.date < ?
+	ORDER BY p.last_name, o.date;`, teamID, seasonStart, nextSeasonStart, teamID, seasonStart, nextSeasonStart)
 	if err != nil {
 		return nil, "", err
 	}
 	defer rows.Close()
 
 	var stats []Stat
-	var capitalizedTeamName string
 	for rows.Next() {
 		var stat Stat
-		if err := rows.Scan(&stat.PlayerID, &stat.Name, &stat.Team, &stat.Position, &stat.OAA, &stat.Date, &stat.ActualSuccessRate, &stat.EstimatedSuccessRate, &stat.DiffSuccessRate); err != nil {
+		if err := rows.Scan(&stat.PlayerID, &stat.Name, &stat.TeamID, &stat.Team, &stat.Position, &stat.OAA, &stat.Date, &stat.ActualSuccessRate, &stat.EstimatedSuccessRate, &stat.DiffSuccessRate); err != nil {
 			return nil, "", err
 		}
 		stats = append(stats, stat)
-		capitalizedTeamName = stat.Team
 	}
 	if err := rows.Err(); err != nil {
 		return nil, "", err
 	}
 
-	if len(stats) == 0 {
-		var teamNameResult string
-		err := db.QueryRow(`
-			SELECT team FROM outs_above_average
-			WHERE LOWER(team) = ?
-			LIMIT 1
-		`, teamName).Scan(&teamNameResult)
-		if err != nil && err != sql.ErrNoRows {
-			return nil, "", err
-		}
-		if err == nil {
-			capitalizedTeamName = teamNameResult
-		}
+	var teamName string
+	if err := db.QueryRow(`SELECT name FROM teams WHERE id = ?`, teamID).Scan(&teamName); err != nil && err != sql.ErrNoRows {
+		return nil, "", err
 	}
 
-	return stats, capitalizedTeamName, nil
+	return stats, teamName, nil
 }
 
 // MapStatsByPlayerID returns a slice of PlayerStats sorted by current OAA in descending order.
@@ -80,12 +78,14 @@ func MapStatsByPlayerID(stats []Stat) []PlayerStats {
 				Name:       stat.Name,
 				Position:   stat.Position,
 				LatestOAA:  stat.OAA,
+				TeamOAA:    stat.OAA,
 				OAAHistory: []SparklinePoint{{Date: stat.Date, OAA: stat.OAA}},
 			}
 			latestDates[stat.PlayerID] = stat.Date
 		} else {
 			if stat.Date.After(latestDates[stat.PlayerID]) {
 				entry.LatestOAA = stat.OAA
+				entry.TeamOAA = stat.OAA
 				latestDates[stat.PlayerID] = stat.Date
 			}
 
@@ -109,18 +109,21 @@ func MapStatsByPlayerID(stats []Stat) []PlayerStats {
 	return playerStatsList
 }
 
-// FetchTeams retrieves distinct team names from the database.
-func FetchTeams(db *sql.DB) ([]string, error) {
-	rows, err := db.Query("SELECT DISTINCT team FROM outs_above_average ORDER BY team")
+// FetchTeams retrieves the teams that appear in any snapshot, ordered by name.
+func FetchTeams(db *sql.DB) ([]Team, error) {
+	rows, err := db.Query(`
+		SELECT id, name, abbreviation FROM teams
+		WHERE id IN (SELECT DISTINCT team_id FROM oaa_snapshots)
+		ORDER BY name`)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var teams []string
+	var teams []Team
 	for rows.Next() {
-		var team string
-		if err := rows.Scan(&team); err != nil {
+		var team Team
+		if err := rows.Scan(&team.ID, &team.Name, &team.Abbreviation); err != nil {
 			return nil, err
 		}
 		teams = append(teams, team)
@@ -132,50 +135,9 @@ func FetchTeams(db *sql.DB) ([]string, error) {
 	return teams, nil
 }
 
-// GetTeamAbbreviation returns the MLB abbreviation for a given team name.
-func GetTeamAbbreviation(teamName string) string {
-	abbreviations := map[string]string{
-		"Angels":    "LAA",
-		"Astros":    "HOU",
-		"Athletics": "OAK",
-		"Blue Jays": "TOR",
-		"Braves":    "ATL",
-		"Brewers":   "MIL",
-		"Cardinals": "STL",
-		"Cubs":      "CHC",
-		"D-backs":   "ARI",
-		"Dodgers":   "LAD",
-		"Giants":    "SF",
-		"Guardians": "CLE",
-		"Mariners":  "SEA",
-		"Marlins":   "MIA",
-		"Mets":      "NYM",
-		"Nationals": "WSH",
-		"Orioles":   "BAL",
-		"Padres":    "SD",
-		"Phillies":  "PHI",
-		"Pirates":   "PIT",
-		"Rangers":   "TEX",
-		"Rays":      "TB",
-		"Red Sox":   "BOS",
-		"Reds":      "CIN",
-		"Rockies":   "COL",
-		"Royals":    "KC",
-		"Tigers":    "DET",
-		"Twins":     "MIN",
-		"White Sox": "CWS",
-		"Yankees":   "NYY",
-	}
-
-	if abbr, ok := abbreviations[teamName]; ok {
-		return abbr
-	}
-	return ""
-}
-
 // FetchSeasons retrieves distinct seasons from the database.
 func FetchSeasons(db *sql.DB) ([]int, error) {
-	rows, err := db.Query("SELECT DISTINCT strftime('%Y', date) as year FROM outs_above_average ORDER BY year DESC")
+	rows, err := db.Query("SELECT DISTINCT strftime('%Y', date) as year FROM oaa_snapshots ORDER BY year DESC")
 	if err != nil {
 		return nil, err
 	}
@@ -203,13 +165,13 @@ func FetchSeasons(db *sql.DB) ([]int, error) {
 }
 
 // FetchTeamSeasons returns the seasons in which the team has recorded stats.
-func FetchTeamSeasons(db *sql.DB, teamName string) ([]int, error) {
+func FetchTeamSeasons(db *sql.DB, teamID int) ([]int, error) {
 	rows, err := db.Query(`
 		SELECT DISTINCT strftime('%Y', date) as year
-		FROM outs_above_average
-		WHERE LOWER(team) = ?
+		FROM oaa_snapshots
+		WHERE team_id = ?
 		ORDER BY year DESC
-	`, strings.ToLower(teamName))
+	`, teamID)
 	if err != nil {
 		return nil, err
 	}
@@ -10,10 +10,10 @@ func FetchPlayerDifferences(db *sql.DB, limit int) ([]PlayerDifference, error) {
 	var count int
 	if err := db.QueryRow(`
 	WITH current AS (
-		SELECT player_id, oaa FROM outs_above_average WHERE date = (SELECT MAX(date) FROM outs_above_average)
+		SELECT player_id, oaa FROM oaa_snapshots WHERE date = (SELECT MAX(date) FROM oaa_snapshots)
 	),
 	previous AS (
-		SELECT player_id, oaa FROM outs_above_average WHERE date = (SELECT MAX(date) FROM outs_above_average WHERE date < (SELECT MAX(date) FROM outs_above_average))
+		SELECT player_id, oaa FROM oaa_snapshots WHERE date = (SELECT MAX(date) FROM oaa_snapshots WHERE date < (SELECT MAX(date) FROM oaa_snapshots))
 	)
 	SELECT COUNT(*) FROM current JOIN previous ON current.player_id = previous.player_id WHERE current.oaa != previous.oaa;
 	`).Scan(&count); err != nil {
@@ -27,17 +27,19 @@ func FetchPlayerDifferences(db *sql.DB, limit int) ([]PlayerDifference, error) {
 			WITH differences AS (
 				SELECT
 					current.player_id,
-					current.full_name,
-					current.team,
+					p.full_name,
+					COALESCE(t.name, '') AS team,
 					COALESCE(current.primary_position, 'N/A') AS position,
 					current.oaa AS current_oaa,
 					previous.oaa AS previous_oaa,
 					current.oaa - previous.oaa AS difference
-				FROM outs_above_average AS current
-				JOIN outs_above_average AS previous
+				FROM oaa_snapshots AS current
+				JOIN oaa_snapshots AS previous
 				ON current.player_id = previous.player_id
-				WHERE current.date = (SELECT MAX(date) FROM outs_above_average WHERE date < (SELECT MAX(date) FROM outs_above_average))
-				AND previous.date = (SELECT MAX(date) FROM outs_above_average WHERE date < (SELECT MAX(date) FROM outs_above_average WHERE date < (SELECT MAX(date) FROM outs_above_average)))
+				JOIN players p ON p.id = current.player_id
+				LEFT JOIN teams t ON t.id = current.team_id
+				WHERE current.date = (SELECT MAX(date) FROM oaa_snapshots WHERE date < (SELECT MAX(date) FROM oaa_snapshots))
+				AND previous.date = (SELECT MAX(date) FROM oaa_snapshots WHERE date < (SELECT MAX(date) FROM oaa_snapshots WHERE date < (SELECT MAX(date) FROM oaa_snapshots)))
 				AND current.oaa != previous.oaa
 			),
 			ranked AS (
@@ -65,17 +67,19 @@ func FetchPlayerDifferences(db *sql.DB, limit int) ([]PlayerDifference, error) {
 			WITH differences AS (
 				SELECT
 					current.player_id,
-					current.full_name,
-					current.team,
+					p.full_name,
+					COALESCE(t.name, '') AS team,
 					COALESCE(current.primary_position, 'N/A') AS position,
 					current.oaa AS current_oaa,
 					previous.oaa AS previous_oaa,
 					current.oaa - previous.oaa AS difference
-				FROM outs_above_average AS current
-				JOIN outs_above_average AS previous
+				FROM oaa_snapshots AS current
+				JOIN oaa_snapshots AS previous
 				ON current.player_id = previous.player_id
-				WHERE current.date = (SELECT MAX(date) FROM outs_above_average)
-				AND previous.date = (SELECT MAX(date) FROM outs_above_average WHERE date < (SELECT MAX(date) FROM outs_above_average))
+				JOIN players p ON p.id = current.player_id
+				LEFT JOIN teams t ON t.id = current.team_id
+				WHERE current.date = (SELECT MAX(date) FROM oaa_snapshots)
+				AND previous.date = (SELECT MAX(date) FROM oaa_snapshots WHERE date < (SELECT MAX(date) FROM oaa_snapshots))
 				AND current.oaa != previous.oaa
 			),
 			ranked AS (
@@ -124,20 +128,22 @@ func FetchNDayTrends(db *sql.DB, daysAgo, threshold int) ([]PlayerDifference, er
 	WITH latest_positions AS (
 		SELECT player_id, primary_position,
 			ROW_NUMBER() OVER (PARTITION BY player_id ORDER BY date DESC) as rn
-		FROM outs_above_average
+		FROM oaa_snapshots
 		WHERE primary_position IS NOT NULL
 	),
 	recent_data AS (
 		SELECT
 			o.player_id,
-			o.full_name,
-			o.team,
+			p.full_name,
+			COALESCE(t.name, '') AS team,
 			COALESCE(lp.primary_position, 'N/A') as position,
 			o.oaa,
 			o.date
-		FROM outs_above_average o
+		FROM oaa_snapshots o
+		JOIN players p ON p.id = o.player_id
+		LEFT JOIN teams t ON t.id = o.team_id
 		LEFT JOIN latest_positions lp ON o.player_id = lp.player_id AND lp.rn = 1
-		WHERE o.date >= (SELECT DATE(MAX(date), ?) FROM outs_above_average)
+		WHERE o.date >= (SELECT DATE(MAX(date), ?) FROM oaa_snapshots)
 	),
 	player_trends AS (
 		SELECT
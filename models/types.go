@@ -8,10 +8,24 @@ type Player struct {
 	Name string `json:"name"`
 }
 
+// PlayerSummary is a player's latest snapshot and active seasons, as listed in
+// search results and the site's search index.
+type PlayerSummary struct {
+	ID        int      `json:"id"`
+	Name      string   `json:"name"`
+	Aliases   []string `json:"aliases,omitempty"`
+	TeamID    int      `json:"team_id"`
+	Team      string   `json:"team"`
+	Position  string   `json:"position"`
+	LatestOAA int      `json:"oaa"`
+	Seasons   []int    `json:"seasons"`
+}
+
 // Stat represents a row in the outs_above_average table.
 type Stat struct {
 	PlayerID             int       `json:"player_id"`
 	Name                 string    `json:"name"`
+	TeamID               int       `json:"team_id"`
 	Team                 string    `json:"team"`
 	Position             string    `json:"position"`
 	OAA                  int       `json:"oaa"`
@@ -43,4 +57,23 @@ type PlayerStats struct {
 	Position   string
 	LatestOAA  int
 	OAAHistory []SparklinePoint
+	// TeamOAA is the OAA accrued while with the team being rendered; it equals
+	// LatestOAA for players who spent the whole season there.
+	TeamOAA int
+	// TradedTo names the player's later team when they left mid-season.
+	TradedTo string
+}
+
+// IngestRun represents a row in the ingest_runs table.
+type IngestRun struct {
+	ID             int       `json:"id"`
+	StartedAt      time.Time `json:"started_at"`
+	FinishedAt     time.Time `json:"finished_at"`
+	Source         string    `json:"source"`
+	SnapshotDate   string    `json:"snapshot_date"`
+	RowCount       int       `json:"row_count"`
+	InsertedCount  int       `json:"inserted_count"`
+	UpdatedCount   int       `json:"updated_count"`
+	UnchangedCount int       `json:"unchanged_count"`
+	SHA256         string    `json:"sha256"`
 }
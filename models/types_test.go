@@ -2,9 +2,13 @@ package models
 
 import (
 	"database/sql"
+	"path/filepath"
+	"reflect"
 	"testing"
 	"time"
 
+	"github.com/benfb/oaamonitor/database"
+	"github.com/benfb/oaamonitor/teams"
 	_ "github.com/ncruces/go-sqlite3/driver"
 )
 
@@ -14,45 +18,70 @@ func setupTestDB(t *testing.T) *sql.DB {
 	if err != nil {
 		t.Fatalf("Failed to open in-memory database: %v", err)
 	}
+	createTestSchema(t, db)
 
-	// Create the necessary tables
+	// Insert test data
 	_, err = db.Exec(`
-    CREATE TABLE IF NOT EXISTS outs_above_average (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		player_id INTEGER,
+    INSERT INTO players (id, first_name, last_name, full_name, updated_on)
+    VALUES
+    (1, 'John', 'Doe', 'John Doe', '2023-08-15'),
+    (2, 'Jane', 'Smith', 'Jane Smith', '2023-08-15'),
+    (3, 'Bob', 'Johnson', 'Bob Johnson', '2023-08-15'),
+    (4, 'Mike', 'Brown', 'Mike Brown', '2022-08-01');
+    INSERT INTO outs_above_average
+    (player_id, team_id, primary_position, oaa, date, actual_success_rate, estimated_success_rate, diff_success_rate)
+    VALUES
+    (1, 147, 'SS', 5, '2023-08-01', 0.75, 0.70, 0.05),
+    (1, 147, 'SS', 7, '2023-08-15', 0.78, 0.71, 0.07),
+    (2, 111, 'CF', 6, '2023-08-01', 0.82, 0.76, 0.06),
+    (2, 111, 'CF', 4, '2023-08-15', 0.80, 0.75, 0.05),
+    (3, 119, '1B', 3, '2023-08-01', 0.65, 0.62, 0.03),
+    (3, 119, NULL, 2, '2023-08-15', 0.64, 0.61, 0.03),
+    (4, 135, '2B', 1, '2022-08-01', 0.60, 0.59, 0.01);`)
+	if err != nil {
+		t.Fatalf("Failed to insert test data: %v", err)
+	}
+
+	return db
+}
+
+// createTestSchema creates the dimension and snapshot tables the models query.
+func createTestSchema(t *testing.T, db *sql.DB) {
+	_, err := db.Exec(`
+    CREATE TABLE teams (id INTEGER PRIMARY KEY, name TEXT NOT NULL, abbreviation TEXT NOT NULL);
+    INSERT INTO teams (id, name, abbreviation) VALUES
+    (111, 'Red Sox', 'BOS'), (119, 'Dodgers', 'LAD'), (121, 'Mets', 'NYM'), (135, 'Padres', 'SD'), (147, 'Yankees', 'NYY');
+    CREATE TABLE players (
+		id INTEGER PRIMARY KEY,
 		first_name TEXT,
 		last_name TEXT,
-		full_name TEXT,
-		team TEXT,
+		full_name TEXT NOT NULL,
+		updated_on DATE NOT NULL
+	);
+    CREATE TABLE outs_above_average (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		player_id INTEGER NOT NULL,
+		team_id INTEGER,
 		primary_position TEXT,
 		oaa INTEGER,
 		actual_success_rate REAL,
 		estimated_success_rate REAL,
 		diff_success_rate REAL,
 		date DATE DEFAULT CURRENT_DATE,
+		valid_to DATE,
 		UNIQUE(player_id, date)
-	);`)
+	);
+    CREATE TABLE snapshot_dates (date DATE PRIMARY KEY);
+    CREATE VIEW oaa_snapshots AS
+	SELECT player_id, team_id, primary_position, oaa, actual_success_rate, estimated_success_rate, diff_success_rate, date
+	FROM outs_above_average WHERE valid_to IS NULL
+	UNION ALL
+	SELECT o.player_id, o.team_id, o.primary_position, o.oaa, o.actual_success_rate, o.estimated_success_rate, o.diff_success_rate, s.date
+	FROM outs_above_average o JOIN snapshot_dates s ON s.date >= o.date AND s.date <= o.valid_to
+	WHERE o.valid_to IS NOT NULL;`)
 	if err != nil {
-		t.Fatalf("Failed to create table: %v", err)
+		t.Fatalf("Failed to create tables: %v", err)
 	}
-
-	// Insert test data
-	_, err = db.Exec(`
-    INSERT INTO outs_above_average
-    (player_id, full_name, first_name, last_name, team, primary_position, oaa, date, actual_success_rate, estimated_success_rate, diff_success_rate)
-    VALUES
-    (1, 'John Doe', 'John', 'Doe', 'Yankees', 'SS', 5, '2023-08-01', 0.75, 0.70, 0.05),
-    (1, 'John Doe', 'John', 'Doe', 'Yankees', 'SS', 7, '2023-08-15', 0.78, 0.71, 0.07),
-    (2, 'Jane Smith', 'Jane', 'Smith', 'Red Sox', 'CF', 6, '2023-08-01', 0.82, 0.76, 0.06),
-    (2, 'Jane Smith', 'Jane', 'Smith', 'Red Sox', 'CF', 4, '2023-08-15', 0.80, 0.75, 0.05),
-    (3, 'Bob Johnson', 'Bob', 'Johnson', 'Dodgers', '1B', 3, '2023-08-01', 0.65, 0.62, 0.03),
-    (3, 'Bob Johnson', 'Bob', 'Johnson', 'Dodgers', NULL, 2, '2023-08-15', 0.64, 0.61, 0.03),
-    (4, 'Mike Brown', 'Mike', 'Brown', 'Padres', '2B', 1, '2022-08-01', 0.60, 0.59, 0.01);`)
-	if err != nil {
-		t.Fatalf("Failed to insert test data: %v", err)
-	}
-
-	return db
 }
 
 func TestFetchTeamStats(t *testing.T) {
@@ -60,7 +89,7 @@ func TestFetchTeamStats(t *testing.T) {
 	defer db.Close()
 
 	// Test successful query
-	stats, teamName, err := FetchTeamStats(db, "yankees", 2023)
+	stats, teamName, err := FetchTeamStats(db, 147, 2023)
 	if err != nil {
 		t.Errorf("FetchTeamStats returned error: %v", err)
 	}
@@ -72,7 +101,7 @@ func TestFetchTeamStats(t *testing.T) {
 	}
 
 	// Test no data found case
-	stats, teamName, err = FetchTeamStats(db, "padres", 2023)
+	stats, teamName, err = FetchTeamStats(db, 135, 2023)
 	if err != nil {
 		t.Errorf("FetchTeamStats returned error: %v", err)
 	}
@@ -131,26 +160,6 @@ func TestMapStatsByPlayerID(t *testing.T) {
 	}
 }
 
-func TestGetTeamAbbreviation(t *testing.T) {
-	tests := []struct {
-		teamName string
-		expected string
-	}{
-		{"Yankees", "NYY"},
-		{"Red Sox", "BOS"},
-		{"Dodgers", "LAD"},
-		{"Unknown Team", ""},
-	}
-
-	for _, tt := range tests {
-		result := GetTeamAbbreviation(tt.teamName)
-		if result != tt.expected {
-			t.Errorf("GetTeamAbbreviation(%s) = %s, expected %s",
-				tt.teamName, result, tt.expected)
-		}
-	}
-}
-
 func TestFetchPlayers(t *testing.T) {
 	db := setupTestDB(t)
 	defer db.Close()
@@ -171,18 +180,18 @@ func TestFetchPlayers(t *testing.T) {
 	}
 }
 
-func TestFetchPlayers_DeduplicatesAfterNameCorrection(t *testing.T) {
+func TestFetchPlayers_OneRowPerPlayer(t *testing.T) {
 	db := setupTestDB(t)
 	defer db.Close()
 
-	// Insert an older row for player 1 with a different (pre-correction) name.
+	// An older snapshot for player 1 must not list the player twice.
 	_, err := db.Exec(`
 		INSERT INTO outs_above_average
-		(player_id, full_name, first_name, last_name, team, primary_position, oaa, date, actual_success_rate, estimated_success_rate, diff_success_rate)
-		VALUES (1, 'Johnathan Doe', 'Johnathan', 'Doe', 'Yankees', 'SS', 4, '2022-01-01', 0.75, 0.70, 0.05)
+		(player_id, team_id, primary_position, oaa, date, actual_success_rate, estimated_success_rate, diff_success_rate)
+		VALUES (1, 147, 'SS', 4, '2022-01-01', 0.75, 0.70, 0.05)
 	`)
 	if err != nil {
-		t.Fatalf("Failed to insert corrected name row: %v", err)
+		t.Fatalf("Failed to insert older snapshot: %v", err)
 	}
 
 	players, err := FetchPlayers(db)
@@ -199,12 +208,6 @@ func TestFetchPlayers_DeduplicatesAfterNameCorrection(t *testing.T) {
 			t.Errorf("player_id %d appears %d times, expected 1", id, count)
 		}
 	}
-
-	for _, p := range players {
-		if p.ID == 1 && p.Name != "John Doe" {
-			t.Errorf("expected player 1 to have most recent name 'John Doe', got %q", p.Name)
-		}
-	}
 }
 
 func TestFetchPlayerStats(t *testing.T) {
@@ -258,6 +261,33 @@ func TestFetchStats(t *testing.T) {
 	}
 }
 
+func TestFetchFilteredStats(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	tests := []struct {
+		filter StatFilter
+		want   int
+	}{
+		{StatFilter{Season: 2023}, 6},
+		{StatFilter{Season: 2022}, 1},
+		{StatFilter{TeamID: 111}, 2},
+		{StatFilter{PlayerID: 3, Season: 2023}, 2},
+		{StatFilter{From: "2023-08-15"}, 3},
+		{StatFilter{From: "2023-08-01", To: "2023-08-01"}, 3},
+		{StatFilter{TeamID: 147, To: "2023-08-14"}, 1},
+	}
+	for _, tt := range tests {
+		stats, err := FetchFilteredStats(db, tt.filter)
+		if err != nil {
+			t.Fatalf("FetchFilteredStats(%+v) returned error: %v", tt.filter, err)
+		}
+		if len(stats) != tt.want {
+			t.Errorf("FetchFilteredStats(%+v) returned %d stats, want %d", tt.filter, len(stats), tt.want)
+		}
+	}
+}
+
 func TestFetchTeams(t *testing.T) {
 	db := setupTestDB(t)
 	defer db.Close()
@@ -271,14 +301,17 @@ func TestFetchTeams(t *testing.T) {
 		t.Errorf("Expected 4 teams, got %d", len(teams))
 	}
 
-	// Teams should be sorted alphabetically
+	// Teams should be sorted alphabetically; the Mets have no snapshots.
 	expectedTeams := []string{"Dodgers", "Padres", "Red Sox", "Yankees"}
 	for i, team := range teams {
-		if team != expectedTeams[i] {
+		if team.Name != expectedTeams[i] {
 			t.Errorf("Expected team[%d] to be '%s', got '%s'",
-				i, expectedTeams[i], team)
+				i, expectedTeams[i], team.Name)
 		}
 	}
+	if teams[3].ID != 147 || teams[3].Abbreviation != "NYY" {
+		t.Errorf("Expected Yankees to be team 147 (NYY), got %+v", teams[3])
+	}
 }
 
 func TestFetchPlayerDifferences(t *testing.T) {
@@ -327,26 +360,17 @@ func TestFetchPlayerDifferences_FallbackWithDateGap(t *testing.T) {
 	}
 	defer db.Close()
 
-	_, err = db.Exec(`
-		CREATE TABLE outs_above_average (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			player_id INTEGER, first_name TEXT, last_name TEXT, full_name TEXT,
-			team TEXT, primary_position TEXT, oaa INTEGER,
-			actual_success_rate REAL, estimated_success_rate REAL, diff_success_rate REAL,
-			date DATE DEFAULT CURRENT_DATE, UNIQUE(player_id, date)
-		)`)
-	if err != nil {
-		t.Fatalf("failed to create table: %v", err)
-	}
+	createTestSchema(t, db)
 
 	// Three snapshots with a 2-day gap between each (not consecutive calendar days).
 	// today == 4-days-ago OAA (so the primary query finds count==0, triggering fallback).
 	// 4-days-ago != 6-days-ago OAA (so there are changes in the fallback period).
 	_, err = db.Exec(`
-		INSERT INTO outs_above_average (player_id, full_name, first_name, last_name, team, primary_position, oaa, date, actual_success_rate, estimated_success_rate, diff_success_rate) VALUES
-		(1, 'John Doe', 'John', 'Doe', 'Yankees', 'SS', 5, date('now'),           0.8, 0.7, 0.1),
-		(1, 'John Doe', 'John', 'Doe', 'Yankees', 'SS', 5, date('now', '-2 days'), 0.8, 0.7, 0.1),
-		(1, 'John Doe', 'John', 'Doe', 'Yankees', 'SS', 2, date('now', '-4 days'), 0.7, 0.6, 0.1)
+		INSERT INTO players (id, first_name, last_name, full_name, updated_on) VALUES (1, 'John', 'Doe', 'John Doe', date('now'));
+		INSERT INTO outs_above_average (player_id, team_id, primary_position, oaa, date, actual_success_rate, estimated_success_rate, diff_success_rate) VALUES
+		(1, 147, 'SS', 5, date('now'),           0.8, 0.7, 0.1),
+		(1, 147, 'SS', 5, date('now', '-2 days'), 0.8, 0.7, 0.1),
+		(1, 147, 'SS', 2, date('now', '-4 days'), 0.7, 0.6, 0.1)
 	`)
 	if err != nil {
 		t.Fatalf("failed to insert test data: %v", err)
@@ -477,13 +501,7 @@ func TestFetchLatestSnapshotDateEmptyTable(t *testing.T) {
 	}
 	defer db.Close()
 
-	if _, err := db.Exec(`
-		CREATE TABLE outs_above_average (
-			date DATE DEFAULT CURRENT_DATE
-		)
-	`); err != nil {
-		t.Fatalf("failed to create table: %v", err)
-	}
+	createTestSchema(t, db)
 
 	latest, err := FetchLatestSnapshotDate(db)
 	if err != nil {
@@ -493,3 +511,242 @@ func TestFetchLatestSnapshotDateEmptyTable(t *testing.T) {
 		t.Fatalf("expected empty latest snapshot date, got %q", latest)
 	}
 }
+
+func TestFetchLastSuccessfulIngestRun(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	run, err := FetchLastSuccessfulIngestRun(db)
+	if err != nil {
+		t.Fatalf("FetchLastSuccessfulIngestRun returned error: %v", err)
+	}
+	if run != nil {
+		t.Fatalf("expected no run without an ingest_runs table, got %+v", run)
+	}
+
+	if _, err := db.Exec(`
+		CREATE TABLE ingest_runs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			started_at TIMESTAMP NOT NULL, finished_at TIMESTAMP NOT NULL,
+			source TEXT NOT NULL, snapshot_date DATE,
+			row_count INTEGER NOT NULL DEFAULT 0, inserted_count INTEGER NOT NULL DEFAULT 0,
+			updated_count INTEGER NOT NULL DEFAULT 0, unchanged_count INTEGER NOT NULL DEFAULT 0,
+			csv_sha256 TEXT, error TEXT
+		)`); err != nil {
+		t.Fatalf("failed to create ingest_runs: %v", err)
+	}
+	started := time.Date(2023, time.August, 15, 14, 0, 0, 0, time.UTC)
+	if _, err := db.Exec(`
+		INSERT INTO ingest_runs (started_at, finished_at, source, snapshot_date, row_count, inserted_count, error) VALUES
+		(?, ?, 'first.csv', '2023-08-15', 300, 300, NULL),
+		(?, ?, 'second.csv', '2023-08-15', 0, 0, 'boom')`,
+		started, started.Add(time.Minute), started.Add(time.Hour), started.Add(time.Hour+time.Minute)); err != nil {
+		t.Fatalf("failed to insert ingest runs: %v", err)
+	}
+
+	run, err = FetchLastSuccessfulIngestRun(db)
+	if err != nil {
+		t.Fatalf("FetchLastSuccessfulIngestRun returned error: %v", err)
+	}
+	if run == nil || run.Source != "first.csv" || run.RowCount != 300 || run.SnapshotDate != "2023-08-15" {
+		t.Fatalf("expected the successful first.csv run, got %+v", run)
+	}
+	if !run.FinishedAt.Equal(started.Add(time.Minute)) {
+		t.Fatalf("expected finished_at %v, got %v", started.Add(time.Minute), run.FinishedAt)
+	}
+}
+
+func TestFetchMetricLeaderboards(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	values, err := FetchFieldingRunValues(db)
+	if err != nil {
+		t.Fatalf("FetchFieldingRunValues returned error: %v", err)
+	}
+	if len(values) != 0 {
+		t.Fatalf("expected no rows without a fielding_run_value table, got %d", len(values))
+	}
+
+	if _, err := db.Exec(`
+		CREATE TABLE fielding_run_value (
+			id INTEGER PRIMARY KEY AUTOINCREMENT, player_id INTEGER NOT NULL, team_id INTEGER,
+			total_runs REAL, arm_runs REAL, range_runs REAL, date DATE NOT NULL, UNIQUE(player_id, date)
+		);
+		INSERT INTO fielding_run_value (player_id, team_id, total_runs, arm_runs, range_runs, date) VALUES
+		(1, 147, 4, NULL, 3, '2023-08-15'),
+		(1, 147, 2, 1, 1, '2023-08-01');`); err != nil {
+		t.Fatalf("failed to create fielding_run_value: %v", err)
+	}
+
+	values, err = FetchFieldingRunValues(db)
+	if err != nil {
+		t.Fatalf("FetchFieldingRunValues returned error: %v", err)
+	}
+	if len(values) != 2 {
+		t.Fatalf("expected 2 rows, got %d", len(values))
+	}
+	if values[0].Date.Format("2006-01-02") != "2023-08-01" || *values[0].TotalRuns != 2 || values[0].Team != "Yankees" {
+		t.Errorf("expected rows ordered by date, got %+v", values[0])
+	}
+	if values[1].ArmRuns != nil {
+		t.Errorf("expected NULL arm runs to scan as nil, got %v", *values[1].ArmRuns)
+	}
+}
+
+func TestFetchOAAByPosition(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	if _, err := db.Exec(`
+		CREATE TABLE oaa_by_position (
+			id INTEGER PRIMARY KEY AUTOINCREMENT, player_id INTEGER NOT NULL, position TEXT NOT NULL,
+			oaa INTEGER, date DATE NOT NULL, UNIQUE(player_id, position, date)
+		);
+		INSERT INTO oaa_by_position (player_id, position, oaa, date) VALUES
+		(1, 'SS', 6, '2023-08-15'),
+		(1, '2B', 1, '2023-08-15');`); err != nil {
+		t.Fatalf("failed to create oaa_by_position: %v", err)
+	}
+
+	values, err := FetchOAAByPosition(db)
+	if err != nil {
+		t.Fatalf("FetchOAAByPosition returned error: %v", err)
+	}
+	total := 0
+	for _, value := range values {
+		total += value.OAA
+	}
+	if len(values) != 2 || total != 7 {
+		t.Fatalf("expected two positions totalling 7 OAA, got %+v", values)
+	}
+}
+
+func TestBuildTeamStints(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2023, time.July, d, 0, 0, 0, 0, time.UTC) }
+	stats := []Stat{
+		{PlayerID: 1, TeamID: 121, Team: "Mets", OAA: 6, Date: day(30)},
+		{PlayerID: 1, TeamID: 147, Team: "Yankees", OAA: 2, Date: day(1)},
+		{PlayerID: 1, TeamID: 147, Team: "Yankees", OAA: 4, Date: day(15)},
+		{PlayerID: 1, TeamID: 147, Team: "Yankees", OAA: 3, Date: time.Date(2022, time.September, 1, 0, 0, 0, 0, time.UTC)},
+		{PlayerID: 2, TeamID: 111, Team: "Red Sox", OAA: -1, Date: day(1)},
+	}
+
+	stints := BuildTeamStints(stats)
+	if len(stints) != 4 {
+		t.Fatalf("expected 4 stints, got %d: %+v", len(stints), stints)
+	}
+
+	// The 2022 stint must not carry its OAA into 2023.
+	if stints[0].Season != 2022 || stints[0].OAA() != 3 {
+		t.Errorf("unexpected 2022 stint: %+v", stints[0])
+	}
+	yankees, mets := stints[1], stints[2]
+	if yankees.Team != "Yankees" || yankees.OAA() != 4 || !yankees.EndDate.Equal(day(15)) {
+		t.Errorf("unexpected Yankees stint: %+v", yankees)
+	}
+	if mets.Team != "Mets" || mets.OAABefore != 4 || mets.OAA() != 2 || !mets.StartDate.Equal(day(30)) {
+		t.Errorf("unexpected Mets stint: %+v", mets)
+	}
+	if stints[3].PlayerID != 2 || stints[3].OAA() != -1 {
+		t.Errorf("unexpected Red Sox stint: %+v", stints[3])
+	}
+}
+
+// setupSearchDB migrates a fresh database so the player search index and its
+// triggers exist.
+func setupSearchDB(t *testing.T) *sql.DB {
+	db, err := database.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := database.Migrate(db, teams.Default()); err != nil {
+		t.Fatalf("Migrate returned error: %v", err)
+	}
+	_, err = db.Exec(`
+    INSERT INTO players (id, first_name, last_name, full_name, updated_on)
+    VALUES
+    (1, 'José', 'Ramírez', 'José Ramírez', '2025-06-01'),
+    (2, 'Mike', 'Trout', 'Mike Trout', '2025-06-01'),
+    (3, 'Michael', 'Harris', 'Michael Harris II', '2025-06-01'),
+    (4, 'Jose', 'Ramos', 'Jose Ramos', '2024-09-29');
+    INSERT INTO snapshot_dates (date) VALUES ('2024-09-29'), ('2025-06-01');
+    INSERT INTO outs_above_average
+    (player_id, team_id, primary_position, oaa, date, actual_success_rate, estimated_success_rate, diff_success_rate)
+    VALUES
+    (1, 114, '3B', 2, '2024-09-29', 0.80, 0.78, 0.02),
+    (1, 114, '3B', 4, '2025-06-01', 0.82, 0.78, 0.04),
+    (2, 108, 'CF', -1, '2025-06-01', 0.70, 0.71, -0.01),
+    (3, 144, 'CF', 6, '2025-06-01', 0.85, 0.80, 0.05),
+    (4, 137, 'LF', 1, '2024-09-29', 0.75, 0.74, 0.01);`)
+	if err != nil {
+		t.Fatalf("Failed to insert test data: %v", err)
+	}
+	return db
+}
+
+func TestSearchPlayers(t *testing.T) {
+	db := setupSearchDB(t)
+	defer db.Close()
+
+	tests := []struct {
+		query string
+		want  []int
+	}{
+		{"ramirez", []int{1}},
+		{"JOSÉ", []int{1, 4}},
+		{"jose ra", []int{1, 4}},
+		{"michael", []int{3, 2}},
+		{"mike harris", []int{3}},
+		{"tro*", []int{2}},
+		{`" OR `, nil},
+	}
+	for _, tt := range tests {
+		players, err := SearchPlayers(db, tt.query)
+		if err != nil {
+			t.Fatalf("SearchPlayers(%q) returned error: %v", tt.query, err)
+		}
+		var got []int
+		for _, player := range players {
+			got = append(got, player.ID)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SearchPlayers(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+
+	// A rename is picked up by the index.
+	if _, err := db.Exec(`UPDATE players SET full_name = 'Jose Ramirez Jr.' WHERE id = 1`); err != nil {
+		t.Fatal(err)
+	}
+	players, err := SearchPlayers(db, "jr")
+	if err != nil || len(players) != 1 || players[0].ID != 1 {
+		t.Errorf("expected the renamed player, got %+v, %v", players, err)
+	}
+}
+
+func TestFetchPlayerSummaries(t *testing.T) {
+	db := setupSearchDB(t)
+	defer db.Close()
+
+	players, err := FetchPlayerSummaries(db)
+	if err != nil {
+		t.Fatalf("FetchPlayerSummaries returned error: %v", err)
+	}
+	var ids []int
+	for _, player := range players {
+		ids = append(ids, player.ID)
+	}
+	// 2025 players first, by latest OAA, then the 2024-only player.
+	if !reflect.DeepEqual(ids, []int{3, 1, 2, 4}) {
+		t.Fatalf("unexpected order %v", ids)
+	}
+
+	ramirez := players[1]
+	if ramirez.Team != "Guardians" || ramirez.Position != "3B" || ramirez.LatestOAA != 4 || !reflect.DeepEqual(ramirez.Seasons, []int{2025, 2024}) {
+		t.Errorf("unexpected summary: %+v", ramirez)
+	}
+	if !reflect.DeepEqual(players[0].Aliases, []string{"Mike"}) {
+		t.Errorf("expected Michael Harris II to have the alias Mike, got %v", players[0].Aliases)
+	}
+}
@@ -0,0 +1,194 @@
+package refresher
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/benfb/oaamonitor/config"
+	"github.com/benfb/oaamonitor/storage"
+)
+
+// archiveKey returns the archive location for a CSV recorded under snapshotDate
+// and fetched at fetchedAt, e.g. raw/2025/06/01/1400.csv.gz.
+func archiveKey(snapshotDate string, fetchedAt time.Time) (string, error) {
+	date, err := time.Parse("2006-01-02", snapshotDate)
+	if err != nil {
+		return "", fmt.Errorf("invalid snapshot date %q: expected YYYY-MM-DD", snapshotDate)
+	}
+	loc, err := time.LoadLocation(baseballTimeZone)
+	if err != nil {
+		return "", fmt.Errorf("failed to load time zone %s: %v", baseballTimeZone, err)
+	}
+	return path.Join("raw", date.Format("2006/01/02"), fetchedAt.In(loc).Format("1504")+".csv.gz"), nil
+}
+
+// archiveCSV stores a gzip-compressed copy of a downloaded CSV in the configured
+// local archive directory and/or object storage.
+func archiveCSV(ctx context.Context, cfg *config.Config, csvPath, snapshotDate string, fetchedAt time.Time) error {
+	if cfg.ArchiveDir == "" && !cfg.ArchiveToStorage {
+		return nil
+	}
+
+	key, err := archiveKey(snapshotDate, fetchedAt)
+	if err != nil {
+		return err
+	}
+
+	data, err := gzipFile(csvPath)
+	if err != nil {
+		return fmt.Errorf("failed to compress CSV: %v", err)
+	}
+
+	if cfg.ArchiveDir != "" {
+		target := filepath.Join(cfg.ArchiveDir, filepath.FromSlash(key))
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(target, data, 0o644); err != nil {
+			return err
+		}
+		log.Printf("Archived raw CSV to %s", target)
+	}
+
+	if cfg.ArchiveToStorage {
+		backend, err := storage.Open(cfg.StorageURL)
+		if err != nil {
+			return err
+		}
+		if err := storage.UploadRawCSV(ctx, backend, key, bytes.NewReader(data), int64(len(data))); err != nil {
+			return fmt.Errorf("failed to upload raw CSV: %v", err)
+		}
+		log.Printf("Archived raw CSV to object storage at %s", key)
+	}
+
+	return nil
+}
+
+// ReingestArchive rebuilds a fresh database from every archived CSV, oldest
+// first, reading the local archive directory when one is configured and the
+// raw/ prefix of object storage otherwise. Files the guardrails reject are
+// skipped. It refuses to write into a database that already exists.
+func ReingestArchive(ctx context.Context, cfg *config.Config) error {
+	if _, err := os.Stat(cfg.DatabasePath); err == nil {
+		return fmt.Errorf("database %s already exists; re-ingest into a fresh path", cfg.DatabasePath)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	if cfg.ArchiveDir != "" {
+		return importCSVDir(ctx, cfg, filepath.Join(cfg.ArchiveDir, "raw"), true)
+	}
+
+	dir, err := os.MkdirTemp("", "oaamonitor-archive")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	backend, err := storage.Open(cfg.StorageURL)
+	if err != nil {
+		return err
+	}
+	count, err := downloadArchive(ctx, backend, dir)
+	if err != nil {
+		return fmt.Errorf("failed to download archive from %s: %v", cfg.StorageURL, err)
+	}
+	log.Printf("Downloaded %d archived CSVs from %s", count, cfg.StorageURL)
+
+	return importCSVDir(ctx, cfg, filepath.Join(dir, "raw"), true)
+}
+
+// downloadArchive copies every object under raw/ in backend into dir, keeping
+// its key as the relative path so archive dates survive.
+func downloadArchive(ctx context.Context, backend storage.Backend, dir string) (int, error) {
+	objects, err := backend.List(ctx, "raw/")
+	if err != nil {
+		return 0, err
+	}
+
+	for _, object := range objects {
+		target := filepath.Join(dir, filepath.FromSlash(object.Key))
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return 0, err
+		}
+		if err := downloadObject(ctx, backend, object.Key, target); err != nil {
+			return 0, fmt.Errorf("%s: %v", object.Key, err)
+		}
+	}
+	return len(objects), nil
+}
+
+func downloadObject(ctx context.Context, backend storage.Backend, key, target string) error {
+	body, _, err := backend.Get(ctx, key, storage.GetOptions{})
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	file, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(file, body); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func gzipFile(csvPath string) ([]byte, error) {
+	file, err := os.Open(csvPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Name = filepath.Base(csvPath)
+	if _, err := io.Copy(zw, file); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// openCSV opens a CSV file, transparently decompressing archived .csv.gz files.
+func openCSV(csvPath string) (io.ReadCloser, error) {
+	file, err := os.Open(csvPath)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(filepath.Ext(csvPath), ".gz") {
+		return file, nil
+	}
+
+	zr, err := gzip.NewReader(file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to decompress %s: %v", csvPath, err)
+	}
+	return &gzipFileReader{Reader: zr, file: file}, nil
+}
+
+type gzipFileReader struct {
+	*gzip.Reader
+	file *os.File
+}
+
+func (r *gzipFileReader) Close() error {
+	r.Reader.Close()
+	return r.file.Close()
+}
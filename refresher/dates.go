@@ -0,0 +1,41 @@
+package refresher
+
+import (
+	"fmt"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/benfb/oaamonitor/config"
+)
+
+// baseballTimeZone is the zone MLB schedules in. Using it instead of UTC keeps
+// the late-evening cron run on the same baseball day as the afternoon runs.
+const baseballTimeZone = "America/New_York"
+
+// resolveSnapshotDate returns the configured snapshot date, or the current
+// baseball day when none is configured.
+func resolveSnapshotDate(cfg *config.Config, now time.Time) (string, error) {
+	if cfg.SnapshotDate != "" {
+		if err := validateSnapshotDate(cfg.SnapshotDate); err != nil {
+			return "", err
+		}
+		return cfg.SnapshotDate, nil
+	}
+	return baseballDay(now)
+}
+
+// baseballDay returns the date of now in the baseball time zone in YYYY-MM-DD format.
+func baseballDay(now time.Time) (string, error) {
+	loc, err := time.LoadLocation(baseballTimeZone)
+	if err != nil {
+		return "", fmt.Errorf("failed to load time zone %s: %v", baseballTimeZone, err)
+	}
+	return now.In(loc).Format("2006-01-02"), nil
+}
+
+func validateSnapshotDate(date string) error {
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		return fmt.Errorf("invalid snapshot date %q: expected YYYY-MM-DD", date)
+	}
+	return nil
+}
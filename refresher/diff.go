@@ -0,0 +1,253 @@
+package refresher
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/benfb/oaamonitor/config"
+	"github.com/benfb/oaamonitor/database"
+	"github.com/benfb/oaamonitor/teams"
+)
+
+// DryRun parses a leaderboard CSV without writing anything and prints how it
+// differs from the latest stored snapshot. When csvPath is empty the current
+// season's leaderboard is downloaded from Baseball Savant.
+func DryRun(ctx context.Context, cfg *config.Config, csvPath string, w io.Writer) error {
+	snapshotDate, err := resolveSnapshotDate(cfg, time.Now())
+	if err != nil {
+		return err
+	}
+	// Like ImportCSV, a local file without an explicit date is compared as of
+	// the date in its name.
+	if csvPath != "" && cfg.SnapshotDate == "" {
+		date, ok := dateFromFileName(csvPath)
+		if !ok {
+			return fmt.Errorf("no snapshot date given and none found in file name %q", filepath.Base(csvPath))
+		}
+		snapshotDate = date
+	}
+
+	if csvPath == "" {
+		season, _ := strconv.Atoi(snapshotDate[:4])
+		result, err := newDownloader(cfg).fetch(ctx, leaderboardURL(season), cacheValidators{})
+		if err != nil {
+			return fmt.Errorf("failed to download CSV file: %v", err)
+		}
+		defer os.Remove(result.path)
+		csvPath = result.path
+	}
+
+	incoming, err := parseCSV(csvPath)
+	if err != nil {
+		return err
+	}
+	registry, err := teams.Load(cfg.TeamsFile)
+	if err != nil {
+		return err
+	}
+	season, _ := strconv.Atoi(snapshotDate[:4])
+	resolveTeamIDs(incoming, registry, season)
+
+	previousDate, previous, err := loadStoredSnapshotFromPath(cfg.DatabasePath, snapshotDate)
+	if err != nil {
+		return err
+	}
+
+	diff := diffSnapshots(previous, incoming)
+	diff.previousDate = previousDate
+	diff.snapshotDate = snapshotDate
+	diff.print(w)
+
+	return guardrailsFromConfig(cfg).check(previous, incoming)
+}
+
+// loadStoredSnapshotFromPath is loadStoredSnapshot for a database path that may
+// not exist yet; it never creates the file.
+func loadStoredSnapshotFromPath(dbPath, snapshotDate string) (string, []snapshotRow, error) {
+	if _, err := os.Stat(dbPath); errors.Is(err, os.ErrNotExist) {
+		return "", nil, nil
+	} else if err != nil {
+		return "", nil, err
+	}
+
+	db, err := database.Open(dbPath)
+	if err != nil {
+		return "", nil, err
+	}
+	defer db.Close()
+
+	if err := database.CheckSchemaVersion(db); err != nil {
+		return "", nil, err
+	}
+	return loadStoredSnapshot(db, snapshotDate)
+}
+
+// loadStoredSnapshot returns the date and rows of the latest snapshot on or before
+// snapshotDate in the same season, so a new season is never compared against the
+// previous season's final leaderboard.
+func loadStoredSnapshot(db interface {
+	QueryRow(string, ...any) *sql.Row
+	Query(string, ...any) (*sql.Rows, error)
+}, snapshotDate string) (string, []snapshotRow, error) {
+	var latest sql.NullString
+	seasonStart := snapshotDate[:4] + "-01-01"
+	if err := db.QueryRow("SELECT MAX(date) FROM oaa_snapshots WHERE date <= ? AND date >= ?", snapshotDate, seasonStart).Scan(&latest); err != nil {
+		return "", nil, err
+	}
+	if !latest.Valid {
+		return "", nil, nil
+	}
+
+	rows, err := db.Query(`
+		SELECT o.player_id, p.first_name, p.last_name, p.full_name, COALESCE(t.name, ''), COALESCE(o.team_id, 0), COALESCE(o.primary_position, ''), o.oaa,
+			o.actual_success_rate, o.estimated_success_rate, o.diff_success_rate
+		FROM oaa_snapshots o
+		JOIN players p ON p.id = o.player_id
+		LEFT JOIN teams t ON t.id = o.team_id
+		WHERE o.date = ?
+		ORDER BY o.player_id`, latest.String)
+	if err != nil {
+		return "", nil, err
+	}
+	defer rows.Close()
+
+	var snapshot []snapshotRow
+	for rows.Next() {
+		var row snapshotRow
+		if err := rows.Scan(&row.playerID, &row.firstName, &row.lastName, &row.fullName, &row.team, &row.teamID, &row.primaryPosition, &row.oaa,
+			&row.actualSuccessRate, &row.estimatedSuccessRate, &row.diffSuccessRate); err != nil {
+			return "", nil, err
+		}
+		snapshot = append(snapshot, row)
+	}
+	if err := rows.Err(); err != nil {
+		return "", nil, err
+	}
+
+	return latest.String, snapshot, nil
+}
+
+// resolveTeamIDs fills in the registry ID of each row's team. Stored rows carry
+// the registry's current name for a club, which need not be the leaderboard's
+// spelling, so teams are compared by ID. Unknown names keep ID 0.
+func resolveTeamIDs(rows []snapshotRow, registry *teams.Registry, season int) {
+	for i := range rows {
+		if team, ok := registry.Resolve(rows[i].team, season); ok {
+			rows[i].teamID = team.ID
+		}
+	}
+}
+
+type playerChange struct {
+	previous snapshotRow
+	incoming snapshotRow
+}
+
+// snapshotDiff is a per-player comparison between a stored snapshot and an incoming batch.
+type snapshotDiff struct {
+	snapshotDate    string
+	previousDate    string
+	previousCount   int
+	incomingCount   int
+	added           []snapshotRow
+	dropped         []snapshotRow
+	oaaChanges      []playerChange
+	teamChanges     []playerChange
+	positionChanges []playerChange
+}
+
+func diffSnapshots(previous, incoming []snapshotRow) snapshotDiff {
+	diff := snapshotDiff{
+		previousCount: len(previous),
+		incomingCount: len(incoming),
+	}
+
+	previousByID := make(map[int]snapshotRow, len(previous))
+	for _, row := range previous {
+		previousByID[row.playerID] = row
+	}
+
+	seen := make(map[int]struct{}, len(incoming))
+	for _, row := range incoming {
+		seen[row.playerID] = struct{}{}
+		old, ok := previousByID[row.playerID]
+		if !ok {
+			diff.added = append(diff.added, row)
+			continue
+		}
+
+		change := playerChange{previous: old, incoming: row}
+		if old.oaa != row.oaa {
+			diff.oaaChanges = append(diff.oaaChanges, change)
+		}
+		if old.teamID != row.teamID {
+			diff.teamChanges = append(diff.teamChanges, change)
+		}
+		if old.primaryPosition != row.primaryPosition {
+			diff.positionChanges = append(diff.positionChanges, change)
+		}
+	}
+
+	for _, row := range previous {
+		if _, ok := seen[row.playerID]; !ok {
+			diff.dropped = append(diff.dropped, row)
+		}
+	}
+
+	sortRows := func(rows []snapshotRow) {
+		sort.Slice(rows, func(i, j int) bool { return rows[i].fullName < rows[j].fullName })
+	}
+	sortRows(diff.added)
+	sortRows(diff.dropped)
+	sort.Slice(diff.oaaChanges, func(i, j int) bool {
+		di := diff.oaaChanges[i].incoming.oaa - diff.oaaChanges[i].previous.oaa
+		dj := diff.oaaChanges[j].incoming.oaa - diff.oaaChanges[j].previous.oaa
+		if di == dj {
+			return diff.oaaChanges[i].incoming.fullName < diff.oaaChanges[j].incoming.fullName
+		}
+		return di > dj
+	})
+
+	return diff
+}
+
+func (d snapshotDiff) print(w io.Writer) {
+	if d.previousDate == "" {
+		fmt.Fprintf(w, "Dry run for %s: %d players incoming, no stored snapshot to compare against\n", d.snapshotDate, d.incomingCount)
+	} else {
+		fmt.Fprintf(w, "Dry run for %s: %d players incoming, %d in stored snapshot from %s\n", d.snapshotDate, d.incomingCount, d.previousCount, d.previousDate)
+	}
+
+	fmt.Fprintf(w, "\nNew players (%d):\n", len(d.added))
+	for _, row := range d.added {
+		fmt.Fprintf(w, "  + %s (%d) %s %s, OAA %d\n", row.fullName, row.playerID, row.team, row.primaryPosition, row.oaa)
+	}
+
+	fmt.Fprintf(w, "\nDropped players (%d):\n", len(d.dropped))
+	for _, row := range d.dropped {
+		fmt.Fprintf(w, "  - %s (%d) %s %s, OAA %d\n", row.fullName, row.playerID, row.team, row.primaryPosition, row.oaa)
+	}
+
+	fmt.Fprintf(w, "\nOAA changes (%d):\n", len(d.oaaChanges))
+	for _, change := range d.oaaChanges {
+		fmt.Fprintf(w, "  %s (%d): %d -> %d (%+d)\n", change.incoming.fullName, change.incoming.playerID, change.previous.oaa, change.incoming.oaa, change.incoming.oaa-change.previous.oaa)
+	}
+
+	fmt.Fprintf(w, "\nTeam changes (%d):\n", len(d.teamChanges))
+	for _, change := range d.teamChanges {
+		fmt.Fprintf(w, "  %s (%d): %s -> %s\n", change.incoming.fullName, change.incoming.playerID, change.previous.team, change.incoming.team)
+	}
+
+	fmt.Fprintf(w, "\nPosition changes (%d):\n", len(d.positionChanges))
+	for _, change := range d.positionChanges {
+		fmt.Fprintf(w, "  %s (%d): %s -> %s\n", change.incoming.fullName, change.incoming.playerID, change.previous.primaryPosition, change.incoming.primaryPosition)
+	}
+}
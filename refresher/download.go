@@ -0,0 +1,201 @@
+package refresher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/benfb/oaamonitor/config"
+)
+
+// maxRetryDelay caps the delay between download attempts, including one a
+// server asks for with Retry-After.
+const maxRetryDelay = time.Minute
+
+// cacheValidators are the ETag and Last-Modified values returned by a previous
+// download, replayed as If-None-Match and If-Modified-Since.
+type cacheValidators struct {
+	etag         string
+	lastModified string
+}
+
+// download is the outcome of a leaderboard request. When notModified is set the
+// server confirmed the previous download is current and no file was written.
+type download struct {
+	path        string
+	validators  cacheValidators
+	notModified bool
+}
+
+// downloader fetches leaderboard CSVs, retrying transient failures with
+// exponential backoff and jitter.
+type downloader struct {
+	client    *http.Client
+	retries   int
+	baseDelay time.Duration
+	maxDelay  time.Duration
+	sleep     func(context.Context, time.Duration) error
+}
+
+func newDownloader(cfg *config.Config) *downloader {
+	return &downloader{
+		client:    &http.Client{Timeout: time.Duration(cfg.RequestTimeout) * time.Second},
+		retries:   cfg.DownloadRetries,
+		baseDelay: time.Duration(cfg.RetryBaseDelay) * time.Second,
+		maxDelay:  maxRetryDelay,
+		sleep:     sleepContext,
+	}
+}
+
+// retryableError marks a failed attempt that may succeed if repeated.
+type retryableError struct {
+	err        error
+	retryAfter time.Duration
+}
+
+func (e *retryableError) Error() string { return e.err.Error() }
+func (e *retryableError) Unwrap() error { return e.err }
+
+// fetch downloads url into a temporary file, sending previous as conditional
+// request headers. The caller removes the returned file.
+func (d *downloader) fetch(ctx context.Context, url string, previous cacheValidators) (*download, error) {
+	for attempt := 0; ; attempt++ {
+		result, err := d.attempt(ctx, url, previous)
+		if err == nil {
+			return result, nil
+		}
+
+		var retryable *retryableError
+		if !errors.As(err, &retryable) || attempt >= d.retries {
+			return nil, err
+		}
+
+		delay := d.backoff(attempt, retryable.retryAfter)
+		log.Printf("Download attempt %d of %d failed: %v; retrying in %s", attempt+1, d.retries+1, err, delay)
+		if err := d.sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (d *downloader) attempt(ctx context.Context, url string, previous cacheValidators) (*download, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if previous.etag != "" {
+		req.Header.Set("If-None-Match", previous.etag)
+	}
+	if previous.lastModified != "" {
+		req.Header.Set("If-Modified-Since", previous.lastModified)
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		return nil, &retryableError{err: err}
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified:
+		return &download{validators: previous, notModified: true}, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		retryAfter, _ := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+		return nil, &retryableError{
+			err:        fmt.Errorf("unexpected status %d fetching CSV: %s", resp.StatusCode, strings.TrimSpace(string(body))),
+			retryAfter: retryAfter,
+		}
+	case resp.StatusCode != http.StatusOK:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("unexpected status %d fetching CSV: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	tmpFile, err := os.CreateTemp("", "outs_above_average_*.csv")
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := io.Copy(tmpFile, resp.Body); err != nil {
+		tmpFile.Close()
+		os.Remove(tmpFile.Name())
+		return nil, &retryableError{err: err}
+	}
+
+	if err := tmpFile.Close(); err != nil {
+		os.Remove(tmpFile.Name())
+		return nil, err
+	}
+
+	return &download{
+		path: tmpFile.Name(),
+		validators: cacheValidators{
+			etag:         resp.Header.Get("ETag"),
+			lastModified: resp.Header.Get("Last-Modified"),
+		},
+	}, nil
+}
+
+// backoff returns the delay before retrying after the given zero-based attempt:
+// the server's Retry-After when it sent one, otherwise an exponentially growing
+// delay with equal jitter. Either way it is capped at maxDelay, so a server
+// asking for hours cannot stall the refresh.
+func (d *downloader) backoff(attempt int, retryAfter time.Duration) time.Duration {
+	if retryAfter > 0 {
+		return min(retryAfter, d.maxDelay)
+	}
+	if d.baseDelay <= 0 {
+		return 0
+	}
+
+	delay := d.baseDelay << attempt
+	if delay <= 0 || delay > d.maxDelay {
+		delay = d.maxDelay
+	}
+	half := delay / 2
+	return half + rand.N(half+1)
+}
+
+// parseRetryAfter interprets a Retry-After header given as either delay seconds
+// or an HTTP date.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if delay := at.Sub(now); delay > 0 {
+			return delay, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
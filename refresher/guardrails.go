@@ -0,0 +1,71 @@
+package refresher
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/benfb/oaamonitor/config"
+)
+
+// ErrAnomalousSnapshot is returned when an incoming leaderboard differs from the
+// stored snapshot by more than a routine daily refresh plausibly would.
+var ErrAnomalousSnapshot = errors.New("incoming snapshot looks anomalous")
+
+// guardrails are the thresholds an incoming batch must satisfy, relative to the
+// previous snapshot of the same season, before it is committed. A zero
+// threshold disables that check.
+type guardrails struct {
+	maxRowDropPercent int
+	maxOAASumDrift    int
+}
+
+func guardrailsFromConfig(cfg *config.Config) guardrails {
+	return guardrails{
+		maxRowDropPercent: cfg.MaxRowDropPercent,
+		maxOAASumDrift:    cfg.MaxOAASumDrift,
+	}
+}
+
+// check returns an error wrapping ErrAnomalousSnapshot describing the first
+// threshold the incoming batch exceeds.
+func (g guardrails) check(previous, incoming []snapshotRow) error {
+	if len(incoming) == 0 {
+		return fmt.Errorf("%w: leaderboard has no rows", ErrAnomalousSnapshot)
+	}
+
+	seen := make(map[int]struct{}, len(incoming))
+	for _, row := range incoming {
+		if _, ok := seen[row.playerID]; ok {
+			return fmt.Errorf("%w: player ID %d (%s) appears more than once", ErrAnomalousSnapshot, row.playerID, row.fullName)
+		}
+		seen[row.playerID] = struct{}{}
+	}
+
+	if len(previous) == 0 {
+		return nil
+	}
+
+	if g.maxRowDropPercent > 0 && len(incoming) < len(previous) {
+		dropPercent := (len(previous) - len(incoming)) * 100 / len(previous)
+		if dropPercent > g.maxRowDropPercent {
+			return fmt.Errorf("%w: row count fell from %d to %d (%d%%, limit %d%%)", ErrAnomalousSnapshot, len(previous), len(incoming), dropPercent, g.maxRowDropPercent)
+		}
+	}
+
+	if g.maxOAASumDrift > 0 {
+		drift := sumOAA(incoming) - sumOAA(previous)
+		if drift > g.maxOAASumDrift || -drift > g.maxOAASumDrift {
+			return fmt.Errorf("%w: league-wide OAA sum moved by %+d (limit %d)", ErrAnomalousSnapshot, drift, g.maxOAASumDrift)
+		}
+	}
+
+	return nil
+}
+
+func sumOAA(rows []snapshotRow) int {
+	total := 0
+	for _, row := range rows {
+		total += row.oaa
+	}
+	return total
+}
@@ -0,0 +1,157 @@
+package refresher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/benfb/oaamonitor/config"
+	"github.com/benfb/oaamonitor/teams"
+)
+
+var (
+	csvFileDatePattern    = regexp.MustCompile(`\d{4}-\d{2}-\d{2}`)
+	archiveDirDatePattern = regexp.MustCompile(`(?:^|/)(\d{4})/(\d{2})/(\d{2})/[^/]+$`)
+)
+
+// ImportCSV writes a local leaderboard export into the database as of snapshotDate.
+// When snapshotDate is empty the date is taken from the file name.
+func ImportCSV(ctx context.Context, cfg *config.Config, path, snapshotDate string) error {
+	if snapshotDate == "" {
+		date, ok := dateFromFileName(path)
+		if !ok {
+			return fmt.Errorf("no snapshot date given and none found in file name %q", filepath.Base(path))
+		}
+		snapshotDate = date
+	}
+	if err := validateSnapshotDate(snapshotDate); err != nil {
+		return err
+	}
+
+	registry, err := teams.Load(cfg.TeamsFile)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Importing %s as of %s", path, snapshotDate)
+	src := csvSource{path: path, origin: path, snapshotDate: snapshotDate}
+	if err := processCSV(ctx, cfg.DatabasePath, src, guardrailsFromConfig(cfg), registry); err != nil {
+		return fmt.Errorf("failed to process CSV file %s: %v", path, err)
+	}
+
+	return uploadIfEnabled(ctx, cfg)
+}
+
+// ImportCSVDir imports every CSV (or archived .csv.gz) under dir whose file name
+// contains a YYYY-MM-DD date, or which sits in a YYYY/MM/DD archive directory,
+// oldest first, so later snapshots win when a date appears more than once.
+func ImportCSVDir(ctx context.Context, cfg *config.Config, dir string) error {
+	return importCSVDir(ctx, cfg, dir, false)
+}
+
+// importCSVDir is ImportCSVDir. With skipAnomalous it logs and skips files the
+// guardrails reject instead of stopping at the first one.
+func importCSVDir(ctx context.Context, cfg *config.Config, dir string, skipAnomalous bool) error {
+	files, err := findDatedCSVFiles(dir)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no dated CSV files found in %s", dir)
+	}
+
+	registry, err := teams.Load(cfg.TeamsFile)
+	if err != nil {
+		return err
+	}
+
+	imported := 0
+	for _, file := range files {
+		log.Printf("Importing %s as of %s", file.path, file.date)
+		src := csvSource{path: file.path, origin: file.path, snapshotDate: file.date}
+		err := processCSV(ctx, cfg.DatabasePath, src, guardrailsFromConfig(cfg), registry)
+		if skipAnomalous && errors.Is(err, ErrAnomalousSnapshot) {
+			log.Printf("warning: skipping %s: %v", file.path, err)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to process CSV file %s: %v", file.path, err)
+		}
+		imported++
+	}
+	log.Printf("Imported %d of %d CSV files from %s", imported, len(files), dir)
+
+	return uploadIfEnabled(ctx, cfg)
+}
+
+type datedCSVFile struct {
+	path string
+	date string
+}
+
+func findDatedCSVFiles(dir string) ([]datedCSVFile, error) {
+	var files []datedCSVFile
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || !isCSVFile(path) {
+			return nil
+		}
+
+		date, ok := dateFromPath(path)
+		if !ok {
+			log.Printf("Skipping %s: no YYYY-MM-DD date in file name or path", path)
+			return nil
+		}
+		files = append(files, datedCSVFile{path: path, date: date})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(files, func(i, j int) bool {
+		if files[i].date == files[j].date {
+			return files[i].path < files[j].path
+		}
+		return files[i].date < files[j].date
+	})
+	return files, nil
+}
+
+func isCSVFile(path string) bool {
+	name := strings.ToLower(filepath.Base(path))
+	return strings.HasSuffix(name, ".csv") || strings.HasSuffix(name, ".csv.gz")
+}
+
+func dateFromFileName(path string) (string, bool) {
+	date := csvFileDatePattern.FindString(filepath.Base(path))
+	if date == "" || validateSnapshotDate(date) != nil {
+		return "", false
+	}
+	return date, true
+}
+
+// dateFromPath extends dateFromFileName to archive paths like raw/2025/06/01/1400.csv.gz.
+func dateFromPath(path string) (string, bool) {
+	if date, ok := dateFromFileName(path); ok {
+		return date, true
+	}
+
+	match := archiveDirDatePattern.FindStringSubmatch(filepath.ToSlash(path))
+	if match == nil {
+		return "", false
+	}
+	date := match[1] + "-" + match[2] + "-" + match[3]
+	if validateSnapshotDate(date) != nil {
+		return "", false
+	}
+	return date, true
+}
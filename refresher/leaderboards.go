@@ -0,0 +1,651 @@
+package refresher
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/benfb/oaamonitor/config"
+	"github.com/benfb/oaamonitor/database"
+	"github.com/benfb/oaamonitor/teams"
+)
+
+// leaderboard describes a Baseball Savant leaderboard export: where to download
+// it, which header aliases identify its columns, and which table stores it.
+type leaderboard struct {
+	name string
+	// urlTemplate is the CSV export URL with {season} in place of the year and,
+	// for leaderboards that can be filtered by position, {position} in place of
+	// the Savant position number.
+	urlTemplate string
+	table       string
+	columns     map[csvColumn][]string
+	// optional columns are read when the export has them and left unset otherwise.
+	optional map[csvColumn][]string
+	// metrics lists the numeric columns of a metric leaderboard in table order;
+	// each is stored in the table column of the same name.
+	metrics []csvColumn
+}
+
+func (l leaderboard) url(season int) string {
+	return l.positionURL(season, "")
+}
+
+// positionURL is url filtered to a single Savant position number.
+func (l leaderboard) positionURL(season int, position string) string {
+	return strings.NewReplacer("{season}", strconv.Itoa(season), "{position}", position).Replace(l.urlTemplate)
+}
+
+// resolveColumns resolves the leaderboard's required and optional columns in header.
+func (l leaderboard) resolveColumns(header []string) (csvColumns, error) {
+	columns, err := resolveCSVColumns(header, l.columns)
+	if err != nil {
+		return nil, err
+	}
+
+	indexes := csvHeaderIndexes(header)
+	for column, aliases := range l.optional {
+		if index, ok := findCSVColumn(indexes, aliases); ok {
+			columns[column] = index
+		}
+	}
+	return columns, nil
+}
+
+var oaaLeaderboard = leaderboard{
+	name:        "oaa",
+	urlTemplate: "https://baseballsavant.mlb.com/leaderboard/outs_above_average?type=Fielder&startYear={season}&endYear={season}&split=yes&team=&range=year&min=10&pos={position}&roles=&viz=hide&csv=true",
+	table:       "outs_above_average",
+	columns: map[csvColumn][]string{
+		csvColumnName: {
+			"name",
+			"player",
+			"playername",
+			"last_name, first_name",
+			"fielder",
+			"fieldername",
+			"fielder_name",
+			"entityname",
+		},
+		csvColumnPlayerID: {
+			"playerid",
+			"player_id",
+			"mlbid",
+			"mlb_id",
+		},
+		csvColumnTeam: {
+			"team",
+			"teamname",
+			"team_name",
+			"display_team_name",
+		},
+		csvColumnPrimaryPosition: {
+			"primaryposition",
+			"primary_position",
+			"primarypos",
+			"primary_pos",
+			"primaryposformatted",
+			"primary_pos_formatted",
+			"position",
+			"pos",
+		},
+		csvColumnOAA: {
+			"oaa",
+			"outsaboveaverage",
+			"outs_above_average",
+		},
+		csvColumnActualSuccessRate: {
+			"actualsuccessrate",
+			"actual_success_rate",
+			"actualsuccessrateformatted",
+			"actual_success_rate_formatted",
+		},
+		csvColumnEstimatedSuccessRate: {
+			"estimatedsuccessrate",
+			"estimated_success_rate",
+			"estimatedsuccessrateformatted",
+			"estimated_success_rate_formatted",
+			"adj_estimated_success_rate_formatted",
+		},
+		csvColumnDiffSuccessRate: {
+			"diffsuccessrate",
+			"diff_success_rate",
+			"diffsuccessrateformatted",
+			"diff_success_rate_formatted",
+			"differenceinsuccessrate",
+			"difference_in_success_rate",
+		},
+	},
+	optional: map[csvColumn][]string{
+		csvColumnOAAInFront: {
+			"outs_above_average_infront",
+			"oaa_infront",
+			"oaa_in_front",
+		},
+		csvColumnOAATowardThirdBase: {
+			"outs_above_average_lateral_toward3bline",
+			"oaa_lateral_toward3bline",
+			"oaa_toward_3b",
+		},
+		csvColumnOAATowardFirstBase: {
+			"outs_above_average_lateral_toward1bline",
+			"oaa_lateral_toward1bline",
+			"oaa_toward_1b",
+		},
+		csvColumnOAABehind: {
+			"outs_above_average_behind",
+			"oaa_behind",
+		},
+	},
+}
+
+// oaaPositions are the Savant position numbers the OAA leaderboard can be
+// filtered by, with the abbreviation stored in oaa_by_position.
+var oaaPositions = []struct {
+	number       string
+	abbreviation string
+}{
+	{"3", "1B"},
+	{"4", "2B"},
+	{"5", "3B"},
+	{"6", "SS"},
+	{"7", "LF"},
+	{"8", "CF"},
+	{"9", "RF"},
+}
+
+// positionOAAName selects the per-position OAA leaderboards in LEADERBOARDS.
+const positionOAAName = "oaa_by_position"
+
+// metricIdentityColumns identify the player on every metric leaderboard. Team is
+// optional because the framing and jump exports omit it.
+var metricIdentityColumns = map[csvColumn][]string{
+	csvColumnName: append([]string{
+		"name_display_last_first",
+		"name_display_first_last",
+	}, oaaLeaderboard.columns[csvColumnName]...),
+	csvColumnPlayerID: append([]string{
+		"id",
+		"entity_id",
+		"resp_fielder_id",
+	}, oaaLeaderboard.columns[csvColumnPlayerID]...),
+}
+
+// metricOptionalColumns are read from every metric leaderboard that has them.
+var metricOptionalColumns = map[csvColumn][]string{
+	csvColumnTeam: oaaLeaderboard.columns[csvColumnTeam],
+}
+
+// metricLeaderboards are the fielding leaderboards ingested alongside OAA.
+var metricLeaderboards = []leaderboard{
+	{
+		name:        "fielding_run_value",
+		urlTemplate: "https://baseballsavant.mlb.com/leaderboard/fielding-run-value?gameType=Regular&seasonStart={season}&seasonEnd={season}&type=fielder&position=&minInnings=0&minResults=1&csv=true",
+		table:       "fielding_run_value",
+		columns: metricColumns(map[csvColumn][]string{
+			"total_runs": {"total_runs", "run_value", "fielding_run_value", "frv"},
+			"arm_runs":   {"arm_runs", "run_value_arm", "arm"},
+			"range_runs": {"range_runs", "run_value_range", "range"},
+		}),
+		optional: metricOptionalColumns,
+		metrics:  []csvColumn{"total_runs", "arm_runs", "range_runs"},
+	},
+	{
+		name:        "arm_strength",
+		urlTemplate: "https://baseballsavant.mlb.com/leaderboard/arm-strength?type=player&year={season}&minThrows=50&pos=&team=&csv=true",
+		table:       "arm_strength",
+		columns: metricColumns(map[csvColumn][]string{
+			"arm_overall":      {"arm_overall", "arm_strength"},
+			"max_arm_strength": {"max_arm_strength", "max_arm"},
+		}),
+		optional: metricOptionalColumns,
+		metrics:  []csvColumn{"arm_overall", "max_arm_strength"},
+	},
+	{
+		name:        "catcher_framing",
+		urlTemplate: "https://baseballsavant.mlb.com/leaderboard/catcher-framing?type=catcher&seasonStart={season}&seasonEnd={season}&team=&min=q&sortColumn=rv_tot&sortDirection=desc&csv=true",
+		table:       "catcher_framing",
+		columns: metricColumns(map[csvColumn][]string{
+			"framing_runs": {"rv_tot", "runs_extra_strikes", "framing_runs"},
+			"strike_rate":  {"pct_tot", "strike_rate"},
+		}),
+		optional: metricOptionalColumns,
+		metrics:  []csvColumn{"framing_runs", "strike_rate"},
+	},
+	{
+		name:        "outfield_jump",
+		urlTemplate: "https://baseballsavant.mlb.com/leaderboard/outfield_jump?year={season}&min=q&csv=true",
+		table:       "outfield_jump",
+		columns: metricColumns(map[csvColumn][]string{
+			"jump_feet":     {"rel_league_bootup_distance", "jump", "feet_vs_avg"},
+			"reaction_feet": {"rel_league_reaction_distance", "reaction"},
+			"burst_feet":    {"rel_league_burst_distance", "burst"},
+			"route_feet":    {"rel_league_routing_distance", "route"},
+		}),
+		optional: metricOptionalColumns,
+		metrics:  []csvColumn{"jump_feet", "reaction_feet", "burst_feet", "route_feet"},
+	},
+}
+
+func metricColumns(metrics map[csvColumn][]string) map[csvColumn][]string {
+	columns := make(map[csvColumn][]string, len(metricIdentityColumns)+len(metrics))
+	for column, aliases := range metricIdentityColumns {
+		columns[column] = aliases
+	}
+	for column, aliases := range metrics {
+		columns[column] = aliases
+	}
+	return columns
+}
+
+// findMetricLeaderboards resolves a comma-separated list of leaderboard names;
+// "none" selects no leaderboards.
+func findMetricLeaderboards(names string) ([]leaderboard, error) {
+	if strings.TrimSpace(names) == "none" {
+		return nil, nil
+	}
+
+	var boards []leaderboard
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" || name == positionOAAName {
+			continue
+		}
+		board, ok := findMetricLeaderboard(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown leaderboard %q", name)
+		}
+		boards = append(boards, board)
+	}
+	return boards, nil
+}
+
+func findMetricLeaderboard(name string) (leaderboard, bool) {
+	for _, board := range metricLeaderboards {
+		if board.name == name {
+			return board, true
+		}
+	}
+	return leaderboard{}, false
+}
+
+// refreshMetricLeaderboards refreshes every configured metric leaderboard for a
+// season. A failing leaderboard is logged rather than returned so a change to a
+// secondary export never blocks the OAA refresh.
+func refreshMetricLeaderboards(ctx context.Context, cfg *config.Config, season int, snapshotDate string) (bool, error) {
+	boards, err := findMetricLeaderboards(cfg.Leaderboards)
+	if err != nil {
+		return false, err
+	}
+
+	anyChanged := false
+	for _, board := range boards {
+		changed, err := refreshMetricLeaderboard(ctx, cfg, board, season, snapshotDate)
+		if err != nil {
+			log.Printf("warning: failed to refresh %s leaderboard: %v", board.name, err)
+			continue
+		}
+		anyChanged = anyChanged || changed
+	}
+
+	if wantsPositionOAA(cfg.Leaderboards) {
+		for _, position := range oaaPositions {
+			changed, err := refreshPositionOAA(ctx, cfg, season, snapshotDate, position.number, position.abbreviation)
+			if err != nil {
+				log.Printf("warning: failed to refresh %s OAA leaderboard: %v", position.abbreviation, err)
+				continue
+			}
+			anyChanged = anyChanged || changed
+		}
+	}
+	return anyChanged, nil
+}
+
+func wantsPositionOAA(names string) bool {
+	for _, name := range strings.Split(names, ",") {
+		if strings.TrimSpace(name) == positionOAAName {
+			return true
+		}
+	}
+	return false
+}
+
+func refreshMetricLeaderboard(ctx context.Context, cfg *config.Config, board leaderboard, season int, snapshotDate string) (bool, error) {
+	url := board.url(season)
+	validators, err := lastValidators(cfg.DatabasePath, url, snapshotDate)
+	if err != nil {
+		log.Printf("warning: ignoring cached validators: %v", err)
+		validators = cacheValidators{}
+	}
+
+	result, err := newDownloader(cfg).fetch(ctx, url, validators)
+	if err != nil {
+		return false, fmt.Errorf("failed to download CSV file: %v", err)
+	}
+	if result.notModified {
+		log.Printf("%s leaderboard unchanged since the last fetch for %s; skipping.", board.name, snapshotDate)
+		return false, nil
+	}
+	defer os.Remove(result.path)
+
+	registry, err := teams.Load(cfg.TeamsFile)
+	if err != nil {
+		return false, err
+	}
+
+	src := csvSource{path: result.path, origin: url, snapshotDate: snapshotDate, validators: result.validators}
+	if err := processMetricCSV(ctx, cfg.DatabasePath, src, board, registry); err != nil {
+		return false, fmt.Errorf("failed to process CSV file: %v", err)
+	}
+
+	log.Printf("%s leaderboard successfully inserted or updated in the database.", board.name)
+	return true, nil
+}
+
+// metricRow is a single player's line from a metric leaderboard. Values follow
+// the leaderboard's metrics order; blank cells are stored as NULL.
+type metricRow struct {
+	playerID int
+	// firstName and lastName are set only when the export lists names as
+	// "Last, First".
+	firstName sql.NullString
+	lastName  sql.NullString
+	fullName  string
+	team      string
+	values    []sql.NullFloat64
+}
+
+func processMetricCSV(ctx context.Context, dbPath string, src csvSource, board leaderboard, registry *teams.Registry) (err error) {
+	if err := validateSnapshotDate(src.snapshotDate); err != nil {
+		return err
+	}
+
+	db, err := database.Open(dbPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := database.EnsureSchema(db, registry); err != nil {
+		return err
+	}
+
+	run := newIngestRun(src)
+	defer func() {
+		run.finish(err)
+		if recordErr := recordIngestRun(db, run); recordErr != nil {
+			log.Printf("warning: failed to record ingest run: %v", recordErr)
+		}
+	}()
+
+	if run.sha256, err = hashFile(src.path); err != nil {
+		return err
+	}
+
+	rows, err := parseMetricCSV(src.path, board)
+	if err != nil {
+		return err
+	}
+	run.rowCount = len(rows)
+	if err := checkMetricRows(rows); err != nil {
+		return err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			tx.Rollback()
+		}
+	}()
+
+	season, _ := strconv.Atoi(src.snapshotDate[:4])
+	writer, err := newMetricWriter(tx, board, registry, season)
+	if err != nil {
+		return err
+	}
+	defer writer.Close()
+
+	for _, row := range rows {
+		outcome, err := writer.write(row, src.snapshotDate)
+		if err != nil {
+			return fmt.Errorf("error writing record %v: %w", row.fullName, err)
+		}
+		run.count(outcome)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	committed = true
+	return nil
+}
+
+// checkMetricRows applies the structural guardrails that do not depend on OAA.
+func checkMetricRows(rows []metricRow) error {
+	if len(rows) == 0 {
+		return fmt.Errorf("%w: leaderboard has no rows", ErrAnomalousSnapshot)
+	}
+	seen := make(map[int]struct{}, len(rows))
+	for _, row := range rows {
+		if _, ok := seen[row.playerID]; ok {
+			return fmt.Errorf("%w: duplicate player ID %d (%s)", ErrAnomalousSnapshot, row.playerID, row.fullName)
+		}
+		seen[row.playerID] = struct{}{}
+	}
+	return nil
+}
+
+func parseMetricCSV(path string, board leaderboard) ([]metricRow, error) {
+	file, reader, header, err := openCSVReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	columns, err := board.resolveColumns(header)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []metricRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading CSV record: %v", err)
+		}
+
+		row, err := processMetricRecord(record, columns, board.metrics)
+		if err != nil {
+			return nil, fmt.Errorf("error processing record %v: %w", columns.value(record, csvColumnName), err)
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+func processMetricRecord(record []string, columns csvColumns, metrics []csvColumn) (metricRow, error) {
+	name := strings.TrimSpace(columns.value(record, csvColumnName))
+	if name == "" {
+		return metricRow{}, fmt.Errorf("missing %s", csvColumnName)
+	}
+	// Most exports use "Last, First"; the rest already use display order.
+	var firstName, lastName sql.NullString
+	if last, first, ok := strings.Cut(name, ","); ok {
+		firstName = sql.NullString{String: strings.TrimSpace(first), Valid: true}
+		lastName = sql.NullString{String: strings.TrimSpace(last), Valid: true}
+		name = firstName.String + " " + lastName.String
+	}
+
+	playerIDValue := columns.value(record, csvColumnPlayerID)
+	playerID, err := strconv.Atoi(strings.TrimSpace(playerIDValue))
+	if err != nil {
+		return metricRow{}, fmt.Errorf("invalid player ID: %v", playerIDValue)
+	}
+
+	row := metricRow{
+		playerID:  playerID,
+		firstName: firstName,
+		lastName:  lastName,
+		fullName:  name,
+		team:      columns.value(record, csvColumnTeam),
+		values:    make([]sql.NullFloat64, len(metrics)),
+	}
+	for i, metric := range metrics {
+		value, err := parseMetricValue(columns.value(record, metric))
+		if err != nil {
+			return metricRow{}, fmt.Errorf("invalid %s value: %v", metric, err)
+		}
+		row.values[i] = value
+	}
+	return row, nil
+}
+
+func parseMetricValue(value string) (sql.NullFloat64, error) {
+	value = strings.TrimSpace(value)
+	if value == "" || value == "--" {
+		return sql.NullFloat64{}, nil
+	}
+	if strings.HasSuffix(value, "%") {
+		parsed, err := parsePercentage(value)
+		if err != nil {
+			return sql.NullFloat64{}, err
+		}
+		return sql.NullFloat64{Float64: parsed, Valid: true}, nil
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return sql.NullFloat64{}, err
+	}
+	return sql.NullFloat64{Float64: parsed, Valid: true}, nil
+}
+
+// metricWriter is snapshotWriter for a metric leaderboard's table.
+type metricWriter struct {
+	exists *sql.Stmt
+	player *sql.Stmt
+	name   *sql.Stmt
+	upsert *sql.Stmt
+	teams  *teamResolver
+	table  string
+}
+
+func newMetricWriter(tx *sql.Tx, board leaderboard, registry *teams.Registry, season int) (*metricWriter, error) {
+	w := &metricWriter{table: board.table}
+	var err error
+	if w.teams, err = newTeamResolver(tx, registry, season); err != nil {
+		return nil, err
+	}
+	if w.exists, err = tx.Prepare(fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE player_id = ? AND date = ?`, board.table)); err != nil {
+		w.Close()
+		return nil, err
+	}
+	// The OAA leaderboard owns player names; a metric leaderboard only adds
+	// the players it has not seen, such as most catchers. Its own spelling
+	// still goes into player_names.
+	if w.player, err = tx.Prepare(`
+	INSERT INTO players (id, first_name, last_name, full_name, updated_on)
+	VALUES (?, ?, ?, ?, ?)
+	ON CONFLICT(id) DO NOTHING`); err != nil {
+		w.Close()
+		return nil, err
+	}
+	if w.name, err = preparePlayerNameStatement(tx); err != nil {
+		w.Close()
+		return nil, err
+	}
+
+	columns := []string{"team_id"}
+	for _, metric := range board.metrics {
+		columns = append(columns, string(metric))
+	}
+	updates := make([]string, len(columns))
+	changed := make([]string, len(columns))
+	for i, column := range columns {
+		updates[i] = fmt.Sprintf("%s = excluded.%s", column, column)
+		changed[i] = fmt.Sprintf("%s IS NOT excluded.%s", column, column)
+	}
+	upsertSQL := fmt.Sprintf(`
+	INSERT INTO %s (player_id, %s, date)
+	VALUES (?%s, ?)
+	ON CONFLICT(player_id, date) DO UPDATE SET %s
+	WHERE %s`,
+		board.table, strings.Join(columns, ", "), strings.Repeat(", ?", len(columns)),
+		strings.Join(updates, ", "), strings.Join(changed, " OR "))
+
+	if w.upsert, err = tx.Prepare(upsertSQL); err != nil {
+		w.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *metricWriter) write(row metricRow, snapshotDate string) (ingestOutcome, error) {
+	teamID, err := w.teams.resolve(row.team)
+	if err != nil {
+		return 0, err
+	}
+
+	var existing int
+	if err := w.exists.QueryRow(row.playerID, snapshotDate).Scan(&existing); err != nil {
+		return 0, err
+	}
+
+	if _, err := w.player.Exec(row.playerID, row.firstName, row.lastName, row.fullName, snapshotDate); err != nil {
+		return 0, fmt.Errorf("failed to write player: %v", err)
+	}
+	if _, err := w.name.Exec(row.playerID, row.fullName, w.table, snapshotDate); err != nil {
+		return 0, fmt.Errorf("failed to record player name: %v", err)
+	}
+
+	args := []any{row.playerID, teamID}
+	for _, value := range row.values {
+		args = append(args, value)
+	}
+	args = append(args, snapshotDate)
+
+	result, err := w.upsert.Exec(args...)
+	if err != nil {
+		return 0, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	switch {
+	case existing == 0:
+		return outcomeInserted, nil
+	case affected > 0:
+		return outcomeUpdated, nil
+	default:
+		return outcomeUnchanged, nil
+	}
+}
+
+func (w *metricWriter) Close() error {
+	if w.teams != nil {
+		w.teams.Close()
+	}
+	for _, stmt := range []*sql.Stmt{w.exists, w.player, w.name} {
+		if stmt != nil {
+			stmt.Close()
+		}
+	}
+	if w.upsert == nil {
+		return nil
+	}
+	return w.upsert.Close()
+}
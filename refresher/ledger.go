@@ -0,0 +1,134 @@
+package refresher
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"time"
+
+	"github.com/benfb/oaamonitor/database"
+)
+
+type ingestOutcome int
+
+const (
+	outcomeInserted ingestOutcome = iota
+	outcomeUpdated
+	outcomeUnchanged
+)
+
+// ingestRun accumulates the provenance recorded in ingest_runs for one CSV.
+type ingestRun struct {
+	startedAt    time.Time
+	finishedAt   time.Time
+	source       string
+	snapshotDate string
+	rowCount     int
+	inserted     int
+	updated      int
+	unchanged    int
+	sha256       string
+	validators   cacheValidators
+	err          error
+}
+
+func newIngestRun(src csvSource) *ingestRun {
+	source := src.origin
+	if source == "" {
+		source = src.path
+	}
+	return &ingestRun{
+		startedAt:    time.Now().UTC(),
+		source:       source,
+		snapshotDate: src.snapshotDate,
+		validators:   src.validators,
+	}
+}
+
+func (r *ingestRun) count(outcome ingestOutcome) {
+	switch outcome {
+	case outcomeInserted:
+		r.inserted++
+	case outcomeUpdated:
+		r.updated++
+	case outcomeUnchanged:
+		r.unchanged++
+	}
+}
+
+// finish stamps the end time and, for failed runs, clears the write counts
+// because the transaction that produced them was rolled back.
+func (r *ingestRun) finish(err error) {
+	r.finishedAt = time.Now().UTC()
+	r.err = err
+	if err != nil {
+		r.inserted, r.updated, r.unchanged = 0, 0, 0
+	}
+}
+
+func recordIngestRun(db *sql.DB, run *ingestRun) error {
+	var errText sql.NullString
+	if run.err != nil {
+		errText = sql.NullString{String: run.err.Error(), Valid: true}
+	}
+
+	_, err := db.Exec(`
+	INSERT INTO ingest_runs (started_at, finished_at, source, snapshot_date, row_count, inserted_count, updated_count, unchanged_count, csv_sha256, etag, last_modified, error)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		run.startedAt, run.finishedAt, run.source, run.snapshotDate, run.rowCount, run.inserted, run.updated, run.unchanged, run.sha256,
+		nullIfEmpty(run.validators.etag), nullIfEmpty(run.validators.lastModified), errText)
+	return err
+}
+
+// lastValidators returns the cache validators of the last successful ingest of
+// source for snapshotDate. Validators are only reused within a snapshot date so
+// an unchanged leaderboard never leaves a new day without a snapshot.
+func lastValidators(dbPath, source, snapshotDate string) (cacheValidators, error) {
+	if _, err := os.Stat(dbPath); errors.Is(err, os.ErrNotExist) {
+		return cacheValidators{}, nil
+	} else if err != nil {
+		return cacheValidators{}, err
+	}
+
+	db, err := database.Open(dbPath)
+	if err != nil {
+		return cacheValidators{}, err
+	}
+	defer db.Close()
+
+	var etag, lastModified sql.NullString
+	err = db.QueryRow(`
+		SELECT etag, last_modified FROM ingest_runs
+		WHERE source = ? AND snapshot_date = ? AND error IS NULL
+		ORDER BY finished_at DESC
+		LIMIT 1`, source, snapshotDate).Scan(&etag, &lastModified)
+	if err == sql.ErrNoRows {
+		return cacheValidators{}, nil
+	}
+	if err != nil {
+		return cacheValidators{}, err
+	}
+	return cacheValidators{etag: etag.String, lastModified: lastModified.String}, nil
+}
+
+func nullIfEmpty(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
+
+// hashFile returns the hex SHA-256 of a CSV's uncompressed contents.
+func hashFile(path string) (string, error) {
+	file, err := openCSV(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
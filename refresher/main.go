@@ -8,8 +8,8 @@ import (
 	"fmt"
 	"io"
 	"log"
-	"net/http"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -17,103 +17,189 @@ import (
 	"github.com/benfb/oaamonitor/config"
 	"github.com/benfb/oaamonitor/database"
 	"github.com/benfb/oaamonitor/storage"
+	"github.com/benfb/oaamonitor/teams"
 )
 
+// GetLatestOAA downloads the current season's leaderboard and records it under the
+// configured snapshot date, or today's baseball day when none is configured.
 func GetLatestOAA(ctx context.Context, cfg *config.Config) error {
-	currentYear := time.Now().Year()
-	url := fmt.Sprintf("https://baseballsavant.mlb.com/leaderboard/outs_above_average?type=Fielder&startYear=%d&endYear=%d&split=yes&team=&range=year&min=10&pos=&roles=&viz=hide&csv=true", currentYear, currentYear)
+	snapshotDate, err := resolveSnapshotDate(cfg, time.Now())
+	if err != nil {
+		return err
+	}
+	// The snapshot date, not the wall clock, decides which season is current,
+	// so a New Year's Eve run stays in the season it belongs to.
+	season, _ := strconv.Atoi(snapshotDate[:4])
 
-	timeout := time.Duration(cfg.RequestTimeout) * time.Second
-	tmpFile, err := downloadFile(ctx, url, timeout)
+	changed, err := refreshSeason(ctx, cfg, season, snapshotDate)
 	if err != nil {
-		return fmt.Errorf("failed to download CSV file: %v", err)
+		return err
+	}
+	metricsChanged, err := refreshMetricLeaderboards(ctx, cfg, season, snapshotDate)
+	if err != nil {
+		return err
 	}
-	defer os.Remove(tmpFile.Name())
+	if !changed && !metricsChanged {
+		return nil
+	}
+	return uploadIfEnabled(ctx, cfg)
+}
 
-	if err := processCSV(ctx, tmpFile.Name(), cfg.DatabasePath); err != nil {
-		return fmt.Errorf("failed to process CSV file: %v", err)
+// BackfillSeasons downloads the end-of-season leaderboard for every season from
+// startSeason through endSeason and records each one as of that season's final day.
+func BackfillSeasons(ctx context.Context, cfg *config.Config, startSeason, endSeason int) error {
+	if startSeason > endSeason {
+		return fmt.Errorf("invalid season range %d..%d", startSeason, endSeason)
 	}
 
-	log.Println("CSV data successfully inserted or updated in the database.")
+	today, err := baseballDay(time.Now())
+	if err != nil {
+		return err
+	}
+	anyChanged := false
+	for season := startSeason; season <= endSeason; season++ {
+		snapshotDate := SeasonEndDate(season)
+		if snapshotDate > today {
+			// The season is still in progress, so the leaderboard only runs through today.
+			snapshotDate = today
+		}
 
-	if cfg.UploadDatabase {
-		if err := database.CheckpointWAL(cfg.DatabasePath); err != nil {
-			return fmt.Errorf("failed to checkpoint WAL before upload: %v", err)
+		log.Printf("Backfilling %d season as of %s", season, snapshotDate)
+		changed, err := refreshSeason(ctx, cfg, season, snapshotDate)
+		if err != nil {
+			return fmt.Errorf("season %d: %w", season, err)
 		}
-		if err := storage.UploadDatabase(ctx, cfg.DatabasePath); err != nil {
-			return fmt.Errorf("failed to upload database: %v", err)
+		metricsChanged, err := refreshMetricLeaderboards(ctx, cfg, season, snapshotDate)
+		if err != nil {
+			return fmt.Errorf("season %d: %w", season, err)
 		}
-		log.Println("Database successfully uploaded to object storage.")
+		anyChanged = anyChanged || changed || metricsChanged
 	}
 
-	return nil
+	if !anyChanged {
+		return nil
+	}
+	return uploadIfEnabled(ctx, cfg)
 }
 
-func downloadFile(ctx context.Context, url string, timeout time.Duration) (*os.File, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+func leaderboardURL(season int) string {
+	return oaaLeaderboard.url(season)
+}
+
+// refreshSeason downloads a season's leaderboard and writes it into the database
+// as of snapshotDate. It reports false without touching the database when the
+// server confirms the leaderboard is unchanged since this date was last fetched.
+func refreshSeason(ctx context.Context, cfg *config.Config, season int, snapshotDate string) (bool, error) {
+	url := leaderboardURL(season)
+	validators, err := lastValidators(cfg.DatabasePath, url, snapshotDate)
 	if err != nil {
-		return nil, err
+		log.Printf("warning: ignoring cached validators: %v", err)
+		validators = cacheValidators{}
 	}
 
-	client := &http.Client{Timeout: timeout}
-	resp, err := client.Do(req)
+	result, err := newDownloader(cfg).fetch(ctx, url, validators)
 	if err != nil {
-		return nil, err
+		return false, fmt.Errorf("failed to download CSV file: %v", err)
+	}
+	if result.notModified {
+		log.Printf("Leaderboard unchanged since the last fetch for %s; skipping refresh.", snapshotDate)
+		return false, nil
 	}
-	defer resp.Body.Close()
+	defer os.Remove(result.path)
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
-		return nil, fmt.Errorf("unexpected status %d fetching CSV: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	// Archive before processing so leaderboards the parser or guardrails
+	// reject are kept for auditing too; a re-ingest skips the anomalous ones.
+	if err := archiveCSV(ctx, cfg, result.path, snapshotDate, time.Now()); err != nil {
+		log.Printf("warning: failed to archive raw CSV: %v", err)
 	}
 
-	tmpFile, err := os.CreateTemp("", "outs_above_average_*.csv")
+	registry, err := teams.Load(cfg.TeamsFile)
 	if err != nil {
-		return nil, err
+		return false, err
 	}
 
-	if _, err := io.Copy(tmpFile, resp.Body); err != nil {
-		tmpFile.Close()
-		os.Remove(tmpFile.Name())
-		return nil, err
+	src := csvSource{path: result.path, origin: url, snapshotDate: snapshotDate, validators: result.validators}
+	if err := processCSV(ctx, cfg.DatabasePath, src, guardrailsFromConfig(cfg), registry); err != nil {
+		return false, fmt.Errorf("failed to process CSV file: %v", err)
 	}
 
-	if err := tmpFile.Close(); err != nil {
-		return nil, err
+	log.Println("CSV data successfully inserted or updated in the database.")
+	return true, nil
+}
+
+func uploadIfEnabled(ctx context.Context, cfg *config.Config) error {
+	if cfg.UploadDatabase {
+		compression, err := storage.ParseCompression(cfg.Compression)
+		if err != nil {
+			return err
+		}
+
+		// Upload a backup rather than the live file, which a concurrent writer
+		// could change mid-upload.
+		dir, err := os.MkdirTemp("", "oaamonitor-upload")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(dir)
+
+		snapshot := filepath.Join(dir, "oaamonitor.db")
+		if err := database.BackupFile(cfg.DatabasePath, snapshot); err != nil {
+			return fmt.Errorf("failed to back up database before upload: %v", err)
+		}
+		backend, err := storage.Open(cfg.StorageURL)
+		if err != nil {
+			return err
+		}
+		key, err := storage.UploadDatabase(ctx, backend, snapshot, time.Now(), compression)
+		if err != nil {
+			return fmt.Errorf("failed to upload database: %v", err)
+		}
+		log.Printf("Database successfully uploaded to object storage as %s.", key)
 	}
 
-	return tmpFile, nil
+	return nil
 }
 
-func processCSV(ctx context.Context, filepath, dbPath string) error {
-	file, err := os.Open(filepath)
-	if err != nil {
+// csvSource describes a leaderboard CSV on disk and where it came from.
+type csvSource struct {
+	path         string
+	origin       string
+	snapshotDate string
+	validators   cacheValidators
+}
+
+func processCSV(ctx context.Context, dbPath string, src csvSource, checks guardrails, registry *teams.Registry) (err error) {
+	if err := validateSnapshotDate(src.snapshotDate); err != nil {
 		return err
 	}
-	defer file.Close()
-
-	reader := csv.NewReader(removeBOM(file))
-	reader.LazyQuotes = true
 
-	header, err := reader.Read()
+	db, err := database.Open(dbPath)
 	if err != nil {
-		return fmt.Errorf("failed to read CSV header: %v", err)
+		return err
 	}
+	defer db.Close()
 
-	columns, err := resolveCSVColumns(header)
-	if err != nil {
+	if err := database.EnsureSchema(db, registry); err != nil {
 		return err
 	}
 
-	db, err := database.Open(dbPath)
-	if err != nil {
+	run := newIngestRun(src)
+	defer func() {
+		run.finish(err)
+		if recordErr := recordIngestRun(db, run); recordErr != nil {
+			log.Printf("warning: failed to record ingest run: %v", recordErr)
+		}
+	}()
+
+	if run.sha256, err = hashFile(src.path); err != nil {
 		return err
 	}
-	defer db.Close()
 
-	if err := database.EnsureSchema(db); err != nil {
+	rows, err := parseCSV(src.path)
+	if err != nil {
 		return err
 	}
+	run.rowCount = len(rows)
 
 	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
@@ -126,49 +212,300 @@ func processCSV(ctx context.Context, filepath, dbPath string) error {
 		}
 	}()
 
-	stmt, err := prepareInsertStatement(tx)
+	_, previous, err := loadStoredSnapshot(tx, src.snapshotDate)
 	if err != nil {
 		return err
 	}
-	defer stmt.Close()
 
+	if err := database.PrepareSnapshotDate(tx, src.snapshotDate); err != nil {
+		return err
+	}
+
+	season, _ := strconv.Atoi(src.snapshotDate[:4])
+	writer, err := newSnapshotWriter(tx, registry, season)
+	if err != nil {
+		return err
+	}
+	defer writer.Close()
+
+	for _, row := range rows {
+		outcome, err := writer.write(row, src.snapshotDate)
+		if err != nil {
+			return fmt.Errorf("error writing record %v: %w", row.fullName, err)
+		}
+		run.count(outcome)
+	}
+
+	if err := checks.check(previous, rows); err != nil {
+		return err
+	}
+
+	if err := rebuildTeamStints(tx, src.snapshotDate); err != nil {
+		return fmt.Errorf("failed to rebuild team stints: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	committed = true
+	return nil
+}
+
+// parseCSV reads a leaderboard CSV and parses every record, failing on the first bad row.
+func parseCSV(path string) ([]snapshotRow, error) {
+	file, reader, header, err := openCSVReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	columns, err := oaaLeaderboard.resolveColumns(header)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []snapshotRow
 	for {
 		record, err := reader.Read()
 		if err == io.EOF {
 			break
 		}
 		if err != nil {
-			return fmt.Errorf("error reading CSV record: %v", err)
+			return nil, fmt.Errorf("error reading CSV record: %v", err)
 		}
 
-		if err := processRecord(stmt, record, columns); err != nil {
-			return fmt.Errorf("error processing record %v: %w", columns.value(record, csvColumnName), err)
+		row, err := processRecord(record, columns)
+		if err != nil {
+			return nil, fmt.Errorf("error processing record %v: %w", columns.value(record, csvColumnName), err)
 		}
+		rows = append(rows, row)
 	}
 
-	if err := tx.Commit(); err != nil {
-		return err
+	return rows, nil
+}
+
+// openCSVReader opens a leaderboard CSV, plain or gzip-compressed, and reads its header.
+func openCSVReader(path string) (io.Closer, *csv.Reader, []string, error) {
+	file, err := openCSV(path)
+	if err != nil {
+		return nil, nil, nil, err
 	}
-	committed = true
-	return nil
+
+	reader := csv.NewReader(removeBOM(file))
+	reader.LazyQuotes = true
+
+	header, err := reader.Read()
+	if err != nil {
+		file.Close()
+		return nil, nil, nil, fmt.Errorf("failed to read CSV header: %v", err)
+	}
+	return file, reader, header, nil
+}
+
+// snapshotWriter upserts parsed rows and reports whether each one was new,
+// changed, or identical to the row already stored for that date.
+type snapshotWriter struct {
+	exists      *sql.Stmt
+	player      *sql.Stmt
+	name        *sql.Stmt
+	upsert      *sql.Stmt
+	directional *sql.Stmt
+	teams       *teamResolver
+}
+
+func newSnapshotWriter(tx *sql.Tx, registry *teams.Registry, season int) (*snapshotWriter, error) {
+	w := &snapshotWriter{}
+	var err error
+	if w.teams, err = newTeamResolver(tx, registry, season); err != nil {
+		return nil, err
+	}
+	if w.exists, err = tx.Prepare(`SELECT COUNT(*) FROM outs_above_average WHERE player_id = ? AND date = ?`); err != nil {
+		w.Close()
+		return nil, err
+	}
+	if w.player, err = tx.Prepare(`
+	INSERT INTO players (id, first_name, last_name, full_name, updated_on)
+	VALUES (?, ?, ?, ?, ?)
+	ON CONFLICT(id) DO UPDATE SET
+		first_name = excluded.first_name,
+		last_name = excluded.last_name,
+		full_name = excluded.full_name,
+		updated_on = excluded.updated_on
+	WHERE excluded.updated_on >= players.updated_on`); err != nil {
+		w.Close()
+		return nil, err
+	}
+	if w.name, err = preparePlayerNameStatement(tx); err != nil {
+		w.Close()
+		return nil, err
+	}
+	if w.upsert, err = prepareInsertStatement(tx); err != nil {
+		w.Close()
+		return nil, err
+	}
+	if w.directional, err = tx.Prepare(`
+	INSERT INTO oaa_directional (player_id, in_front, toward_third_base, toward_first_base, behind, date)
+	VALUES (?, ?, ?, ?, ?, ?)
+	ON CONFLICT(player_id, date) DO UPDATE SET
+		in_front = excluded.in_front,
+		toward_third_base = excluded.toward_third_base,
+		toward_first_base = excluded.toward_first_base,
+		behind = excluded.behind`); err != nil {
+		w.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *snapshotWriter) write(row snapshotRow, snapshotDate string) (ingestOutcome, error) {
+	teamID, err := w.teams.resolve(row.team)
+	if err != nil {
+		return 0, err
+	}
+
+	var existing int
+	if err := w.exists.QueryRow(row.playerID, snapshotDate).Scan(&existing); err != nil {
+		return 0, err
+	}
+
+	if _, err := w.player.Exec(row.playerID, row.firstName, row.lastName, row.fullName, snapshotDate); err != nil {
+		return 0, fmt.Errorf("failed to write player: %v", err)
+	}
+	if _, err := w.name.Exec(row.playerID, row.fullName, oaaLeaderboard.table, snapshotDate); err != nil {
+		return 0, fmt.Errorf("failed to record player name: %v", err)
+	}
+
+	result, err := w.upsert.Exec(row.playerID, teamID, row.primaryPosition, row.oaa, row.actualSuccessRate, row.estimatedSuccessRate, row.diffSuccessRate, snapshotDate)
+	if err != nil {
+		return 0, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	if d := row.directional; d != nil {
+		if _, err := w.directional.Exec(row.playerID, d.inFront, d.towardThirdBase, d.towardFirstBase, d.behind, snapshotDate); err != nil {
+			return 0, fmt.Errorf("failed to write directional OAA: %v", err)
+		}
+	}
+
+	switch {
+	case existing == 0:
+		return outcomeInserted, nil
+	case affected > 0:
+		return outcomeUpdated, nil
+	default:
+		return outcomeUnchanged, nil
+	}
+}
+
+// teamResolver resolves leaderboard team names through the team registry and
+// keeps the teams table in step with each club's current era.
+type teamResolver struct {
+	upsert   *sql.Stmt
+	registry *teams.Registry
+	season   int
+	ids      map[string]int
+	synced   map[int]bool
+}
+
+func newTeamResolver(tx *sql.Tx, registry *teams.Registry, season int) (*teamResolver, error) {
+	upsert, err := tx.Prepare(`
+	INSERT INTO teams (id, name, abbreviation) VALUES (?, ?, ?)
+	ON CONFLICT(id) DO UPDATE SET
+		name = excluded.name,
+		abbreviation = excluded.abbreviation`)
+	if err != nil {
+		return nil, err
+	}
+	return &teamResolver{
+		upsert:   upsert,
+		registry: registry,
+		season:   season,
+		ids:      make(map[string]int),
+		synced:   make(map[int]bool),
+	}, nil
+}
+
+// resolve returns the team ID for a leaderboard team name. A blank name is
+// stored as NULL; a name the registry does not know fails the ingest so a new
+// or renamed club is mapped deliberately instead of silently dropped.
+func (r *teamResolver) resolve(name string) (sql.NullInt64, error) {
+	if name == "" {
+		return sql.NullInt64{}, nil
+	}
+	id, ok := r.ids[name]
+	if !ok {
+		team, found := r.registry.Resolve(name, r.season)
+		if !found {
+			return sql.NullInt64{}, fmt.Errorf("unknown team %q: add it to the team registry", name)
+		}
+		id = team.ID
+		r.ids[name] = id
+	}
+
+	if !r.synced[id] {
+		current, _ := r.registry.Current(id)
+		if _, err := r.upsert.Exec(current.ID, current.ShortName, current.Abbreviation); err != nil {
+			return sql.NullInt64{}, fmt.Errorf("failed to write team %s: %v", current.ShortName, err)
+		}
+		r.synced[id] = true
+	}
+	return sql.NullInt64{Int64: int64(id), Valid: true}, nil
+}
+
+func (r *teamResolver) Close() error {
+	return r.upsert.Close()
+}
+
+func (w *snapshotWriter) Close() error {
+	if w.teams != nil {
+		w.teams.Close()
+	}
+	for _, stmt := range []*sql.Stmt{w.exists, w.player, w.name, w.directional} {
+		if stmt != nil {
+			stmt.Close()
+		}
+	}
+	if w.upsert == nil {
+		return nil
+	}
+	return w.upsert.Close()
+}
+
+// preparePlayerNameStatement records the name a leaderboard gave a player,
+// keeping the earliest snapshot date it appeared on. The players table holds
+// only the latest name, so this history is what the doctor checks for
+// conflicting names.
+func preparePlayerNameStatement(tx *sql.Tx) (*sql.Stmt, error) {
+	return tx.Prepare(`
+	INSERT INTO player_names (player_id, full_name, source, first_seen)
+	VALUES (?, ?, ?, ?)
+	ON CONFLICT(player_id, full_name, source) DO UPDATE SET
+		first_seen = excluded.first_seen
+	WHERE excluded.first_seen < player_names.first_seen`)
 }
 
 func prepareInsertStatement(db interface {
 	Prepare(string) (*sql.Stmt, error)
 }) (*sql.Stmt, error) {
 	insertSQL := `
-	INSERT INTO outs_above_average (player_id, first_name, last_name, full_name, team, primary_position, oaa, actual_success_rate, estimated_success_rate, diff_success_rate, date)
-	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_DATE)
+	INSERT INTO outs_above_average (player_id, team_id, primary_position, oaa, actual_success_rate, estimated_success_rate, diff_success_rate, date)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?)
 	ON CONFLICT(player_id, date) DO UPDATE SET
-		first_name = excluded.first_name,
-		last_name = excluded.last_name,
-		full_name = excluded.full_name,
-		team = excluded.team,
+		team_id = excluded.team_id,
 		primary_position = excluded.primary_position,
 		oaa = excluded.oaa,
 		actual_success_rate = excluded.actual_success_rate,
 		estimated_success_rate = excluded.estimated_success_rate,
 		diff_success_rate = excluded.diff_success_rate
+	WHERE team_id IS NOT excluded.team_id
+		OR primary_position IS NOT excluded.primary_position
+		OR oaa IS NOT excluded.oaa
+		OR actual_success_rate IS NOT excluded.actual_success_rate
+		OR estimated_success_rate IS NOT excluded.estimated_success_rate
+		OR diff_success_rate IS NOT excluded.diff_success_rate
 	`
 	return db.Prepare(insertSQL)
 }
@@ -184,79 +521,20 @@ const (
 	csvColumnActualSuccessRate    csvColumn = "actual success rate"
 	csvColumnEstimatedSuccessRate csvColumn = "estimated success rate"
 	csvColumnDiffSuccessRate      csvColumn = "diff success rate"
+	csvColumnOAAInFront           csvColumn = "OAA in front"
+	csvColumnOAATowardThirdBase   csvColumn = "OAA toward 3B line"
+	csvColumnOAATowardFirstBase   csvColumn = "OAA toward 1B line"
+	csvColumnOAABehind            csvColumn = "OAA behind"
 )
 
 type csvColumns map[csvColumn]int
 
-var requiredCSVColumns = map[csvColumn][]string{
-	csvColumnName: {
-		"name",
-		"player",
-		"playername",
-		"last_name, first_name",
-		"fielder",
-		"fieldername",
-		"fielder_name",
-		"entityname",
-	},
-	csvColumnPlayerID: {
-		"playerid",
-		"player_id",
-		"mlbid",
-		"mlb_id",
-	},
-	csvColumnTeam: {
-		"team",
-		"teamname",
-		"team_name",
-		"display_team_name",
-	},
-	csvColumnPrimaryPosition: {
-		"primaryposition",
-		"primary_position",
-		"primarypos",
-		"primary_pos",
-		"primaryposformatted",
-		"primary_pos_formatted",
-		"position",
-		"pos",
-	},
-	csvColumnOAA: {
-		"oaa",
-		"outsaboveaverage",
-		"outs_above_average",
-	},
-	csvColumnActualSuccessRate: {
-		"actualsuccessrate",
-		"actual_success_rate",
-		"actualsuccessrateformatted",
-		"actual_success_rate_formatted",
-	},
-	csvColumnEstimatedSuccessRate: {
-		"estimatedsuccessrate",
-		"estimated_success_rate",
-		"estimatedsuccessrateformatted",
-		"estimated_success_rate_formatted",
-		"adj_estimated_success_rate_formatted",
-	},
-	csvColumnDiffSuccessRate: {
-		"diffsuccessrate",
-		"diff_success_rate",
-		"diffsuccessrateformatted",
-		"diff_success_rate_formatted",
-		"differenceinsuccessrate",
-		"difference_in_success_rate",
-	},
-}
-
-func resolveCSVColumns(header []string) (csvColumns, error) {
-	indexes := make(map[string]int, len(header))
-	for i, name := range header {
-		indexes[normalizeCSVHeader(name)] = i
-	}
-
-	columns := make(csvColumns, len(requiredCSVColumns))
-	for column, aliases := range requiredCSVColumns {
+// resolveCSVColumns finds the index of every required column in header, trying
+// each column's aliases in order.
+func resolveCSVColumns(header []string, required map[csvColumn][]string) (csvColumns, error) {
+	indexes := csvHeaderIndexes(header)
+	columns := make(csvColumns, len(required))
+	for column, aliases := range required {
 		index, ok := findCSVColumn(indexes, aliases)
 		if !ok {
 			return nil, fmt.Errorf("CSV header missing required column %q; got %v", column, header)
@@ -267,6 +545,14 @@ func resolveCSVColumns(header []string) (csvColumns, error) {
 	return columns, nil
 }
 
+func csvHeaderIndexes(header []string) map[string]int {
+	indexes := make(map[string]int, len(header))
+	for i, name := range header {
+		indexes[normalizeCSVHeader(name)] = i
+	}
+	return indexes
+}
+
 func findCSVColumn(indexes map[string]int, aliases []string) (int, bool) {
 	for _, alias := range aliases {
 		if index, ok := indexes[normalizeCSVHeader(alias)]; ok {
@@ -295,15 +581,42 @@ func (columns csvColumns) value(record []string, column csvColumn) string {
 	return record[index]
 }
 
-func processRecord(stmt *sql.Stmt, record []string, columns csvColumns) error {
+// snapshotRow is a single player's parsed leaderboard line.
+type snapshotRow struct {
+	playerID  int
+	firstName string
+	lastName  string
+	fullName  string
+	team      string
+	// teamID is the registry ID of team, or 0 when it is unknown or not
+	// yet resolved.
+	teamID               int
+	primaryPosition      string
+	oaa                  int
+	actualSuccessRate    float64
+	estimatedSuccessRate float64
+	diffSuccessRate      float64
+	// directional is nil when the export has no directional columns.
+	directional *directionalOAA
+}
+
+// directionalOAA splits a player's OAA by the direction of the fielding attempt.
+type directionalOAA struct {
+	inFront         sql.NullInt64
+	towardThirdBase sql.NullInt64
+	towardFirstBase sql.NullInt64
+	behind          sql.NullInt64
+}
+
+func processRecord(record []string, columns csvColumns) (snapshotRow, error) {
 	name := columns.value(record, csvColumnName)
 	if name == "" {
-		return fmt.Errorf("missing %s", csvColumnName)
+		return snapshotRow{}, fmt.Errorf("missing %s", csvColumnName)
 	}
 
 	nameParts := strings.Split(name, ",")
 	if len(nameParts) != 2 {
-		return fmt.Errorf("invalid name format: %v", name)
+		return snapshotRow{}, fmt.Errorf("invalid name format: %v", name)
 	}
 	firstName := strings.TrimSpace(nameParts[1])
 	lastName := strings.TrimSpace(nameParts[0])
@@ -312,7 +625,7 @@ func processRecord(stmt *sql.Stmt, record []string, columns csvColumns) error {
 	playerIDValue := columns.value(record, csvColumnPlayerID)
 	playerID, err := strconv.Atoi(playerIDValue)
 	if err != nil {
-		return fmt.Errorf("invalid player ID: %v", playerIDValue)
+		return snapshotRow{}, fmt.Errorf("invalid player ID: %v", playerIDValue)
 	}
 
 	team := columns.value(record, csvColumnTeam)
@@ -320,7 +633,7 @@ func processRecord(stmt *sql.Stmt, record []string, columns csvColumns) error {
 	oaaValue := columns.value(record, csvColumnOAA)
 	oaa, err := strconv.Atoi(oaaValue)
 	if err != nil {
-		return fmt.Errorf("invalid OAA value: %v", oaaValue)
+		return snapshotRow{}, fmt.Errorf("invalid OAA value: %v", oaaValue)
 	}
 
 	primaryPosition := columns.value(record, csvColumnPrimaryPosition)
@@ -328,23 +641,64 @@ func processRecord(stmt *sql.Stmt, record []string, columns csvColumns) error {
 	actualSuccessRateValue := columns.value(record, csvColumnActualSuccessRate)
 	actualSuccessRate, err := parsePercentage(actualSuccessRateValue)
 	if err != nil {
-		return fmt.Errorf("invalid actual success rate: %v", actualSuccessRateValue)
+		return snapshotRow{}, fmt.Errorf("invalid actual success rate: %v", actualSuccessRateValue)
 	}
 
 	estimatedSuccessRateValue := columns.value(record, csvColumnEstimatedSuccessRate)
 	estimatedSuccessRate, err := parsePercentage(estimatedSuccessRateValue)
 	if err != nil {
-		return fmt.Errorf("invalid estimated success rate: %v", estimatedSuccessRateValue)
+		return snapshotRow{}, fmt.Errorf("invalid estimated success rate: %v", estimatedSuccessRateValue)
 	}
 
 	diffSuccessRateValue := columns.value(record, csvColumnDiffSuccessRate)
 	diffSuccessRate, err := parsePercentage(diffSuccessRateValue)
 	if err != nil {
-		return fmt.Errorf("invalid diff success rate: %v", diffSuccessRateValue)
+		return snapshotRow{}, fmt.Errorf("invalid diff success rate: %v", diffSuccessRateValue)
 	}
 
-	_, err = stmt.Exec(playerID, firstName, lastName, fullName, team, primaryPosition, oaa, actualSuccessRate, estimatedSuccessRate, diffSuccessRate)
-	return err
+	directional, err := processDirectionalOAA(record, columns)
+	if err != nil {
+		return snapshotRow{}, err
+	}
+
+	return snapshotRow{
+		playerID:             playerID,
+		firstName:            firstName,
+		lastName:             lastName,
+		fullName:             fullName,
+		team:                 team,
+		primaryPosition:      primaryPosition,
+		oaa:                  oaa,
+		actualSuccessRate:    actualSuccessRate,
+		estimatedSuccessRate: estimatedSuccessRate,
+		diffSuccessRate:      diffSuccessRate,
+		directional:          directional,
+	}, nil
+}
+
+func processDirectionalOAA(record []string, columns csvColumns) (*directionalOAA, error) {
+	if _, ok := columns[csvColumnOAAInFront]; !ok {
+		return nil, nil
+	}
+
+	var directional directionalOAA
+	for column, target := range map[csvColumn]*sql.NullInt64{
+		csvColumnOAAInFront:         &directional.inFront,
+		csvColumnOAATowardThirdBase: &directional.towardThirdBase,
+		csvColumnOAATowardFirstBase: &directional.towardFirstBase,
+		csvColumnOAABehind:          &directional.behind,
+	} {
+		value := strings.TrimSpace(columns.value(record, column))
+		if value == "" {
+			continue
+		}
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s value: %v", column, value)
+		}
+		*target = sql.NullInt64{Int64: int64(parsed), Valid: true}
+	}
+	return &directional, nil
 }
 
 func parsePercentage(percentageStr string) (float64, error) {
@@ -3,11 +3,21 @@ package refresher
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/benfb/oaamonitor/config"
+	"github.com/benfb/oaamonitor/database"
+	"github.com/benfb/oaamonitor/storage"
+	"github.com/benfb/oaamonitor/teams"
 )
 
 func TestParsePercentage(t *testing.T) {
@@ -73,7 +83,7 @@ func TestProcessCSV_RollsBackOnParseError(t *testing.T) {
 	}
 	tmpFile.Close()
 
-	if err := processCSV(context.Background(), tmpFile.Name(), dbPath); err == nil {
+	if err := processCSV(context.Background(), dbPath, csvSource{path: tmpFile.Name(), snapshotDate: "2026-06-01"}, guardrails{}, teams.Default()); err == nil {
 		t.Fatal("expected processing to fail on parse error")
 	}
 
@@ -110,7 +120,7 @@ func TestProcessCSV_UsesHeaderNamesWhenColumnsAreReordered(t *testing.T) {
 	}
 	tmpFile.Close()
 
-	if err := processCSV(context.Background(), tmpFile.Name(), dbPath); err != nil {
+	if err := processCSV(context.Background(), dbPath, csvSource{path: tmpFile.Name(), snapshotDate: "2026-06-01"}, guardrails{}, teams.Default()); err != nil {
 		t.Fatalf("processCSV returned error: %v", err)
 	}
 
@@ -133,9 +143,11 @@ func TestProcessCSV_UsesHeaderNamesWhenColumnsAreReordered(t *testing.T) {
 		diffSuccessRate      float64
 	)
 	if err := db.QueryRow(`
-		SELECT player_id, first_name, last_name, full_name, team, primary_position, oaa,
-			actual_success_rate, estimated_success_rate, diff_success_rate
-		FROM outs_above_average
+		SELECT o.player_id, p.first_name, p.last_name, p.full_name, t.name, o.primary_position, o.oaa,
+			o.actual_success_rate, o.estimated_success_rate, o.diff_success_rate
+		FROM outs_above_average o
+		JOIN players p ON p.id = o.player_id
+		JOIN teams t ON t.id = o.team_id
 	`).Scan(&playerID, &firstName, &lastName, &fullName, &team, &primaryPosition, &oaa, &actualSuccessRate, &estimatedSuccessRate, &diffSuccessRate); err != nil {
 		t.Fatalf("failed to read inserted row: %v", err)
 	}
@@ -169,7 +181,7 @@ func TestProcessCSV_UsesBaseballSavantHeaders(t *testing.T) {
 	}
 	tmpFile.Close()
 
-	if err := processCSV(context.Background(), tmpFile.Name(), dbPath); err != nil {
+	if err := processCSV(context.Background(), dbPath, csvSource{path: tmpFile.Name(), snapshotDate: "2026-06-01"}, guardrails{}, teams.Default()); err != nil {
 		t.Fatalf("processCSV returned error: %v", err)
 	}
 
@@ -189,9 +201,11 @@ func TestProcessCSV_UsesBaseballSavantHeaders(t *testing.T) {
 		diffSuccessRate      float64
 	)
 	if err := db.QueryRow(`
-		SELECT full_name, team, primary_position, oaa,
-			actual_success_rate, estimated_success_rate, diff_success_rate
-		FROM outs_above_average
+		SELECT p.full_name, t.name, o.primary_position, o.oaa,
+			o.actual_success_rate, o.estimated_success_rate, o.diff_success_rate
+		FROM outs_above_average o
+		JOIN players p ON p.id = o.player_id
+		JOIN teams t ON t.id = o.team_id
 	`).Scan(&fullName, &team, &primaryPosition, &oaa, &actualSuccessRate, &estimatedSuccessRate, &diffSuccessRate); err != nil {
 		t.Fatalf("failed to read inserted row: %v", err)
 	}
@@ -224,7 +238,7 @@ func TestProcessCSV_RollsBackOnReadError(t *testing.T) {
 	}
 	tmpFile.Close()
 
-	if err := processCSV(context.Background(), tmpFile.Name(), dbPath); err == nil {
+	if err := processCSV(context.Background(), dbPath, csvSource{path: tmpFile.Name(), snapshotDate: "2026-06-01"}, guardrails{}, teams.Default()); err == nil {
 		t.Fatalf("expected processing to fail due to malformed row")
 	}
 
@@ -242,3 +256,1074 @@ func TestProcessCSV_RollsBackOnReadError(t *testing.T) {
 		t.Fatalf("expected zero rows after rollback, got %d", count)
 	}
 }
+
+func TestProcessCSV_RecordsExplicitSnapshotDate(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "test.db")
+
+	csvContent := strings.Join([]string{
+		"Name,PlayerID,Team,PrimaryPosition,OAA,ActualSuccessRate,EstimatedSuccessRate,DiffSuccessRate",
+		`"Smith, John",123,Blue Jays,SS,5,90%,80%,10%`,
+	}, "\n")
+
+	tmpFile, err := os.CreateTemp(dir, "data*.csv")
+	if err != nil {
+		t.Fatalf("failed to create temp csv: %v", err)
+	}
+	if _, err := tmpFile.WriteString(csvContent); err != nil {
+		t.Fatalf("failed to write csv: %v", err)
+	}
+	tmpFile.Close()
+
+	if err := processCSV(context.Background(), dbPath, csvSource{path: tmpFile.Name(), snapshotDate: "2019-09-29"}, guardrails{}, teams.Default()); err != nil {
+		t.Fatalf("processCSV returned error: %v", err)
+	}
+
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	defer db.Close()
+
+	var date string
+	if err := db.QueryRow("SELECT strftime('%Y-%m-%d', date) FROM outs_above_average WHERE player_id = 123").Scan(&date); err != nil {
+		t.Fatalf("failed to read inserted row: %v", err)
+	}
+	if date != "2019-09-29" {
+		t.Fatalf("expected snapshot date 2019-09-29, got %q", date)
+	}
+}
+
+func TestSeasonEndDate(t *testing.T) {
+	if got := SeasonEndDate(2020); got != "2020-09-27" {
+		t.Errorf("SeasonEndDate(2020) = %q, want 2020-09-27", got)
+	}
+	if got := SeasonEndDate(2031); got != "2031-09-30" {
+		t.Errorf("SeasonEndDate(2031) = %q, want 2031-09-30", got)
+	}
+}
+
+func TestFindDatedCSVFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		"oaa_2025-06-02.csv",
+		"nested/oaa_2025-06-01.csv",
+		"notes.txt",
+		"undated.csv",
+		"oaa_2025-13-01.csv",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte("x"), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	files, err := findDatedCSVFiles(dir)
+	if err != nil {
+		t.Fatalf("findDatedCSVFiles returned error: %v", err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("expected 2 dated CSV files, got %d: %+v", len(files), files)
+	}
+	if files[0].date != "2025-06-01" || files[1].date != "2025-06-02" {
+		t.Fatalf("expected files ordered by date, got %+v", files)
+	}
+}
+
+func TestResolveSnapshotDate(t *testing.T) {
+	// 10pm EDT on June 1 is already June 2 in UTC.
+	now := time.Date(2025, time.June, 2, 2, 0, 0, 0, time.UTC)
+
+	got, err := resolveSnapshotDate(&config.Config{}, now)
+	if err != nil {
+		t.Fatalf("resolveSnapshotDate returned error: %v", err)
+	}
+	if got != "2025-06-01" {
+		t.Errorf("expected baseball day 2025-06-01, got %q", got)
+	}
+
+	got, err = resolveSnapshotDate(&config.Config{SnapshotDate: "2024-09-30"}, now)
+	if err != nil {
+		t.Fatalf("resolveSnapshotDate returned error: %v", err)
+	}
+	if got != "2024-09-30" {
+		t.Errorf("expected configured date 2024-09-30, got %q", got)
+	}
+
+	if _, err := resolveSnapshotDate(&config.Config{SnapshotDate: "06/01/2025"}, now); err == nil {
+		t.Error("expected an error for a malformed configured date")
+	}
+}
+
+func TestProcessCSV_RecordsIngestRuns(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "test.db")
+	header := "Name,PlayerID,Team,PrimaryPosition,OAA,ActualSuccessRate,EstimatedSuccessRate,DiffSuccessRate"
+
+	writeCSV := func(name string, rows ...string) string {
+		path := filepath.Join(dir, name)
+		content := strings.Join(append([]string{header}, rows...), "\n")
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write csv: %v", err)
+		}
+		return path
+	}
+
+	first := writeCSV("first.csv",
+		`"Smith, John",123,Blue Jays,SS,5,90%,80%,10%`,
+		`"Doe, Jane",456,Red Sox,CF,3,85%,80%,5%`,
+	)
+	second := writeCSV("second.csv",
+		`"Smith, John",123,Blue Jays,SS,6,90%,80%,10%`,
+		`"Doe, Jane",456,Red Sox,CF,3,85%,80%,5%`,
+		`"Roe, Rich",789,Mets,2B,1,85%,80%,5%`,
+	)
+	broken := writeCSV("broken.csv", `"Smith, John",123,Blue Jays,SS,oops,90%,80%,10%`)
+
+	ctx := context.Background()
+	if err := processCSV(ctx, dbPath, csvSource{path: first, origin: "https://example.com/oaa.csv", snapshotDate: "2025-06-01"}, guardrails{}, teams.Default()); err != nil {
+		t.Fatalf("first processCSV returned error: %v", err)
+	}
+	if err := processCSV(ctx, dbPath, csvSource{path: second, snapshotDate: "2025-06-01"}, guardrails{}, teams.Default()); err != nil {
+		t.Fatalf("second processCSV returned error: %v", err)
+	}
+	if err := processCSV(ctx, dbPath, csvSource{path: broken, snapshotDate: "2025-06-01"}, guardrails{}, teams.Default()); err == nil {
+		t.Fatal("expected broken CSV to fail")
+	}
+
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	defer db.Close()
+
+	rows, err := db.Query(`
+		SELECT source, row_count, inserted_count, updated_count, unchanged_count, csv_sha256, COALESCE(error, '')
+		FROM ingest_runs ORDER BY id`)
+	if err != nil {
+		t.Fatalf("failed to query ingest runs: %v", err)
+	}
+	defer rows.Close()
+
+	type run struct {
+		source                                 string
+		rowCount, inserted, updated, unchanged int
+		sha256, errText                        string
+	}
+	var runs []run
+	for rows.Next() {
+		var r run
+		if err := rows.Scan(&r.source, &r.rowCount, &r.inserted, &r.updated, &r.unchanged, &r.sha256, &r.errText); err != nil {
+			t.Fatalf("failed to scan ingest run: %v", err)
+		}
+		runs = append(runs, r)
+	}
+	if len(runs) != 3 {
+		t.Fatalf("expected 3 ingest runs, got %d", len(runs))
+	}
+
+	if runs[0].source != "https://example.com/oaa.csv" || runs[0].rowCount != 2 || runs[0].inserted != 2 || runs[0].errText != "" {
+		t.Errorf("unexpected first run: %+v", runs[0])
+	}
+	if len(runs[0].sha256) != 64 {
+		t.Errorf("expected a hex SHA-256 digest, got %q", runs[0].sha256)
+	}
+	if runs[1].source != second || runs[1].inserted != 1 || runs[1].updated != 1 || runs[1].unchanged != 1 {
+		t.Errorf("unexpected second run: %+v", runs[1])
+	}
+	if runs[2].errText == "" || runs[2].inserted != 0 {
+		t.Errorf("expected failed run to record its error, got %+v", runs[2])
+	}
+}
+
+func TestArchiveKey(t *testing.T) {
+	fetchedAt := time.Date(2025, time.June, 2, 2, 5, 0, 0, time.UTC) // 10:05pm EDT on June 1
+	key, err := archiveKey("2025-06-01", fetchedAt)
+	if err != nil {
+		t.Fatalf("archiveKey returned error: %v", err)
+	}
+	if key != "raw/2025/06/01/2205.csv.gz" {
+		t.Fatalf("unexpected archive key %q", key)
+	}
+}
+
+func TestArchiveCSVAndReingest(t *testing.T) {
+	dir := t.TempDir()
+	csvPath := filepath.Join(dir, "download.csv")
+	csvContent := strings.Join([]string{
+		"Name,PlayerID,Team,PrimaryPosition,OAA,ActualSuccessRate,EstimatedSuccessRate,DiffSuccessRate",
+		`"Smith, John",123,Blue Jays,SS,5,90%,80%,10%`,
+	}, "\n")
+	if err := os.WriteFile(csvPath, []byte(csvContent), 0o644); err != nil {
+		t.Fatalf("failed to write csv: %v", err)
+	}
+
+	cfg := &config.Config{
+		DatabasePath: filepath.Join(dir, "fresh.db"),
+		ArchiveDir:   filepath.Join(dir, "archive"),
+	}
+	fetchedAt := time.Date(2025, time.June, 1, 18, 0, 0, 0, time.UTC)
+	if err := archiveCSV(context.Background(), cfg, csvPath, "2025-06-01", fetchedAt); err != nil {
+		t.Fatalf("archiveCSV returned error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(cfg.ArchiveDir, "raw", "2025", "06", "01", "1400.csv.gz")); err != nil {
+		t.Fatalf("expected archived file: %v", err)
+	}
+
+	if err := ReingestArchive(context.Background(), cfg); err != nil {
+		t.Fatalf("ReingestArchive returned error: %v", err)
+	}
+
+	db, err := sql.Open("sqlite3", cfg.DatabasePath)
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	defer db.Close()
+
+	var oaa int
+	var date string
+	if err := db.QueryRow("SELECT oaa, strftime('%Y-%m-%d', date) FROM outs_above_average WHERE player_id = 123").Scan(&oaa, &date); err != nil {
+		t.Fatalf("failed to read re-ingested row: %v", err)
+	}
+	if oaa != 5 || date != "2025-06-01" {
+		t.Fatalf("unexpected re-ingested row: oaa=%d date=%s", oaa, date)
+	}
+
+	if err := ReingestArchive(context.Background(), cfg); err == nil {
+		t.Fatal("expected re-ingesting into an existing database to fail")
+	}
+}
+
+func TestReingestArchiveFromStorage(t *testing.T) {
+	dir := t.TempDir()
+	header := "Name,PlayerID,Team,PrimaryPosition,OAA,ActualSuccessRate,EstimatedSuccessRate,DiffSuccessRate"
+	good := filepath.Join(dir, "good.csv")
+	if err := os.WriteFile(good, []byte(header+"\n"+`"Smith, John",123,Blue Jays,SS,5,90%,80%,10%`), 0o644); err != nil {
+		t.Fatalf("failed to write csv: %v", err)
+	}
+	empty := filepath.Join(dir, "empty.csv")
+	if err := os.WriteFile(empty, []byte(header), 0o644); err != nil {
+		t.Fatalf("failed to write csv: %v", err)
+	}
+
+	cfg := &config.Config{
+		DatabasePath:     filepath.Join(dir, "fresh.db"),
+		ArchiveToStorage: true,
+		StorageURL:       "file://" + filepath.Join(dir, "storage"),
+	}
+	fetchedAt := time.Date(2025, time.June, 1, 18, 0, 0, 0, time.UTC)
+	if err := archiveCSV(context.Background(), cfg, good, "2025-06-01", fetchedAt); err != nil {
+		t.Fatalf("archiveCSV returned error: %v", err)
+	}
+	// Rejected leaderboards are archived too, and must not stop the rebuild.
+	if err := archiveCSV(context.Background(), cfg, empty, "2025-06-02", fetchedAt.AddDate(0, 0, 1)); err != nil {
+		t.Fatalf("archiveCSV returned error: %v", err)
+	}
+
+	if err := ReingestArchive(context.Background(), cfg); err != nil {
+		t.Fatalf("ReingestArchive returned error: %v", err)
+	}
+
+	db, err := sql.Open("sqlite3", cfg.DatabasePath)
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	defer db.Close()
+
+	var rows int
+	if err := db.QueryRow("SELECT COUNT(*) FROM outs_above_average WHERE player_id = 123").Scan(&rows); err != nil || rows != 1 {
+		t.Fatalf("expected the archived row to be re-ingested, got %d, %v", rows, err)
+	}
+}
+
+func TestUploadToFileStorage(t *testing.T) {
+	dir := t.TempDir()
+	csvPath := filepath.Join(dir, "download.csv")
+	csvContent := strings.Join([]string{
+		"Name,PlayerID,Team,PrimaryPosition,OAA,ActualSuccessRate,EstimatedSuccessRate,DiffSuccessRate",
+		`"Smith, John",123,Blue Jays,SS,5,90%,80%,10%`,
+	}, "\n")
+	if err := os.WriteFile(csvPath, []byte(csvContent), 0o644); err != nil {
+		t.Fatalf("failed to write csv: %v", err)
+	}
+
+	cfg := &config.Config{
+		DatabasePath:     filepath.Join(dir, "oaamonitor.db"),
+		UploadDatabase:   true,
+		ArchiveToStorage: true,
+		StorageURL:       "file://" + filepath.Join(dir, "storage"),
+		Compression:      "gzip",
+	}
+	if err := ImportCSV(context.Background(), cfg, csvPath, "2025-06-01"); err != nil {
+		t.Fatalf("ImportCSV returned error: %v", err)
+	}
+	fetchedAt := time.Date(2025, time.June, 1, 18, 0, 0, 0, time.UTC)
+	if err := archiveCSV(context.Background(), cfg, csvPath, "2025-06-01", fetchedAt); err != nil {
+		t.Fatalf("archiveCSV returned error: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "storage", "raw", "2025", "06", "01", "1400.csv.gz")); err != nil {
+		t.Errorf("expected the archived CSV in storage: %v", err)
+	}
+
+	backend := storage.NewFileBackend(filepath.Join(dir, "storage"))
+	snapshots, err := storage.ListSnapshots(context.Background(), backend)
+	if err != nil || len(snapshots) != 1 || !strings.HasSuffix(snapshots[0].Key, ".db.gz") {
+		t.Fatalf("expected one gzip database snapshot in storage, got %v, %v", snapshots, err)
+	}
+	restored := filepath.Join(dir, "restored.db")
+	if err := storage.DownloadDatabase(context.Background(), backend, restored); err != nil {
+		t.Fatalf("DownloadDatabase returned error: %v", err)
+	}
+	db, err := database.Open(restored)
+	if err != nil {
+		t.Fatalf("failed to open restored database: %v", err)
+	}
+	defer db.Close()
+	var players int
+	if err := db.QueryRow("SELECT COUNT(*) FROM players").Scan(&players); err != nil || players != 1 {
+		t.Errorf("expected one player in the restored database, got %d, %v", players, err)
+	}
+}
+
+func TestDiffSnapshots(t *testing.T) {
+	previous := []snapshotRow{
+		{playerID: 1, fullName: "John Smith", team: "Mets", teamID: 121, primaryPosition: "SS", oaa: 4},
+		{playerID: 2, fullName: "Jane Doe", team: "Red Sox", teamID: 111, primaryPosition: "CF", oaa: 3},
+		{playerID: 3, fullName: "Rich Roe", team: "Cubs", teamID: 112, primaryPosition: "2B", oaa: 1},
+	}
+	incoming := []snapshotRow{
+		{playerID: 1, fullName: "John Smith", team: "Blue Jays", teamID: 141, primaryPosition: "SS", oaa: 6},
+		{playerID: 2, fullName: "Jane Doe", team: "Red Sox", teamID: 111, primaryPosition: "LF", oaa: 3},
+		{playerID: 4, fullName: "Al New", team: "Twins", teamID: 142, primaryPosition: "C", oaa: 0},
+	}
+
+	diff := diffSnapshots(previous, incoming)
+	if len(diff.added) != 1 || diff.added[0].playerID != 4 {
+		t.Errorf("expected player 4 to be new, got %+v", diff.added)
+	}
+	if len(diff.dropped) != 1 || diff.dropped[0].playerID != 3 {
+		t.Errorf("expected player 3 to be dropped, got %+v", diff.dropped)
+	}
+	if len(diff.oaaChanges) != 1 || diff.oaaChanges[0].incoming.playerID != 1 {
+		t.Errorf("expected one OAA change for player 1, got %+v", diff.oaaChanges)
+	}
+	if len(diff.teamChanges) != 1 || diff.teamChanges[0].incoming.team != "Blue Jays" {
+		t.Errorf("expected one team change to Blue Jays, got %+v", diff.teamChanges)
+	}
+	if len(diff.positionChanges) != 1 || diff.positionChanges[0].incoming.primaryPosition != "LF" {
+		t.Errorf("expected one position change to LF, got %+v", diff.positionChanges)
+	}
+	if err := (guardrails{maxRowDropPercent: 50}).check(previous, incoming); err != nil {
+		t.Errorf("expected routine diff to pass, got %v", err)
+	}
+
+	if err := (guardrails{maxRowDropPercent: 50}).check(previous, incoming[:1]); !errors.Is(err, ErrAnomalousSnapshot) {
+		t.Errorf("expected losing two of three players to be anomalous, got %v", err)
+	}
+}
+
+func TestDryRunDoesNotWrite(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "test.db")
+	header := "Name,PlayerID,Team,PrimaryPosition,OAA,ActualSuccessRate,EstimatedSuccessRate,DiffSuccessRate"
+	stored := filepath.Join(dir, "stored.csv")
+	if err := os.WriteFile(stored, []byte(header+"\n"+`"Smith, John",123,Diamondbacks,SS,5,90%,80%,10%`), 0o644); err != nil {
+		t.Fatalf("failed to write csv: %v", err)
+	}
+	if err := processCSV(context.Background(), dbPath, csvSource{path: stored, snapshotDate: "2025-06-01"}, guardrails{}, teams.Default()); err != nil {
+		t.Fatalf("processCSV returned error: %v", err)
+	}
+
+	incoming := filepath.Join(dir, "incoming.csv")
+	if err := os.WriteFile(incoming, []byte(header+"\n"+`"Smith, John",123,Diamondbacks,SS,7,90%,80%,10%`), 0o644); err != nil {
+		t.Fatalf("failed to write csv: %v", err)
+	}
+
+	var out strings.Builder
+	cfg := &config.Config{DatabasePath: dbPath, SnapshotDate: "2025-06-02"}
+	if err := DryRun(context.Background(), cfg, incoming, &out); err != nil {
+		t.Fatalf("DryRun returned error: %v", err)
+	}
+	if !strings.Contains(out.String(), "John Smith (123): 5 -> 7 (+2)") {
+		t.Errorf("expected OAA change in output, got:\n%s", out.String())
+	}
+	// The stored team is the registry's "D-backs", which is the same club.
+	if !strings.Contains(out.String(), "Team changes (0)") {
+		t.Errorf("expected no team change for a differently spelled club, got:\n%s", out.String())
+	}
+
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	defer db.Close()
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM outs_above_average").Scan(&count); err != nil {
+		t.Fatalf("failed to count rows: %v", err)
+	}
+	if count != 1 {
+		t.Fatalf("expected dry run to leave 1 row, got %d", count)
+	}
+}
+
+func TestDryRunUsesFileNameDate(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "test.db")
+	header := "Name,PlayerID,Team,PrimaryPosition,OAA,ActualSuccessRate,EstimatedSuccessRate,DiffSuccessRate"
+	for date, oaa := range map[string]string{"2025-06-01": "5", "2025-06-10": "9"} {
+		stored := filepath.Join(dir, date+".csv")
+		if err := os.WriteFile(stored, []byte(header+"\n"+`"Smith, John",123,Blue Jays,SS,`+oaa+`,90%,80%,10%`), 0o644); err != nil {
+			t.Fatalf("failed to write csv: %v", err)
+		}
+		if err := processCSV(context.Background(), dbPath, csvSource{path: stored, snapshotDate: date}, guardrails{}, teams.Default()); err != nil {
+			t.Fatalf("processCSV returned error: %v", err)
+		}
+	}
+
+	incoming := filepath.Join(dir, "oaa-2025-06-05.csv")
+	if err := os.WriteFile(incoming, []byte(header+"\n"+`"Smith, John",123,Blue Jays,SS,6,90%,80%,10%`), 0o644); err != nil {
+		t.Fatalf("failed to write csv: %v", err)
+	}
+
+	var out strings.Builder
+	if err := DryRun(context.Background(), &config.Config{DatabasePath: dbPath}, incoming, &out); err != nil {
+		t.Fatalf("DryRun returned error: %v", err)
+	}
+	if !strings.Contains(out.String(), "Dry run for 2025-06-05") || !strings.Contains(out.String(), "John Smith (123): 5 -> 6 (+1)") {
+		t.Errorf("expected a diff against the 2025-06-01 snapshot, got:\n%s", out.String())
+	}
+}
+
+func TestGuardrailsCheck(t *testing.T) {
+	previous := []snapshotRow{
+		{playerID: 1, oaa: 10},
+		{playerID: 2, oaa: 5},
+		{playerID: 3, oaa: -3},
+		{playerID: 4, oaa: 0},
+	}
+	checks := guardrails{maxRowDropPercent: 25, maxOAASumDrift: 10}
+
+	tests := []struct {
+		name     string
+		incoming []snapshotRow
+		wantErr  bool
+	}{
+		{"routine refresh", []snapshotRow{{playerID: 1, oaa: 11}, {playerID: 2, oaa: 5}, {playerID: 3, oaa: -3}, {playerID: 4, oaa: 1}}, false},
+		{"empty leaderboard", nil, true},
+		{"duplicate player", []snapshotRow{{playerID: 1, oaa: 10}, {playerID: 1, oaa: 10}, {playerID: 3}, {playerID: 4}}, true},
+		{"row count drop", []snapshotRow{{playerID: 1, oaa: 10}, {playerID: 2, oaa: 2}}, true},
+		{"OAA sum drift", []snapshotRow{{playerID: 1, oaa: 30}, {playerID: 2, oaa: 5}, {playerID: 3, oaa: -3}, {playerID: 4}}, true},
+	}
+	for _, tt := range tests {
+		err := checks.check(previous, tt.incoming)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: check() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+		if err != nil && !errors.Is(err, ErrAnomalousSnapshot) {
+			t.Errorf("%s: expected ErrAnomalousSnapshot, got %v", tt.name, err)
+		}
+	}
+
+	if err := (guardrails{}).check(previous, []snapshotRow{{playerID: 1, oaa: 90}}); err != nil {
+		t.Errorf("expected zero thresholds to disable drop and drift checks, got %v", err)
+	}
+}
+
+func TestProcessCSV_RollsBackTruncatedLeaderboard(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "test.db")
+	header := "Name,PlayerID,Team,PrimaryPosition,OAA,ActualSuccessRate,EstimatedSuccessRate,DiffSuccessRate"
+
+	full := filepath.Join(dir, "full.csv")
+	if err := os.WriteFile(full, []byte(strings.Join([]string{
+		header,
+		`"Smith, John",123,Blue Jays,SS,5,90%,80%,10%`,
+		`"Doe, Jane",456,Red Sox,CF,3,85%,80%,5%`,
+		`"Roe, Rich",789,Mets,2B,1,85%,80%,5%`,
+	}, "\n")), 0o644); err != nil {
+		t.Fatalf("failed to write csv: %v", err)
+	}
+	truncated := filepath.Join(dir, "truncated.csv")
+	if err := os.WriteFile(truncated, []byte(header+"\n"+`"Smith, John",123,Blue Jays,SS,6,90%,80%,10%`), 0o644); err != nil {
+		t.Fatalf("failed to write csv: %v", err)
+	}
+
+	checks := guardrails{maxRowDropPercent: 50}
+	if err := processCSV(context.Background(), dbPath, csvSource{path: full, snapshotDate: "2025-06-01"}, checks, teams.Default()); err != nil {
+		t.Fatalf("processCSV returned error: %v", err)
+	}
+	err := processCSV(context.Background(), dbPath, csvSource{path: truncated, snapshotDate: "2025-06-02"}, checks, teams.Default())
+	if !errors.Is(err, ErrAnomalousSnapshot) {
+		t.Fatalf("expected truncated leaderboard to be rejected, got %v", err)
+	}
+
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	defer db.Close()
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM outs_above_average WHERE date = '2025-06-02'").Scan(&count); err != nil {
+		t.Fatalf("failed to count rows: %v", err)
+	}
+	if count != 0 {
+		t.Fatalf("expected rejected snapshot to be rolled back, got %d rows", count)
+	}
+}
+
+func testDownloader(sleeps *[]time.Duration) *downloader {
+	return &downloader{
+		client:    http.DefaultClient,
+		retries:   3,
+		baseDelay: 10 * time.Millisecond,
+		maxDelay:  time.Minute,
+		sleep: func(_ context.Context, d time.Duration) error {
+			*sleeps = append(*sleeps, d)
+			return nil
+		},
+	}
+}
+
+func TestDownloaderRetriesServerErrors(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		switch attempts {
+		case 1:
+			w.Header().Set("Retry-After", "7")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case 2:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.Header().Set("ETag", `"abc"`)
+			w.Header().Set("Last-Modified", "Sun, 01 Jun 2025 18:00:00 GMT")
+			io.WriteString(w, "player_id\n")
+		}
+	}))
+	defer server.Close()
+
+	var sleeps []time.Duration
+	result, err := testDownloader(&sleeps).fetch(context.Background(), server.URL, cacheValidators{})
+	if err != nil {
+		t.Fatalf("fetch returned error: %v", err)
+	}
+	defer os.Remove(result.path)
+
+	if attempts != 3 {
+		t.Errorf("expected 3 attempts, got %d", attempts)
+	}
+	if len(sleeps) != 2 || sleeps[0] != 7*time.Second {
+		t.Errorf("expected Retry-After to be honored, got sleeps %v", sleeps)
+	}
+	if sleeps[1] < 10*time.Millisecond || sleeps[1] > 20*time.Millisecond {
+		t.Errorf("expected jittered backoff between 10ms and 20ms, got %v", sleeps[1])
+	}
+	if result.validators.etag != `"abc"` || result.validators.lastModified == "" {
+		t.Errorf("expected validators from response, got %+v", result.validators)
+	}
+}
+
+func TestDownloaderCapsRetryAfter(t *testing.T) {
+	d := testDownloader(nil)
+	if delay := d.backoff(0, 24*time.Hour); delay != time.Minute {
+		t.Errorf("expected Retry-After to be capped at a minute, got %v", delay)
+	}
+}
+
+func TestDownloaderDoesNotRetryClientErrors(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	var sleeps []time.Duration
+	if _, err := testDownloader(&sleeps).fetch(context.Background(), server.URL, cacheValidators{}); err == nil {
+		t.Fatal("expected an error for a 404 response")
+	}
+	if attempts != 1 || len(sleeps) != 0 {
+		t.Errorf("expected a single attempt without retries, got %d attempts and sleeps %v", attempts, sleeps)
+	}
+}
+
+func TestDownloaderGivesUpAfterRetries(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	var sleeps []time.Duration
+	if _, err := testDownloader(&sleeps).fetch(context.Background(), server.URL, cacheValidators{}); err == nil {
+		t.Fatal("expected an error after exhausting retries")
+	}
+	if attempts != 4 {
+		t.Errorf("expected 4 attempts, got %d", attempts)
+	}
+}
+
+func TestDownloaderSendsConditionalHeaders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") != `"abc"` || r.Header.Get("If-Modified-Since") == "" {
+			t.Errorf("missing conditional headers: %v", r.Header)
+		}
+		w.WriteHeader(http.StatusNotModified)
+	}))
+	defer server.Close()
+
+	var sleeps []time.Duration
+	previous := cacheValidators{etag: `"abc"`, lastModified: "Sun, 01 Jun 2025 18:00:00 GMT"}
+	result, err := testDownloader(&sleeps).fetch(context.Background(), server.URL, previous)
+	if err != nil {
+		t.Fatalf("fetch returned error: %v", err)
+	}
+	if !result.notModified || result.path != "" {
+		t.Errorf("expected a not-modified result without a file, got %+v", result)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 6, 1, 18, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"", 0, false},
+		{"30", 30 * time.Second, true},
+		{"-1", 0, false},
+		{"Sun, 01 Jun 2025 18:01:00 GMT", time.Minute, true},
+		{"Sun, 01 Jun 2025 17:00:00 GMT", 0, true},
+		{"soon", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseRetryAfter(tt.value, now)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseRetryAfter(%q) = %v, %v; want %v, %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestLastValidatorsMatchesSourceAndDate(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	csvPath := filepath.Join(t.TempDir(), "oaa.csv")
+	content := strings.Join([]string{
+		`"last_name, first_name",player_id,display_team_name,year,primary_pos_formatted,outs_above_average,actual_success_rate_formatted,adj_estimated_success_rate_formatted,diff_success_rate_formatted`,
+		`"Smith, John",123,Blue Jays,2025,SS,5,90%,80%,10%`,
+	}, "\n")
+	if err := os.WriteFile(csvPath, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if v, err := lastValidators(dbPath, "https://example.com/oaa", "2025-06-01"); err != nil || v != (cacheValidators{}) {
+		t.Fatalf("expected no validators for a missing database, got %+v, %v", v, err)
+	}
+	if _, err := os.Stat(dbPath); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("lastValidators should not create the database")
+	}
+
+	src := csvSource{
+		path:         csvPath,
+		origin:       "https://example.com/oaa",
+		snapshotDate: "2025-06-01",
+		validators:   cacheValidators{etag: `"abc"`, lastModified: "Sun, 01 Jun 2025 18:00:00 GMT"},
+	}
+	if err := processCSV(context.Background(), dbPath, src, guardrails{}, teams.Default()); err != nil {
+		t.Fatalf("processCSV returned error: %v", err)
+	}
+
+	got, err := lastValidators(dbPath, src.origin, "2025-06-01")
+	if err != nil {
+		t.Fatalf("lastValidators returned error: %v", err)
+	}
+	if got != src.validators {
+		t.Errorf("expected %+v, got %+v", src.validators, got)
+	}
+
+	got, err = lastValidators(dbPath, src.origin, "2025-06-02")
+	if err != nil {
+		t.Fatalf("lastValidators returned error: %v", err)
+	}
+	if got != (cacheValidators{}) {
+		t.Errorf("expected no validators for a new snapshot date, got %+v", got)
+	}
+}
+
+func TestProcessMetricCSV(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "test.db")
+	board, ok := findMetricLeaderboard("outfield_jump")
+	if !ok {
+		t.Fatal("outfield_jump leaderboard is not defined")
+	}
+
+	csvPath := filepath.Join(dir, "jump.csv")
+	content := strings.Join([]string{
+		`"last_name, first_name",player_id,rel_league_reaction_distance,rel_league_burst_distance,rel_league_routing_distance,rel_league_bootup_distance`,
+		`"Smith, John",123,0.4,1.2,-0.1,1.5`,
+		`"Doe, Jane",456,,0.3,0.2,0.5`,
+	}, "\n")
+	if err := os.WriteFile(csvPath, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	src := csvSource{path: csvPath, origin: csvPath, snapshotDate: "2025-06-01"}
+	for i := 0; i < 2; i++ {
+		if err := processMetricCSV(context.Background(), dbPath, src, board, teams.Default()); err != nil {
+			t.Fatalf("processMetricCSV returned error: %v", err)
+		}
+	}
+
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var name string
+	var jump float64
+	var reaction sql.NullFloat64
+	if err := db.QueryRow(`SELECT p.full_name, j.jump_feet FROM outfield_jump j JOIN players p ON p.id = j.player_id WHERE j.player_id = 123 AND j.date = '2025-06-01'`).Scan(&name, &jump); err != nil {
+		t.Fatalf("failed to read John Smith: %v", err)
+	}
+	if name != "John Smith" || jump != 1.5 {
+		t.Errorf("unexpected row: %s, %v", name, jump)
+	}
+	if err := db.QueryRow(`SELECT reaction_feet FROM outfield_jump WHERE player_id = 456`).Scan(&reaction); err != nil {
+		t.Fatalf("failed to read Jane Doe: %v", err)
+	}
+	if reaction.Valid {
+		t.Errorf("expected a blank cell to be stored as NULL, got %v", reaction.Float64)
+	}
+
+	var inserted, unchanged int
+	if err := db.QueryRow(`SELECT inserted_count, unchanged_count FROM ingest_runs ORDER BY id DESC LIMIT 1`).Scan(&inserted, &unchanged); err != nil {
+		t.Fatalf("failed to read ingest run: %v", err)
+	}
+	if inserted != 0 || unchanged != 2 {
+		t.Errorf("expected the repeat ingest to leave both rows unchanged, got inserted=%d unchanged=%d", inserted, unchanged)
+	}
+}
+
+func TestProcessMetricCSV_ResolvesTeams(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "test.db")
+	board, _ := findMetricLeaderboard("fielding_run_value")
+
+	csvPath := filepath.Join(dir, "frv.csv")
+	content := strings.Join([]string{
+		`name,player_id,team,total_runs,arm_runs,range_runs`,
+		`"Smith, John",123,Diamondbacks,4,1,3`,
+		`"Doe, Jane",456,Expos,1,0,1`,
+	}, "\n")
+	if err := os.WriteFile(csvPath, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	src := csvSource{path: csvPath, origin: csvPath, snapshotDate: "2025-06-01"}
+	if err := processMetricCSV(context.Background(), dbPath, src, board, teams.Default()); err == nil || !strings.Contains(err.Error(), "Expos") {
+		t.Fatalf("expected an unknown team to fail the ingest, got %v", err)
+	}
+
+	content = strings.Join(strings.Split(content, "\n")[:2], "\n")
+	if err := os.WriteFile(csvPath, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := processMetricCSV(context.Background(), dbPath, src, board, teams.Default()); err != nil {
+		t.Fatalf("processMetricCSV returned error: %v", err)
+	}
+
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var teamID int
+	var team, firstName string
+	if err := db.QueryRow(`
+		SELECT f.team_id, t.name, p.first_name
+		FROM fielding_run_value f
+		JOIN teams t ON t.id = f.team_id
+		JOIN players p ON p.id = f.player_id
+		WHERE f.player_id = 123`).Scan(&teamID, &team, &firstName); err != nil {
+		t.Fatalf("failed to read John Smith: %v", err)
+	}
+	if teamID != 109 || team != "D-backs" || firstName != "John" {
+		t.Errorf("unexpected row: team %d (%s), first name %q", teamID, team, firstName)
+	}
+}
+
+func TestProcessMetricCSV_MissingColumn(t *testing.T) {
+	dir := t.TempDir()
+	board, _ := findMetricLeaderboard("arm_strength")
+
+	csvPath := filepath.Join(dir, "arm.csv")
+	if err := os.WriteFile(csvPath, []byte("fielder_name,player_id,arm_overall\n\"Smith, John\",123,88.1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	src := csvSource{path: csvPath, origin: csvPath, snapshotDate: "2025-06-01"}
+	err := processMetricCSV(context.Background(), filepath.Join(dir, "test.db"), src, board, teams.Default())
+	if err == nil || !strings.Contains(err.Error(), "max_arm_strength") {
+		t.Fatalf("expected a missing max_arm_strength column error, got %v", err)
+	}
+}
+
+func TestFindMetricLeaderboards(t *testing.T) {
+	boards, err := findMetricLeaderboards("fielding_run_value, catcher_framing")
+	if err != nil {
+		t.Fatalf("findMetricLeaderboards returned error: %v", err)
+	}
+	if len(boards) != 2 || boards[0].table != "fielding_run_value" || boards[1].table != "catcher_framing" {
+		t.Errorf("unexpected leaderboards: %+v", boards)
+	}
+
+	if boards, err := findMetricLeaderboards("none"); err != nil || len(boards) != 0 {
+		t.Errorf("expected none to select no leaderboards, got %d, %v", len(boards), err)
+	}
+	if _, err := findMetricLeaderboards("spin_rate"); err == nil {
+		t.Error("expected an error for an unknown leaderboard")
+	}
+
+	if got := boards[0].url(2024); !strings.Contains(got, "seasonStart=2024&seasonEnd=2024") {
+		t.Errorf("expected the season in the URL, got %s", got)
+	}
+}
+
+func TestProcessCSV_StoresDirectionalOAA(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "test.db")
+	csvPath := filepath.Join(dir, "oaa.csv")
+	content := strings.Join([]string{
+		`"last_name, first_name",player_id,display_team_name,year,primary_pos_formatted,outs_above_average,outs_above_average_infront,outs_above_average_lateral_toward3bline,outs_above_average_lateral_toward1bline,outs_above_average_behind,actual_success_rate_formatted,adj_estimated_success_rate_formatted,diff_success_rate_formatted`,
+		`"Smith, John",123,Blue Jays,2025,SS,5,1,2,-1,3,90%,80%,10%`,
+	}, "\n")
+	if err := os.WriteFile(csvPath, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	src := csvSource{path: csvPath, origin: csvPath, snapshotDate: "2025-06-01"}
+	if err := processCSV(context.Background(), dbPath, src, guardrails{}, teams.Default()); err != nil {
+		t.Fatalf("processCSV returned error: %v", err)
+	}
+
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var inFront, toward3B, toward1B, behind int
+	if err := db.QueryRow(`SELECT in_front, toward_third_base, toward_first_base, behind FROM oaa_directional WHERE player_id = 123 AND date = '2025-06-01'`).Scan(&inFront, &toward3B, &toward1B, &behind); err != nil {
+		t.Fatalf("failed to read directional OAA: %v", err)
+	}
+	if inFront != 1 || toward3B != 2 || toward1B != -1 || behind != 3 {
+		t.Errorf("unexpected directional OAA: %d %d %d %d", inFront, toward3B, toward1B, behind)
+	}
+}
+
+func TestProcessPositionCSV_ReplacesRowsForDate(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "test.db")
+	header := `"last_name, first_name",player_id,display_team_name,year,primary_pos_formatted,outs_above_average,actual_success_rate_formatted,adj_estimated_success_rate_formatted,diff_success_rate_formatted`
+
+	write := func(name string, lines ...string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(strings.Join(append([]string{header}, lines...), "\n")), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	first := write("ss-1.csv", `"Smith, John",123,Blue Jays,2025,2B,4,90%,80%,10%`, `"Doe, Jane",456,Rays,2025,SS,2,90%,80%,10%`)
+	second := write("ss-2.csv", `"Smith, John",123,Blue Jays,2025,2B,5,90%,80%,10%`)
+
+	for _, path := range []string{first, second} {
+		src := csvSource{path: path, origin: path, snapshotDate: "2025-06-01"}
+		if err := processPositionCSV(context.Background(), dbPath, src, "SS", teams.Default()); err != nil {
+			t.Fatalf("processPositionCSV returned error: %v", err)
+		}
+	}
+
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var count, oaa int
+	if err := db.QueryRow(`SELECT COUNT(*), MAX(oaa) FROM oaa_by_position WHERE position = 'SS' AND date = '2025-06-01'`).Scan(&count, &oaa); err != nil {
+		t.Fatalf("failed to read per-position OAA: %v", err)
+	}
+	if count != 1 || oaa != 5 {
+		t.Errorf("expected only the re-fetched shortstop row with OAA 5, got %d rows, OAA %d", count, oaa)
+	}
+}
+
+func TestPositionLeaderboardURL(t *testing.T) {
+	if got := oaaLeaderboard.positionURL(2025, "6"); !strings.Contains(got, "&pos=6&") || !strings.Contains(got, "startYear=2025") {
+		t.Errorf("unexpected position URL: %s", got)
+	}
+	if got := oaaLeaderboard.url(2025); !strings.Contains(got, "&pos=&") {
+		t.Errorf("expected an unfiltered URL, got %s", got)
+	}
+	if !wantsPositionOAA("arm_strength, oaa_by_position") || wantsPositionOAA("arm_strength") {
+		t.Error("wantsPositionOAA did not match the configured leaderboards")
+	}
+}
+
+func TestProcessCSV_RebuildsTeamStints(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "test.db")
+	header := `"last_name, first_name",player_id,display_team_name,year,primary_pos_formatted,outs_above_average,actual_success_rate_formatted,adj_estimated_success_rate_formatted,diff_success_rate_formatted`
+
+	for i, snapshot := range []struct{ date, team, oaa string }{
+		{"2025-07-01", "Blue Jays", "4"},
+		{"2025-07-31", "Blue Jays", "5"},
+		{"2025-08-01", "Mariners", "7"},
+	} {
+		path := filepath.Join(dir, fmt.Sprintf("oaa-%d.csv", i))
+		content := header + "\n" + `"Smith, John",123,` + snapshot.team + `,2025,SS,` + snapshot.oaa + `,90%,80%,10%`
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		src := csvSource{path: path, origin: path, snapshotDate: snapshot.date}
+		if err := processCSV(context.Background(), dbPath, src, guardrails{}, teams.Default()); err != nil {
+			t.Fatalf("processCSV returned error: %v", err)
+		}
+	}
+
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	rows, err := db.Query(`
+		SELECT t.name, s.start_date, s.end_date, s.oaa_after - s.oaa_before
+		FROM player_team_stints s
+		JOIN teams t ON t.id = s.team_id
+		WHERE s.player_id = 123
+		ORDER BY s.start_date`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	var got []string
+	for rows.Next() {
+		var team string
+		var start, end time.Time
+		var oaa int
+		if err := rows.Scan(&team, &start, &end, &oaa); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, fmt.Sprintf("%s %s..%s %+d", team, start.Format("2006-01-02"), end.Format("2006-01-02"), oaa))
+	}
+	want := []string{"Blue Jays 2025-07-01..2025-07-31 +5", "Mariners 2025-08-01..2025-08-01 +2"}
+	if strings.Join(got, "; ") != strings.Join(want, "; ") {
+		t.Errorf("expected stints %v, got %v", want, got)
+	}
+}
+
+func TestProcessCSV_ResolvesTeamAliases(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "test.db")
+	header := `"last_name, first_name",player_id,display_team_name,year,primary_pos_formatted,outs_above_average,actual_success_rate_formatted,adj_estimated_success_rate_formatted,diff_success_rate_formatted`
+
+	path := filepath.Join(dir, "oaa.csv")
+	if err := os.WriteFile(path, []byte(header+"\n"+`"Smith, John",123,Diamondbacks,2025,SS,4,90%,80%,10%`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := processCSV(context.Background(), dbPath, csvSource{path: path, snapshotDate: "2025-07-01"}, guardrails{}, teams.Default()); err != nil {
+		t.Fatalf("processCSV returned error: %v", err)
+	}
+
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var teamID int
+	if err := db.QueryRow(`SELECT team_id FROM outs_above_average WHERE player_id = 123`).Scan(&teamID); err != nil {
+		t.Fatal(err)
+	}
+	if teamID != 109 {
+		t.Errorf("expected Diamondbacks to resolve to team 109, got %d", teamID)
+	}
+
+	if err := os.WriteFile(path, []byte(header+"\n"+`"Smith, John",123,Expos,2025,SS,5,90%,80%,10%`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	err = processCSV(context.Background(), dbPath, csvSource{path: path, snapshotDate: "2025-07-02"}, guardrails{}, teams.Default())
+	if err == nil || !strings.Contains(err.Error(), `unknown team "Expos"`) {
+		t.Fatalf("expected an unknown team error, got %v", err)
+	}
+}
+
+func TestProcessCSV_RecordsPlayerNameHistory(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "test.db")
+	header := `"last_name, first_name",player_id,display_team_name,year,primary_pos_formatted,outs_above_average,actual_success_rate_formatted,adj_estimated_success_rate_formatted,diff_success_rate_formatted`
+
+	path := filepath.Join(dir, "oaa.csv")
+	for _, snapshot := range []struct{ date, name string }{
+		{"2025-07-02", "Smith, Johnny"},
+		{"2025-07-01", "Smith, John"},
+	} {
+		if err := os.WriteFile(path, []byte(header+"\n"+`"`+snapshot.name+`",123,Yankees,2025,SS,4,90%,80%,10%`), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := processCSV(context.Background(), dbPath, csvSource{path: path, snapshotDate: snapshot.date}, guardrails{}, teams.Default()); err != nil {
+			t.Fatalf("processCSV returned error: %v", err)
+		}
+	}
+
+	board, _ := findMetricLeaderboard("arm_strength")
+	metricPath := filepath.Join(dir, "arm.csv")
+	if err := os.WriteFile(metricPath, []byte("fielder_name,player_id,arm_overall,max_arm_strength\nJ. Smith,123,85.2,91.4"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := processMetricCSV(context.Background(), dbPath, csvSource{path: metricPath, snapshotDate: "2025-07-02"}, board, teams.Default()); err != nil {
+		t.Fatalf("processMetricCSV returned error: %v", err)
+	}
+
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	rows, err := db.Query(`SELECT full_name, source, first_seen FROM player_names WHERE player_id = 123 ORDER BY first_seen, source`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var got []string
+	for rows.Next() {
+		var name, source string
+		var firstSeen time.Time
+		if err := rows.Scan(&name, &source, &firstSeen); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, fmt.Sprintf("%s %s %s", firstSeen.Format("2006-01-02"), source, name))
+	}
+	// The players table keeps the latest name; the history keeps every one.
+	want := []string{
+		"2025-07-01 outs_above_average John Smith",
+		"2025-07-02 arm_strength J. Smith",
+		"2025-07-02 outs_above_average Johnny Smith",
+	}
+	if strings.Join(got, ", ") != strings.Join(want, ", ") {
+		t.Errorf("unexpected name history:\n got %v\nwant %v", got, want)
+	}
+}
@@ -0,0 +1,125 @@
+package refresher
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/benfb/oaamonitor/config"
+	"github.com/benfb/oaamonitor/database"
+	"github.com/benfb/oaamonitor/teams"
+)
+
+// refreshPositionOAA downloads a season's OAA leaderboard filtered to one position
+// and stores each player's OAA at that position as of snapshotDate.
+func refreshPositionOAA(ctx context.Context, cfg *config.Config, season int, snapshotDate, number, position string) (bool, error) {
+	url := oaaLeaderboard.positionURL(season, number)
+	validators, err := lastValidators(cfg.DatabasePath, url, snapshotDate)
+	if err != nil {
+		log.Printf("warning: ignoring cached validators: %v", err)
+		validators = cacheValidators{}
+	}
+
+	result, err := newDownloader(cfg).fetch(ctx, url, validators)
+	if err != nil {
+		return false, fmt.Errorf("failed to download CSV file: %v", err)
+	}
+	if result.notModified {
+		log.Printf("%s OAA leaderboard unchanged since the last fetch for %s; skipping.", position, snapshotDate)
+		return false, nil
+	}
+	defer os.Remove(result.path)
+
+	registry, err := teams.Load(cfg.TeamsFile)
+	if err != nil {
+		return false, err
+	}
+
+	src := csvSource{path: result.path, origin: url, snapshotDate: snapshotDate, validators: result.validators}
+	if err := processPositionCSV(ctx, cfg.DatabasePath, src, position, registry); err != nil {
+		return false, fmt.Errorf("failed to process CSV file: %v", err)
+	}
+	return true, nil
+}
+
+// processPositionCSV replaces the position's rows for the snapshot date with the
+// players on a position-filtered OAA leaderboard, so a player who falls off the
+// leaderboard on a re-fetch does not keep a stale row.
+func processPositionCSV(ctx context.Context, dbPath string, src csvSource, position string, registry *teams.Registry) (err error) {
+	if err := validateSnapshotDate(src.snapshotDate); err != nil {
+		return err
+	}
+
+	db, err := database.Open(dbPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := database.EnsureSchema(db, registry); err != nil {
+		return err
+	}
+
+	run := newIngestRun(src)
+	defer func() {
+		run.finish(err)
+		if recordErr := recordIngestRun(db, run); recordErr != nil {
+			log.Printf("warning: failed to record ingest run: %v", recordErr)
+		}
+	}()
+
+	if run.sha256, err = hashFile(src.path); err != nil {
+		return err
+	}
+
+	rows, err := parseCSV(src.path)
+	if err != nil {
+		return err
+	}
+	run.rowCount = len(rows)
+
+	// Early in the season a position can legitimately have no qualified fielders,
+	// so only duplicate IDs are rejected here.
+	seen := make(map[int]struct{}, len(rows))
+	for _, row := range rows {
+		if _, ok := seen[row.playerID]; ok {
+			return fmt.Errorf("%w: duplicate player ID %d (%s)", ErrAnomalousSnapshot, row.playerID, row.fullName)
+		}
+		seen[row.playerID] = struct{}{}
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			tx.Rollback()
+		}
+	}()
+
+	if _, err := tx.Exec(`DELETE FROM oaa_by_position WHERE position = ? AND date = ?`, position, src.snapshotDate); err != nil {
+		return err
+	}
+
+	insert, err := tx.Prepare(`INSERT INTO oaa_by_position (player_id, position, oaa, date) VALUES (?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer insert.Close()
+
+	for _, row := range rows {
+		if _, err := insert.Exec(row.playerID, position, row.oaa, src.snapshotDate); err != nil {
+			return fmt.Errorf("error writing record %v: %w", row.fullName, err)
+		}
+		run.count(outcomeInserted)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	committed = true
+	return nil
+}
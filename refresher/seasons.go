@@ -0,0 +1,27 @@
+package refresher
+
+import "fmt"
+
+// regularSeasonEndDates lists the final day of each MLB regular season since
+// Statcast began publishing Outs Above Average.
+var regularSeasonEndDates = map[int]string{
+	2016: "2016-10-02",
+	2017: "2017-10-01",
+	2018: "2018-10-01",
+	2019: "2019-09-29",
+	2020: "2020-09-27",
+	2021: "2021-10-03",
+	2022: "2022-10-05",
+	2023: "2023-10-01",
+	2024: "2024-09-30",
+	2025: "2025-09-28",
+}
+
+// SeasonEndDate returns the last day of the given regular season in YYYY-MM-DD
+// format, falling back to September 30 for seasons without a recorded date.
+func SeasonEndDate(season int) string {
+	if date, ok := regularSeasonEndDates[season]; ok {
+		return date
+	}
+	return fmt.Sprintf("%04d-09-30", season)
+}
@@ -0,0 +1,70 @@
+package refresher
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+
+	"github.com/benfb/oaamonitor/models"
+)
+
+// rebuildTeamStints recomputes player_team_stints for the season containing
+// snapshotDate from the stored snapshots. When the table is empty, as it is right
+// after it was introduced, every season is rebuilt instead.
+func rebuildTeamStints(tx *sql.Tx, snapshotDate string) error {
+	var stored int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM player_team_stints`).Scan(&stored); err != nil {
+		return err
+	}
+
+	seasonStart, nextSeasonStart := "0000-01-01", "9999-12-31"
+	if stored > 0 {
+		season, _ := strconv.Atoi(snapshotDate[:4])
+		seasonStart = fmt.Sprintf("%04d-01-01", season)
+		nextSeasonStart = fmt.Sprintf("%04d-01-01", season+1)
+	}
+
+	rows, err := tx.Query(`
+		SELECT player_id, COALESCE(team_id, 0), oaa, date
+		FROM oaa_snapshots
+		WHERE date >= ? AND date < ?`, seasonStart, nextSeasonStart)
+	if err != nil {
+		return err
+	}
+	var stats []models.Stat
+	for rows.Next() {
+		var stat models.Stat
+		if err := rows.Scan(&stat.PlayerID, &stat.TeamID, &stat.OAA, &stat.Date); err != nil {
+			rows.Close()
+			return err
+		}
+		stats = append(stats, stat)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM player_team_stints WHERE start_date >= ? AND start_date < ?`, seasonStart, nextSeasonStart); err != nil {
+		return err
+	}
+
+	insert, err := tx.Prepare(`
+		INSERT INTO player_team_stints (player_id, team_id, season, start_date, end_date, oaa_before, oaa_after)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer insert.Close()
+
+	for _, stint := range models.BuildTeamStints(stats) {
+		if stint.TeamID == 0 {
+			// Snapshots without a team break a stint but credit no club.
+			continue
+		}
+		if _, err := insert.Exec(stint.PlayerID, stint.TeamID, stint.Season, stint.StartDate.Format("2006-01-02"), stint.EndDate.Format("2006-01-02"), stint.OAABefore, stint.OAAAfter); err != nil {
+			return fmt.Errorf("failed to write team stint for player %d: %v", stint.PlayerID, err)
+		}
+	}
+	return nil
+}
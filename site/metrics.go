@@ -0,0 +1,93 @@
+package site
+
+import "github.com/benfb/oaamonitor/models"
+
+// PlayerMetrics holds one player's season of the OAA breakdowns and fielding
+// leaderboards charted next to OAA.
+type PlayerMetrics struct {
+	OAAByPosition    []models.OAAByPosition    `json:"oaa_by_position"`
+	OAADirectional   []models.OAADirectional   `json:"oaa_directional"`
+	FieldingRunValue []models.FieldingRunValue `json:"fielding_run_value"`
+	ArmStrength      []models.ArmStrength      `json:"arm_strength"`
+	CatcherFraming   []models.CatcherFraming   `json:"catcher_framing"`
+	OutfieldJump     []models.OutfieldJump     `json:"outfield_jump"`
+}
+
+// loadMetrics indexes every metric leaderboard by player ID and season.
+func (b *Builder) loadMetrics() (map[int]map[int]*PlayerMetrics, error) {
+	if b.metrics != nil {
+		return b.metrics, nil
+	}
+
+	index := make(map[int]map[int]*PlayerMetrics)
+	get := func(playerID, season int) *PlayerMetrics {
+		seasons, ok := index[playerID]
+		if !ok {
+			seasons = make(map[int]*PlayerMetrics)
+			index[playerID] = seasons
+		}
+		metrics, ok := seasons[season]
+		if !ok {
+			metrics = &PlayerMetrics{}
+			seasons[season] = metrics
+		}
+		return metrics
+	}
+
+	byPosition, err := models.FetchOAAByPosition(b.db.DB)
+	if err != nil {
+		return nil, err
+	}
+	for _, value := range byPosition {
+		metrics := get(value.PlayerID, value.Date.Year())
+		metrics.OAAByPosition = append(metrics.OAAByPosition, value)
+	}
+
+	directional, err := models.FetchOAADirectional(b.db.DB)
+	if err != nil {
+		return nil, err
+	}
+	for _, value := range directional {
+		metrics := get(value.PlayerID, value.Date.Year())
+		metrics.OAADirectional = append(metrics.OAADirectional, value)
+	}
+
+	fieldingRunValues, err := models.FetchFieldingRunValues(b.db.DB)
+	if err != nil {
+		return nil, err
+	}
+	for _, value := range fieldingRunValues {
+		metrics := get(value.PlayerID, value.Date.Year())
+		metrics.FieldingRunValue = append(metrics.FieldingRunValue, value)
+	}
+
+	armStrengths, err := models.FetchArmStrengths(b.db.DB)
+	if err != nil {
+		return nil, err
+	}
+	for _, value := range armStrengths {
+		metrics := get(value.PlayerID, value.Date.Year())
+		metrics.ArmStrength = append(metrics.ArmStrength, value)
+	}
+
+	catcherFraming, err := models.FetchCatcherFraming(b.db.DB)
+	if err != nil {
+		return nil, err
+	}
+	for _, value := range catcherFraming {
+		metrics := get(value.PlayerID, value.Date.Year())
+		metrics.CatcherFraming = append(metrics.CatcherFraming, value)
+	}
+
+	outfieldJumps, err := models.FetchOutfieldJumps(b.db.DB)
+	if err != nil {
+		return nil, err
+	}
+	for _, value := range outfieldJumps {
+		metrics := get(value.PlayerID, value.Date.Year())
+		metrics.OutfieldJump = append(metrics.OutfieldJump, value)
+	}
+
+	b.metrics = index
+	return index, nil
+}
@@ -2,22 +2,34 @@ package site
 
 import (
 	"fmt"
+	"path/filepath"
 	"sort"
 	"strconv"
-	"strings"
 	"time"
 
 	"github.com/benfb/oaamonitor/config"
 	"github.com/benfb/oaamonitor/database"
 	"github.com/benfb/oaamonitor/models"
 	"github.com/benfb/oaamonitor/renderer"
+	"github.com/benfb/oaamonitor/teams"
 )
 
 // Team represents metadata needed to render navigation links and locate data.
 type Team struct {
-	Name       string
-	Slug       string
-	normalized string
+	ID           int
+	Name         string
+	Abbreviation string
+	Slug         string
+}
+
+// TeamEra is the registry entry a team page shows for one season.
+type TeamEra struct {
+	Name         string `json:"name"`
+	ShortName    string `json:"short_name"`
+	Abbreviation string `json:"abbreviation"`
+	League       string `json:"league"`
+	Division     string `json:"division"`
+	PrimaryColor string `json:"primary_color"`
 }
 
 // Builder prepares view data and renders templates for the static site.
@@ -25,15 +37,18 @@ type Builder struct {
 	db          *database.DB
 	cfg         *config.Config
 	renderer    *renderer.Renderer
+	registry    *teams.Registry
 	teams       []Team
 	teamsLoaded bool
 	stats       *statIndex
+	metrics     map[int]map[int]*PlayerMetrics
 }
 
 type statIndex struct {
-	playerStats map[int][]models.Stat
-	teamStats   map[string][]models.Stat
-	seasons     []int
+	playerStats  map[int][]models.Stat
+	teamStats    map[int][]models.Stat
+	playerStints map[int][]models.TeamStint
+	seasons      []int
 }
 
 // NewBuilder constructs a Builder backed by the provided database and config.
@@ -43,10 +58,16 @@ func NewBuilder(db *database.DB, cfg *config.Config) (*Builder, error) {
 		return nil, err
 	}
 
+	registry, err := teams.Load(cfg.TeamsFile)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Builder{
 		db:       db,
 		cfg:      cfg,
 		renderer: r,
+		registry: registry,
 	}, nil
 }
 
@@ -55,30 +76,26 @@ func (b *Builder) loadTeams() ([]Team, error) {
 		return b.teams, nil
 	}
 
-	names, err := models.FetchTeams(b.db.DB)
+	rows, err := models.FetchTeams(b.db.DB)
 	if err != nil {
 		return nil, err
 	}
 
-	teams := make([]Team, 0, len(names))
-	seen := make(map[string]struct{})
-	for _, name := range names {
-		lower := strings.ToLower(name)
-		normalized := NormalizeTeamName(lower)
-		if _, ok := seen[normalized]; ok {
-			continue
+	result := make([]Team, 0, len(rows))
+	for _, row := range rows {
+		team := Team{ID: row.ID, Name: row.Name, Abbreviation: row.Abbreviation, Slug: strconv.Itoa(row.ID)}
+		if current, ok := b.registry.Current(row.ID); ok {
+			team.Name = current.ShortName
+			team.Abbreviation = current.Abbreviation
+			team.Slug = b.registry.Slug(row.ID)
 		}
-		seen[normalized] = struct{}{}
-		teams = append(teams, Team{
-			Name:       name,
-			Slug:       slugifyTeamName(normalized),
-			normalized: normalized,
-		})
+		result = append(result, team)
 	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
 
-	b.teams = teams
+	b.teams = result
 	b.teamsLoaded = true
-	return teams, nil
+	return result, nil
 }
 
 func (b *Builder) loadStats() (*statIndex, error) {
@@ -91,17 +108,27 @@ func (b *Builder) loadStats() (*statIndex, error) {
 		return nil, err
 	}
 
+	stints, err := models.FetchTeamStints(b.db.DB)
+	if err != nil {
+		return nil, err
+	}
+
 	index := &statIndex{
-		playerStats: make(map[int][]models.Stat),
-		teamStats:   make(map[string][]models.Stat),
+		playerStats:  make(map[int][]models.Stat),
+		teamStats:    make(map[int][]models.Stat),
+		playerStints: make(map[int][]models.TeamStint),
+	}
+	for _, stint := range stints {
+		index.playerStints[stint.PlayerID] = append(index.playerStints[stint.PlayerID], stint)
 	}
 	seasons := make(map[int]struct{})
 
 	for _, stat := range stats {
 		index.playerStats[stat.PlayerID] = append(index.playerStats[stat.PlayerID], stat)
 
-		normalizedTeam := NormalizeTeamName(strings.ToLower(stat.Team))
-		index.teamStats[normalizedTeam] = append(index.teamStats[normalizedTeam], stat)
+		if stat.TeamID != 0 {
+			index.teamStats[stat.TeamID] = append(index.teamStats[stat.TeamID], stat)
+		}
 
 		seasons[stat.Date.Year()] = struct{}{}
 	}
@@ -157,16 +184,6 @@ func latestPlayerName(stats []models.Stat) string {
 	return name
 }
 
-func teamName(stats []models.Stat) string {
-	name := ""
-	for _, stat := range stats {
-		if stat.Team != "" {
-			name = stat.Team
-		}
-	}
-	return name
-}
-
 func normalizeTeamSeasonPositions(stats []models.Stat) []models.Stat {
 	type latestPosition struct {
 		position string
@@ -205,6 +222,46 @@ func normalizeTeamSeasonPositions(stats []models.Stat) []models.Stat {
 	return result
 }
 
+// applyTeamStints credits each player with the OAA accrued during their stints
+// with the team and flags players who were traded away later in the season.
+// Current players are listed first; traded-away players follow.
+func applyTeamStints(players []models.PlayerStats, stints map[int][]models.TeamStint, teamID int, season int) []models.PlayerStats {
+	for i := range players {
+		player := &players[i]
+		var seasonStints []models.TeamStint
+		for _, stint := range stints[player.PlayerID] {
+			if stint.Season == season {
+				seasonStints = append(seasonStints, stint)
+			}
+		}
+		if len(seasonStints) == 0 {
+			continue
+		}
+
+		teamOAA := 0
+		for _, stint := range seasonStints {
+			if stint.TeamID == teamID {
+				teamOAA += stint.OAA()
+			}
+		}
+		player.TeamOAA = teamOAA
+
+		last := seasonStints[len(seasonStints)-1]
+		if last.TeamID != teamID {
+			player.TradedTo = last.Team
+		}
+	}
+
+	sort.SliceStable(players, func(i, j int) bool {
+		iTraded, jTraded := players[i].TradedTo != "", players[j].TradedTo != ""
+		if iTraded != jTraded {
+			return !iTraded
+		}
+		return players[i].TeamOAA > players[j].TeamOAA
+	})
+	return players
+}
+
 // Teams returns a copy of the cached teams list for navigation and routing.
 func (b *Builder) Teams() ([]Team, error) {
 	teams, err := b.loadTeams()
@@ -216,8 +273,9 @@ func (b *Builder) Teams() ([]Team, error) {
 	return result, nil
 }
 
-// RenderIndex builds the home page HTML.
-func (b *Builder) RenderIndex(players []models.Player) (string, error) {
+// RenderIndex builds the home page HTML. downloadDir holds the published
+// database files whose sizes the page shows.
+func (b *Builder) RenderIndex(players []models.Player, downloadDir string) (string, error) {
 	teams, err := b.loadTeams()
 	if err != nil {
 		return "", err
@@ -238,16 +296,25 @@ func (b *Builder) RenderIndex(players []models.Player) (string, error) {
 		return "", err
 	}
 
-	dbSize, err := database.GetDatabaseSize(b.cfg.DatabasePath)
+	dbSize, err := database.GetDatabaseSize(filepath.Join(downloadDir, "oaamonitor.db"))
 	if err != nil {
 		dbSize = "Unknown"
 	}
+	compressedDBSize, err := database.GetDatabaseSize(filepath.Join(downloadDir, "oaamonitor.db.gz"))
+	if err != nil {
+		compressedDBSize = "Unknown"
+	}
 
 	latestSnapshotDate, err := models.FetchLatestSnapshotDate(b.db.DB)
 	if err != nil {
 		return "", err
 	}
 
+	lastIngestRun, err := models.FetchLastSuccessfulIngestRun(b.db.DB)
+	if err != nil {
+		return "", err
+	}
+
 	data := struct {
 		Title              string
 		Players            []models.Player
@@ -256,7 +323,9 @@ func (b *Builder) RenderIndex(players []models.Player) (string, error) {
 		SevenDayTrends     []models.PlayerDifference
 		ThirtyDayTrends    []models.PlayerDifference
 		DatabaseSize       string
+		CompressedDBSize   string
 		LatestSnapshotDate string
+		LastIngestRun      *models.IngestRun
 	}{
 		Title:              "Outs Above Average Monitor",
 		Players:            players,
@@ -265,7 +334,9 @@ func (b *Builder) RenderIndex(players []models.Player) (string, error) {
 		SevenDayTrends:     sevenDayTrends,
 		ThirtyDayTrends:    thirtyDayTrends,
 		DatabaseSize:       dbSize,
+		CompressedDBSize:   compressedDBSize,
 		LatestSnapshotDate: latestSnapshotDate,
+		LastIngestRun:      lastIngestRun,
 	}
 
 	return b.renderer.RenderToString("index.html", data)
@@ -315,6 +386,11 @@ func (b *Builder) RenderPlayer(playerID int) (string, error) {
 		return "", err
 	}
 
+	metrics, err := b.loadMetrics()
+	if err != nil {
+		return "", err
+	}
+
 	selectedStats := playerStatsBySeason[selectedSeason]
 	selectedPosition := playerPositions[selectedSeason]
 	if selectedPosition == "" {
@@ -334,6 +410,7 @@ func (b *Builder) RenderPlayer(playerID int) (string, error) {
 		PlayerStats         []models.Stat
 		PlayerStatsBySeason map[int][]models.Stat
 		PlayerPositions     map[int]string
+		PlayerMetrics       map[int]*PlayerMetrics
 		Teams               []Team
 		Seasons             []int
 		SelectedSeason      int
@@ -345,6 +422,7 @@ func (b *Builder) RenderPlayer(playerID int) (string, error) {
 		PlayerStats:         selectedStats,
 		PlayerStatsBySeason: playerStatsBySeason,
 		PlayerPositions:     playerPositions,
+		PlayerMetrics:       metrics[playerID],
 		Teams:               teams,
 		Seasons:             playerSeasons,
 		SelectedSeason:      selectedSeason,
@@ -360,7 +438,7 @@ func (b *Builder) RenderTeam(team Team) (string, error) {
 		return "", err
 	}
 
-	teamStats := index.teamStats[team.normalized]
+	teamStats := index.teamStats[team.ID]
 	teamSeasons := seasonsForStats(teamStats)
 	if len(teamSeasons) == 0 {
 		teamSeasons = index.seasons
@@ -374,17 +452,13 @@ func (b *Builder) RenderTeam(team Team) (string, error) {
 
 	teamStatsBySeason := groupStatsBySeason(teamStats)
 	sparklinesBySeason := make(map[int][]models.PlayerStats)
-	var capitalizedTeamName string
+	erasBySeason := make(map[int]TeamEra)
 
 	for _, season := range teamSeasons {
 		stats := normalizeTeamSeasonPositions(teamStatsBySeason[season])
-		name := teamName(stats)
 		teamStatsBySeason[season] = stats
-		sparklinesBySeason[season] = models.MapStatsByPlayerID(stats)
-
-		if capitalizedTeamName == "" && name != "" {
-			capitalizedTeamName = name
-		}
+		sparklinesBySeason[season] = applyTeamStints(models.MapStatsByPlayerID(stats), index.playerStints, team.ID, season)
+		erasBySeason[season] = b.teamEra(team, season)
 	}
 
 	if _, ok := teamStatsBySeason[selectedSeason]; !ok {
@@ -399,19 +473,14 @@ func (b *Builder) RenderTeam(team Team) (string, error) {
 		return "", err
 	}
 
-	if capitalizedTeamName == "" {
-		capitalizedTeamName = team.Name
-	}
-	if capitalizedTeamName == "" {
-		capitalizedTeamName = strings.TrimSpace(team.normalized)
-	}
-
+	era := erasBySeason[selectedSeason]
 	selectedTeamStats := teamStatsBySeason[selectedSeason]
 	selectedSparklines := sparklinesBySeason[selectedSeason]
 
 	data := struct {
 		Title              string
 		TeamName           string
+		TeamEra            TeamEra
 		TeamStats          []models.Stat
 		Teams              []Team
 		SparklinesData     []models.PlayerStats
@@ -421,50 +490,39 @@ func (b *Builder) RenderTeam(team Team) (string, error) {
 		SelectedSeason     int
 		TeamStatsBySeason  map[int][]models.Stat
 		SparklinesBySeason map[int][]models.PlayerStats
+		TeamErasBySeason   map[int]TeamEra
 	}{
-		Title:              fmt.Sprintf("%s Outs Above Average", capitalizedTeamName),
-		TeamName:           capitalizedTeamName,
+		Title:              fmt.Sprintf("%s Outs Above Average", era.Name),
+		TeamName:           era.Name,
+		TeamEra:            era,
 		TeamStats:          selectedTeamStats,
 		Teams:              teams,
 		SparklinesData:     selectedSparklines,
 		CurrentYear:        strconv.Itoa(selectedSeason),
-		TeamAbbreviation:   models.GetTeamAbbreviation(capitalizedTeamName),
+		TeamAbbreviation:   era.Abbreviation,
 		Seasons:            teamSeasons,
 		SelectedSeason:     selectedSeason,
 		TeamStatsBySeason:  teamStatsBySeason,
 		SparklinesBySeason: sparklinesBySeason,
+		TeamErasBySeason:   erasBySeason,
 	}
 
 	return b.renderer.RenderToString("team.html", data)
 }
 
-// NormalizeTeamName normalizes team names to a standard format used in queries.
-func NormalizeTeamName(teamName string) string {
-	switch teamName {
-	case "diamondbacks", "dbacks":
-		return "d-backs"
-	case "blue+jays", "red+sox", "white+sox":
-		return strings.ReplaceAll(teamName, "+", " ")
-	case "bluejays":
-		return "blue jays"
-	case "redsox":
-		return "red sox"
-	case "whitesox":
-		return "white sox"
-	default:
-		return teamName
+// teamEra returns the registry entry for a team in season, or the stored name
+// and abbreviation for a team the registry does not know.
+func (b *Builder) teamEra(team Team, season int) TeamEra {
+	entry, ok := b.registry.Team(team.ID, season)
+	if !ok {
+		return TeamEra{Name: team.Name, ShortName: team.Name, Abbreviation: team.Abbreviation}
+	}
+	return TeamEra{
+		Name:         entry.Name,
+		ShortName:    entry.ShortName,
+		Abbreviation: entry.Abbreviation,
+		League:       entry.League,
+		Division:     entry.Division,
+		PrimaryColor: entry.Colors.Primary,
 	}
 }
-
-func slugifyTeamName(name string) string {
-	slug := strings.ToLower(name)
-	slug = strings.ReplaceAll(slug, "'", "")
-	slug = strings.ReplaceAll(slug, ".", "")
-	slug = strings.ReplaceAll(slug, "&", "and")
-	slug = strings.ReplaceAll(slug, "+", "-")
-	slug = strings.ReplaceAll(slug, " ", "-")
-	for strings.Contains(slug, "--") {
-		slug = strings.ReplaceAll(slug, "--", "-")
-	}
-	return strings.Trim(slug, "-")
-}
@@ -72,6 +72,183 @@ function createChart(ctx, playerName, position, stats) {
   });
 }
 
+const positionColors = {
+  "1B": "255, 99, 132",
+  "2B": "255, 159, 64",
+  "3B": "255, 205, 86",
+  SS: "75, 192, 192",
+  LF: "54, 162, 235",
+  CF: "153, 102, 255",
+  RF: "201, 203, 207",
+};
+
+const metricDefinitions = {
+  oaa_by_position: {
+    title: "OAA by Position",
+    stacked: true,
+    groupBy: "position",
+    valueField: "oaa",
+  },
+  oaa_directional: {
+    title: "OAA by Direction",
+    stacked: true,
+    series: [
+      { field: "in_front", label: "In", color: "75, 192, 192" },
+      { field: "behind", label: "Back", color: "153, 102, 255" },
+      { field: "toward_third_base", label: "Toward 3B", color: "255, 159, 64" },
+      { field: "toward_first_base", label: "Toward 1B", color: "54, 162, 235" },
+    ],
+  },
+  fielding_run_value: {
+    title: "Fielding Run Value",
+    series: [
+      { field: "total_runs", label: "Total", color: "75, 192, 192" },
+      { field: "arm_runs", label: "Arm", color: "255, 159, 64" },
+      { field: "range_runs", label: "Range", color: "153, 102, 255" },
+    ],
+  },
+  arm_strength: {
+    title: "Arm Strength (mph)",
+    series: [
+      { field: "arm_overall", label: "Average", color: "75, 192, 192" },
+      { field: "max_arm_strength", label: "Max", color: "255, 99, 132" },
+    ],
+  },
+  catcher_framing: {
+    title: "Catcher Framing Runs",
+    series: [
+      { field: "framing_runs", label: "Runs", color: "75, 192, 192" },
+    ],
+  },
+  outfield_jump: {
+    title: "Outfield Jump (ft vs. avg)",
+    series: [
+      { field: "jump_feet", label: "Jump", color: "75, 192, 192" },
+      { field: "reaction_feet", label: "Reaction", color: "255, 159, 64" },
+      { field: "burst_feet", label: "Burst", color: "153, 102, 255" },
+      { field: "route_feet", label: "Route", color: "255, 99, 132" },
+    ],
+  },
+};
+
+function metricDataset(label, color, stacked) {
+  return {
+    label,
+    data: [],
+    backgroundColor: `rgba(${color}, ${stacked ? 0.8 : 0.5})`,
+    borderColor: `rgba(${color}, 1)`,
+    borderWidth: 1,
+    fill: false,
+    spanGaps: true,
+    pointRadius: 4,
+    pointHoverRadius: 6,
+  };
+}
+
+// groupedDatasets turns rows like {date, position, oaa} into one stacked dataset
+// per position, aligned to the sorted list of dates.
+function groupedDatasets(rows, definition) {
+  const labels = [...new Set(rows.map((row) => row.date))].sort();
+  const groups = [...new Set(rows.map((row) => row[definition.groupBy]))].sort();
+  const datasets = groups.map((group) => {
+    const values = new Map(
+      rows
+        .filter((row) => row[definition.groupBy] === group)
+        .map((row) => [row.date, row[definition.valueField]]),
+    );
+    const dataset = metricDataset(
+      group,
+      positionColors[group] || "201, 203, 207",
+      true,
+    );
+    dataset.data = labels.map((label) => values.get(label) ?? null);
+    return dataset;
+  });
+  return { labels, datasets };
+}
+
+function createMetricChart(ctx, definition) {
+  const stacked = Boolean(definition.stacked);
+  return new Chart(ctx, {
+    type: stacked ? "bar" : "line",
+    data: {
+      labels: [],
+      datasets: (definition.series || []).map((series) =>
+        metricDataset(series.label, series.color, stacked),
+      ),
+    },
+    options: {
+      scales: {
+        x: {
+          type: "time",
+          stacked,
+          time: {
+            unit: "day",
+            tooltipFormat: "MMM d, yyyy",
+          },
+          grid: {
+            color: gridColor,
+          },
+        },
+        y: {
+          stacked,
+          grid: {
+            color: gridColor,
+          },
+        },
+      },
+      plugins: {
+        customCanvasBackgroundColor: {
+          color: chartBackgroundColor,
+        },
+        title: {
+          display: true,
+          text: definition.title,
+          font: {
+            size: 16,
+          },
+        },
+      },
+    },
+    plugins: [backgroundPlugin],
+  });
+}
+
+function setupMetricCharts(metricsBySeason) {
+  const charts = [];
+  document.querySelectorAll(".metric-chart").forEach((container) => {
+    const key = container.dataset.metric;
+    const definition = metricDefinitions[key];
+    if (!definition) return;
+    const ctx = container.querySelector("canvas").getContext("2d");
+    charts.push({
+      key,
+      definition,
+      container,
+      chart: createMetricChart(ctx, definition),
+    });
+  });
+
+  return (season) => {
+    const metrics = metricsBySeason?.[String(season)] || {};
+    charts.forEach(({ key, definition, container, chart }) => {
+      const rows = metrics[key] || [];
+      container.hidden = rows.length === 0;
+      if (definition.groupBy) {
+        const { labels, datasets } = groupedDatasets(rows, definition);
+        chart.data.labels = labels;
+        chart.data.datasets = datasets;
+      } else {
+        chart.data.labels = rows.map((row) => row.date);
+        definition.series.forEach((series, i) => {
+          chart.data.datasets[i].data = rows.map((row) => row[series.field]);
+        });
+      }
+      chart.update();
+    });
+  };
+}
+
 document.addEventListener("DOMContentLoaded", () => {
   const data = window.playerPageData || {};
   const seasons = Array.isArray(data.seasons) ? data.seasons : [];
@@ -156,8 +333,10 @@ document.addEventListener("DOMContentLoaded", () => {
     getPositionForSeason(currentSeason),
     getStatsForSeason(currentSeason),
   );
+  const updateMetricCharts = setupMetricCharts(data.playerMetricsBySeason);
   updateSavantLink(currentSeason);
   updateHeroMeta(currentSeason);
+  updateMetricCharts(currentSeason);
 
   const updateSeason = (season, pushState = true) => {
     currentSeason = Number(season);
@@ -174,6 +353,7 @@ document.addEventListener("DOMContentLoaded", () => {
 
     updateSavantLink(currentSeason);
     updateHeroMeta(currentSeason);
+    updateMetricCharts(currentSeason);
 
     if (pushState) {
       const params = new URLSearchParams(window.location.search);
@@ -39,10 +39,39 @@ function loadSearchIndex() {
     return searchIndexPromise;
 }
 
+// foldText lowercases and strips accents, so "jose" matches "José".
+function foldText(value) {
+    return value.normalize('NFD').replace(/[\u0300-\u036f]/g, '').toLowerCase();
+}
+
+function searchWords(value) {
+    return foldText(value).split(/[^\p{L}\p{N}]+/u).filter((word) => word.length > 0);
+}
+
+// matchesQuery requires every query word to start a word of the player's name
+// or one of its first-name variants, mirroring the database search.
+function matchesQuery(player, queryWords) {
+    if (!player.searchWords) {
+        const aliases = Array.isArray(player.aliases) ? player.aliases : [];
+        player.searchWords = searchWords([player.name, ...aliases].join(' '));
+    }
+    return queryWords.every((queryWord) =>
+        player.searchWords.some((word) => word.startsWith(queryWord))
+    );
+}
+
+function describePlayer(player) {
+    const details = [player.team, player.position].filter((value) => value && value !== 'N/A');
+    if (Number.isFinite(player.oaa)) {
+        details.push(`${player.oaa > 0 ? '+' : ''}${player.oaa} OAA`);
+    }
+    return details.join(' · ');
+}
+
 async function liveSearch() {
     const searchInput = document.getElementById('nav-player-search');
     const resultsDiv = document.getElementById('search-results');
-    const query = searchInput.value.trim().toLowerCase();
+    const query = searchInput.value.trim();
 
     if (query.length === 0) {
         resultsDiv.innerHTML = '';
@@ -54,10 +83,12 @@ async function liveSearch() {
 
     await loadSearchIndex();
 
+    // The index is already ranked, so matches keep its order.
+    const queryWords = searchWords(query);
     const filtered = searchIndex.filter((player) =>
         typeof player.name === 'string' &&
         normalizePlayerID(player.id) !== null &&
-        player.name.toLowerCase().includes(query)
+        matchesQuery(player, queryWords)
     );
     currentResults = filtered;
 
@@ -66,7 +97,16 @@ async function liveSearch() {
 
     filtered.forEach((player, index) => {
         const playerDiv = document.createElement('div');
-        playerDiv.textContent = player.name;
+        const nameSpan = document.createElement('span');
+        nameSpan.textContent = player.name;
+        playerDiv.appendChild(nameSpan);
+        const details = describePlayer(player);
+        if (details) {
+            const detailSpan = document.createElement('span');
+            detailSpan.textContent = details;
+            detailSpan.classList.add('search-result-detail');
+            playerDiv.appendChild(detailSpan);
+        }
         playerDiv.classList.add('search-result-item');
         playerDiv.setAttribute('data-index', index);
         playerDiv.onclick = () => {
@@ -177,21 +177,30 @@ function renderTeamTable(tbody, players, season) {
 
   players.forEach((player) => {
     const row = document.createElement("tr");
+    if (player.TradedTo) {
+      row.className = "traded";
+    }
 
     const nameCell = document.createElement("td");
     const link = document.createElement("a");
     link.href = `/player/${player.PlayerID}?season=${season}`;
     link.textContent = player.Name;
     nameCell.appendChild(link);
+    if (player.TradedTo) {
+      const note = document.createElement("span");
+      note.className = "traded-note";
+      note.textContent = `→ ${player.TradedTo}`;
+      nameCell.appendChild(note);
+    }
 
     const positionCell = document.createElement("td");
     positionCell.textContent = player.Position;
 
     const oaaCell = document.createElement("td");
-    oaaCell.textContent = player.LatestOAA;
+    oaaCell.textContent = player.TeamOAA;
     oaaCell.className =
       "col-right" +
-      (player.LatestOAA > 0 ? " positive" : player.LatestOAA < 0 ? " negative" : "");
+      (player.TeamOAA > 0 ? " positive" : player.TeamOAA < 0 ? " negative" : "");
 
     const sparklineCell = document.createElement("td");
     const canvas = document.createElement("canvas");
@@ -259,6 +268,11 @@ document.addEventListener("DOMContentLoaded", () => {
     data.teamStatsBySeason?.[getSeasonKey(season)] || [];
   const getSparklinesForSeason = (season) =>
     data.sparklinesBySeason?.[getSeasonKey(season)] || [];
+  const getEraForSeason = (season) =>
+    data.teamErasBySeason?.[getSeasonKey(season)] || {
+      name: data.teamName,
+      abbreviation: data.teamAbbreviation,
+    };
 
   const urlParams = new URLSearchParams(window.location.search);
   const paramSeason = urlParams.get("season");
@@ -275,6 +289,26 @@ document.addEventListener("DOMContentLoaded", () => {
     selectEl.value = String(currentSeason);
   }
 
+  // The club's name, abbreviation and colors can change between seasons
+  // (e.g. Indians to Guardians), so the header follows the selected season.
+  const updateTeamHeader = (season) => {
+    const era = getEraForSeason(season);
+    const nameEl = document.getElementById("teamName");
+    const metaEl = document.getElementById("teamMeta");
+    const labelEl = document.getElementById("teamSectionLabel");
+    const heroEl = document.querySelector(".team-hero");
+    if (nameEl) nameEl.textContent = era.name;
+    if (labelEl) labelEl.textContent = `${era.name} OAA`;
+    if (metaEl) {
+      metaEl.textContent = era.league
+        ? `${era.abbreviation} · ${era.league} ${era.division}`
+        : era.abbreviation;
+    }
+    if (heroEl && era.primary_color) {
+      heroEl.style.setProperty("--team-color", era.primary_color);
+    }
+  };
+
   const updateSavantLink = (season) => {
     if (!savantLink) return;
     const base =
@@ -284,7 +318,7 @@ document.addEventListener("DOMContentLoaded", () => {
       startYear: String(season),
       endYear: String(season),
       split: "yes",
-      team: data.teamAbbreviation || "",
+      team: getEraForSeason(season).abbreviation || "",
       range: "year",
       min: "10",
       pos: "",
@@ -328,6 +362,7 @@ document.addEventListener("DOMContentLoaded", () => {
 
   renderTeamTable(tableBody, getSparklinesForSeason(currentSeason), currentSeason);
   updateSavantLink(currentSeason);
+  updateTeamHeader(currentSeason);
 
   const updateSeason = (season, pushState = true) => {
     currentSeason = Number(season);
@@ -342,6 +377,7 @@ document.addEventListener("DOMContentLoaded", () => {
       currentSeason,
     );
     updateSavantLink(currentSeason);
+    updateTeamHeader(currentSeason);
 
     if (pushState) {
       const params = new URLSearchParams(window.location.search);
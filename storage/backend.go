@@ -0,0 +1,103 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned when a key does not exist in a backend.
+	ErrNotFound = errors.New("object not found")
+	// ErrNotModified is returned by Get when the object still has the ETag
+	// passed in GetOptions.IfNoneMatch.
+	ErrNotModified = errors.New("object not modified")
+)
+
+// ObjectInfo describes a stored object. Keys are relative to the backend's
+// prefix or root directory and always use forward slashes.
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	LastModified time.Time
+	ETag         string
+	// ContentEncoding is the encoding the object was stored with, such as
+	// gzip or zstd.
+	ContentEncoding string
+	// Metadata holds the user metadata stored with the object, with
+	// lowercase names. Listings leave it empty.
+	Metadata map[string]string
+}
+
+// GetOptions makes a Get conditional.
+type GetOptions struct {
+	// IfNoneMatch skips the download with ErrNotModified when the object's
+	// ETag still equals it.
+	IfNoneMatch string
+}
+
+// PutOptions describes an object being stored.
+type PutOptions struct {
+	// ContentEncoding records how the body is compressed. S3 returns it as
+	// the Content-Encoding header.
+	ContentEncoding string
+	// Metadata is stored with the object; names should be lowercase.
+	Metadata map[string]string
+}
+
+// Backend stores objects by key. Every implementation returns an error
+// wrapping ErrNotFound for a key that does not exist.
+type Backend interface {
+	Get(ctx context.Context, key string, opts GetOptions) (io.ReadCloser, ObjectInfo, error)
+	Put(ctx context.Context, key string, reader io.ReadSeeker, size int64, opts PutOptions) error
+	// List returns every object whose key starts with prefix, ordered by key.
+	List(ctx context.Context, prefix string) ([]ObjectInfo, error)
+	Stat(ctx context.Context, key string) (ObjectInfo, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// Open returns the backend a URL names: s3://bucket/prefix for an
+// S3-compatible bucket, with credentials and endpoint taken from the usual
+// environment variables, or file:///path for a local directory.
+func Open(rawURL string) (Backend, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid storage URL %q: %v", rawURL, err)
+	}
+
+	switch u.Scheme {
+	case "s3":
+		if u.Host == "" {
+			return nil, fmt.Errorf("storage URL %q has no bucket", rawURL)
+		}
+		client, err := getS3Client()
+		if err != nil {
+			return nil, err
+		}
+		return NewS3Backend(client, u.Host, u.Path), nil
+	case "file":
+		if u.Host != "" && u.Host != "localhost" {
+			return nil, fmt.Errorf("storage URL %q must be a local path (file:///path)", rawURL)
+		}
+		if u.Path == "" {
+			return nil, fmt.Errorf("storage URL %q has no path", rawURL)
+		}
+		return NewFileBackend(u.Path), nil
+	default:
+		return nil, fmt.Errorf("unsupported storage URL %q: expected s3:// or file://", rawURL)
+	}
+}
+
+// cleanKey rejects keys that are empty or would escape the backend's root.
+func cleanKey(key string) (string, error) {
+	cleaned := path.Clean("/" + key)[1:]
+	if cleaned == "" || cleaned != strings.TrimPrefix(key, "/") {
+		return "", fmt.Errorf("invalid object key %q", key)
+	}
+	return cleaned, nil
+}
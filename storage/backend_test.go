@@ -0,0 +1,151 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testBackend(t *testing.T, backend Backend) {
+	ctx := context.Background()
+
+	if _, _, err := backend.Get(ctx, "missing.db", GetOptions{}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get of a missing key: expected ErrNotFound, got %v", err)
+	}
+	if _, err := backend.Stat(ctx, "missing.db"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Stat of a missing key: expected ErrNotFound, got %v", err)
+	}
+	if err := backend.Delete(ctx, "missing.db"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Delete of a missing key: expected ErrNotFound, got %v", err)
+	}
+	if err := backend.Put(ctx, "../escape.db", strings.NewReader("x"), 1, PutOptions{}); err == nil {
+		t.Error("expected Put to reject a key outside the backend")
+	}
+
+	objects := map[string]string{
+		"oaamonitor.db":                 "database",
+		"raw/2025/06/01/1400.csv.gz":    "first",
+		"raw/2025/06/02/1400.csv.gz":    "second",
+		"snapshots/2025/06/01T1400Z.db": "snapshot",
+	}
+	for key, body := range objects {
+		if err := backend.Put(ctx, key, strings.NewReader(body), int64(len(body)), PutOptions{}); err != nil {
+			t.Fatalf("Put(%s) returned error: %v", key, err)
+		}
+	}
+	// Overwriting replaces the contents and metadata.
+	metadata := map[string]string{"sha256": "abc123"}
+	if err := backend.Put(ctx, "oaamonitor.db", strings.NewReader("database v2"), 11, PutOptions{Metadata: metadata}); err != nil {
+		t.Fatalf("Put returned error: %v", err)
+	}
+
+	reader, got, err := backend.Get(ctx, "oaamonitor.db", GetOptions{})
+	if err != nil {
+		t.Fatalf("Get returned error: %v", err)
+	}
+	data, _ := io.ReadAll(reader)
+	reader.Close()
+	if string(data) != "database v2" {
+		t.Errorf("expected the overwritten contents, got %q", data)
+	}
+	if got.ETag == "" || !reflect.DeepEqual(got.Metadata, metadata) {
+		t.Errorf("unexpected object info from Get %+v", got)
+	}
+
+	// A matching ETag skips the download; a stale one does not.
+	if _, _, err := backend.Get(ctx, "oaamonitor.db", GetOptions{IfNoneMatch: got.ETag}); !errors.Is(err, ErrNotModified) {
+		t.Errorf("Get with the current ETag: expected ErrNotModified, got %v", err)
+	}
+	reader, _, err = backend.Get(ctx, "oaamonitor.db", GetOptions{IfNoneMatch: `"stale"`})
+	if err != nil {
+		t.Fatalf("Get with a stale ETag returned error: %v", err)
+	}
+	reader.Close()
+
+	info, err := backend.Stat(ctx, "oaamonitor.db")
+	if err != nil {
+		t.Fatalf("Stat returned error: %v", err)
+	}
+	if info.Key != "oaamonitor.db" || info.Size != 11 || info.ETag != got.ETag || info.LastModified.IsZero() || !reflect.DeepEqual(info.Metadata, metadata) {
+		t.Errorf("unexpected object info %+v", info)
+	}
+
+	listed, err := backend.List(ctx, "raw/")
+	if err != nil {
+		t.Fatalf("List returned error: %v", err)
+	}
+	var keys []string
+	for _, object := range listed {
+		keys = append(keys, object.Key)
+	}
+	if !reflect.DeepEqual(keys, []string{"raw/2025/06/01/1400.csv.gz", "raw/2025/06/02/1400.csv.gz"}) {
+		t.Errorf("unexpected listing %v", keys)
+	}
+
+	if err := backend.Delete(ctx, "raw/2025/06/01/1400.csv.gz"); err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+	if listed, _ := backend.List(ctx, ""); len(listed) != 3 {
+		t.Errorf("expected 3 objects after the delete, got %d", len(listed))
+	}
+}
+
+func TestFileBackend(t *testing.T) {
+	testBackend(t, NewFileBackend(t.TempDir()))
+}
+
+func TestFileBackend_ListMissingRoot(t *testing.T) {
+	backend := NewFileBackend(filepath.Join(t.TempDir(), "missing"))
+	objects, err := backend.List(context.Background(), "")
+	if err != nil || len(objects) != 0 {
+		t.Errorf("expected an empty listing, got %v, %v", objects, err)
+	}
+}
+
+func TestMemoryBackend(t *testing.T) {
+	testBackend(t, NewMemoryBackend())
+}
+
+func TestOpen(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "key")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+
+	backend, err := Open("s3://oaamonitor/history/")
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	s3, ok := backend.(*S3Backend)
+	if !ok || s3.bucket != "oaamonitor" || s3.prefix != "history/" {
+		t.Errorf("unexpected S3 backend %+v", backend)
+	}
+
+	dir := t.TempDir()
+	backend, err = Open("file://" + dir)
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	if file, ok := backend.(*FileBackend); !ok || file.root != dir {
+		t.Errorf("unexpected file backend %+v", backend)
+	}
+
+	for _, invalid := range []string{"s3://", "file://host/path", "ftp://example.com/db", "oaamonitor.db"} {
+		if _, err := Open(invalid); err == nil {
+			t.Errorf("Open(%q): expected an error", invalid)
+		}
+	}
+
+	// S3 rejects non-final parts under 5 MiB, so smaller part sizes fail
+	// before any upload starts.
+	t.Setenv("S3_PART_SIZE_MB", "4")
+	if _, err := Open("s3://oaamonitor"); err == nil {
+		t.Error("expected an error for a 4 MiB part size")
+	}
+	t.Setenv("S3_PART_SIZE_MB", "5")
+	if backend, err := Open("s3://oaamonitor"); err != nil || backend.(*S3Backend).client.PartSize != MinPartSize {
+		t.Errorf("expected a 5 MiB part size to be accepted, got %v", err)
+	}
+}
@@ -0,0 +1,159 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression is how a database is compressed for transfer.
+type Compression string
+
+const (
+	CompressionZstd Compression = "zstd"
+	CompressionGzip Compression = "gzip"
+	CompressionNone Compression = "none"
+)
+
+// ParseCompression returns the compression a name selects.
+func ParseCompression(name string) (Compression, error) {
+	switch compression := Compression(name); compression {
+	case CompressionZstd, CompressionGzip, CompressionNone:
+		return compression, nil
+	default:
+		return "", fmt.Errorf("unknown compression %q: expected zstd, gzip or none", name)
+	}
+}
+
+// Extension returns the suffix added after a database's .db extension.
+func (c Compression) Extension() string {
+	switch c {
+	case CompressionZstd:
+		return ".zst"
+	case CompressionGzip:
+		return ".gz"
+	default:
+		return ""
+	}
+}
+
+// ContentEncoding returns the HTTP Content-Encoding for the compression, or ""
+// for uncompressed data.
+func (c Compression) ContentEncoding() string {
+	if c == CompressionNone {
+		return ""
+	}
+	return string(c)
+}
+
+// newWriter returns a writer that compresses into w. Closing it flushes the
+// compressed stream but does not close w.
+func (c Compression) newWriter(w io.Writer) (io.WriteCloser, error) {
+	switch c {
+	case CompressionZstd:
+		return zstd.NewWriter(w)
+	case CompressionGzip:
+		return gzip.NewWriterLevel(w, gzip.BestCompression)
+	default:
+		return nopWriteCloser{w}, nil
+	}
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// CompressedFile describes a file written by CompressFile.
+type CompressedFile struct {
+	// Size is the compressed size and SourceSize the size of the original.
+	Size       int64
+	SourceSize int64
+	// SHA256 is the hex SHA-256 of the original.
+	SHA256 string
+}
+
+// CompressFile writes src to dst with compression, through a temporary file
+// so dst is never left partially written.
+func CompressFile(src, dst string, compression Compression) (CompressedFile, error) {
+	source, err := os.Open(src)
+	if err != nil {
+		return CompressedFile{}, err
+	}
+	defer source.Close()
+
+	tmp, err := os.CreateTemp(filepath.Dir(dst), "."+filepath.Base(dst)+"-*")
+	if err != nil {
+		return CompressedFile{}, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	writer, err := compression.newWriter(tmp)
+	if err != nil {
+		return CompressedFile{}, err
+	}
+	hash := sha256.New()
+	sourceSize, err := io.Copy(writer, io.TeeReader(source, hash))
+	if err != nil {
+		writer.Close()
+		return CompressedFile{}, err
+	}
+	if err := writer.Close(); err != nil {
+		return CompressedFile{}, err
+	}
+	size, err := tmp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return CompressedFile{}, err
+	}
+	if err := tmp.Close(); err != nil {
+		return CompressedFile{}, err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return CompressedFile{}, err
+	}
+	if err := os.Rename(tmp.Name(), dst); err != nil {
+		return CompressedFile{}, err
+	}
+
+	return CompressedFile{Size: size, SourceSize: sourceSize, SHA256: hex.EncodeToString(hash.Sum(nil))}, nil
+}
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// decompressingReader returns a reader that decompresses r if it starts with
+// a gzip or zstd header and passes it through otherwise. Sniffing the data
+// rather than trusting Content-Encoding also copes with HTTP clients and
+// providers that decode the response on the way.
+func decompressingReader(r io.Reader) (io.ReadCloser, error) {
+	buffered := bufio.NewReader(r)
+	header, err := buffered.Peek(len(zstdMagic))
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, err
+	}
+
+	switch {
+	case bytes.HasPrefix(header, zstdMagic):
+		decoder, err := zstd.NewReader(buffered)
+		if err != nil {
+			return nil, err
+		}
+		return decoder.IOReadCloser(), nil
+	case bytes.HasPrefix(header, gzipMagic):
+		return gzip.NewReader(buffered)
+	default:
+		return io.NopCloser(buffered), nil
+	}
+}
@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseCompression(t *testing.T) {
+	for _, name := range []string{"zstd", "gzip", "none"} {
+		if compression, err := ParseCompression(name); err != nil || string(compression) != name {
+			t.Errorf("ParseCompression(%q) = %q, %v", name, compression, err)
+		}
+	}
+	if _, err := ParseCompression("brotli"); err == nil {
+		t.Error("expected an error for an unknown compression")
+	}
+}
+
+func TestCompressFile(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "oaamonitor.db")
+	contents := bytes.Repeat([]byte("SQLite format 3\x00 rows "), 1000)
+	if err := os.WriteFile(source, contents, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, compression := range []Compression{CompressionZstd, CompressionGzip, CompressionNone} {
+		target := source + compression.Extension() + ".out"
+		result, err := CompressFile(source, target, compression)
+		if err != nil {
+			t.Fatalf("CompressFile(%s) returned error: %v", compression, err)
+		}
+		if result.SourceSize != int64(len(contents)) || result.SHA256 == "" {
+			t.Errorf("%s: unexpected result %+v", compression, result)
+		}
+		if compression != CompressionNone && result.Size >= result.SourceSize/10 {
+			t.Errorf("%s: expected repetitive data to compress well, got %d of %d bytes", compression, result.Size, result.SourceSize)
+		}
+		if info, err := os.Stat(target); err != nil || info.Size() != result.Size {
+			t.Errorf("%s: expected a %d byte file, got %v, %v", compression, result.Size, info, err)
+		}
+
+		file, err := os.Open(target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader, err := decompressingReader(file)
+		if err != nil {
+			t.Fatalf("decompressingReader(%s) returned error: %v", compression, err)
+		}
+		data, err := io.ReadAll(reader)
+		reader.Close()
+		file.Close()
+		if err != nil || !bytes.Equal(data, contents) {
+			t.Errorf("%s: round trip failed: %v", compression, err)
+		}
+	}
+}
+
+func TestDecompressingReader_Short(t *testing.T) {
+	reader, err := decompressingReader(bytes.NewReader([]byte("x")))
+	if err != nil {
+		t.Fatalf("decompressingReader returned error: %v", err)
+	}
+	if data, _ := io.ReadAll(reader); string(data) != "x" {
+		t.Errorf("expected short input to pass through, got %q", data)
+	}
+}
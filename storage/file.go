@@ -0,0 +1,227 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// FileBackend stores objects as files under a local directory, with key
+// segments as subdirectories. Content encoding and metadata are kept in a
+// hidden JSON file next to each object.
+type FileBackend struct {
+	root string
+}
+
+// NewFileBackend returns a backend rooted at dir. The directory is created on
+// the first Put.
+func NewFileBackend(dir string) *FileBackend {
+	return &FileBackend{root: dir}
+}
+
+func (b *FileBackend) path(key string) (string, error) {
+	cleaned, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(b.root, filepath.FromSlash(cleaned)), nil
+}
+
+// fileMetadata is the hidden file stored next to an object.
+type fileMetadata struct {
+	ContentEncoding string            `json:"content_encoding,omitempty"`
+	Metadata        map[string]string `json:"metadata,omitempty"`
+}
+
+// metadataPath returns the hidden file holding the metadata for target.
+func metadataPath(target string) string {
+	return filepath.Join(filepath.Dir(target), "."+filepath.Base(target)+".meta.json")
+}
+
+// readMetadata fills info from the metadata file for target, if there is one.
+func readMetadata(target string, info *ObjectInfo) error {
+	data, err := os.ReadFile(metadataPath(target))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var metadata fileMetadata
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		return fmt.Errorf("invalid metadata for %s: %w", target, err)
+	}
+	info.ContentEncoding = metadata.ContentEncoding
+	info.Metadata = metadata.Metadata
+	return nil
+}
+
+// Get opens the file for key.
+func (b *FileBackend) Get(ctx context.Context, key string, opts GetOptions) (io.ReadCloser, ObjectInfo, error) {
+	target, err := b.path(key)
+	if err != nil {
+		return nil, ObjectInfo{}, err
+	}
+	file, err := os.Open(target)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ObjectInfo{}, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	if err != nil {
+		return nil, ObjectInfo{}, err
+	}
+
+	fileInfo, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, ObjectInfo{}, err
+	}
+	info := fileObjectInfo(key, fileInfo)
+	if err := readMetadata(target, &info); err != nil {
+		file.Close()
+		return nil, ObjectInfo{}, err
+	}
+	if opts.IfNoneMatch != "" && opts.IfNoneMatch == info.ETag {
+		file.Close()
+		return nil, info, fmt.Errorf("%w: %s", ErrNotModified, key)
+	}
+	return file, info, nil
+}
+
+// Put writes reader to key through a temporary file, so readers never see a
+// partial object.
+func (b *FileBackend) Put(ctx context.Context, key string, reader io.ReadSeeker, size int64, opts PutOptions) error {
+	target, err := b.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+
+	if opts.ContentEncoding == "" && len(opts.Metadata) == 0 {
+		if err := os.Remove(metadataPath(target)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	} else {
+		data, err := json.Marshal(fileMetadata{ContentEncoding: opts.ContentEncoding, Metadata: opts.Metadata})
+		if err != nil {
+			return err
+		}
+		if err := writeAtomic(metadataPath(target), bytes.NewReader(data)); err != nil {
+			return err
+		}
+	}
+	return writeAtomic(target, reader)
+}
+
+func writeAtomic(target string, reader io.Reader) error {
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, reader); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), target)
+}
+
+// List walks the directory for files whose key starts with prefix.
+func (b *FileBackend) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	var objects []ObjectInfo
+	err := filepath.WalkDir(b.root, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) && p == b.root {
+				return filepath.SkipAll
+			}
+			return err
+		}
+		// Hidden files are in-progress uploads and metadata.
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			return nil
+		}
+		rel, err := filepath.Rel(b.root, p)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		objects = append(objects, fileObjectInfo(key, info))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+	return objects, nil
+}
+
+// Stat describes the file for key.
+func (b *FileBackend) Stat(ctx context.Context, key string) (ObjectInfo, error) {
+	target, err := b.path(key)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	info, err := os.Stat(target)
+	if errors.Is(err, fs.ErrNotExist) || (err == nil && info.IsDir()) {
+		return ObjectInfo{}, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	object := fileObjectInfo(key, info)
+	if err := readMetadata(target, &object); err != nil {
+		return ObjectInfo{}, err
+	}
+	return object, nil
+}
+
+// Delete removes the file for key.
+func (b *FileBackend) Delete(ctx context.Context, key string) error {
+	target, err := b.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(target); errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%w: %s", ErrNotFound, key)
+	} else if err != nil {
+		return err
+	}
+	if err := os.Remove(metadataPath(target)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// fileObjectInfo derives an ETag from the size and modification time, which
+// change whenever Put replaces the file.
+func fileObjectInfo(key string, info fs.FileInfo) ObjectInfo {
+	return ObjectInfo{
+		Key:          key,
+		Size:         info.Size(),
+		LastModified: info.ModTime(),
+		ETag:         fmt.Sprintf(`"%x-%x"`, info.ModTime().UnixNano(), info.Size()),
+	}
+}
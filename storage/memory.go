@@ -0,0 +1,126 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"maps"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryBackend keeps objects in memory. It is meant for tests and dry runs.
+type MemoryBackend struct {
+	mu      sync.Mutex
+	objects map[string]memoryObject
+}
+
+type memoryObject struct {
+	data         []byte
+	lastModified time.Time
+	encoding     string
+	metadata     map[string]string
+}
+
+// NewMemoryBackend returns an empty in-memory backend.
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{objects: make(map[string]memoryObject)}
+}
+
+// Get returns a copy of the object stored under key.
+func (b *MemoryBackend) Get(ctx context.Context, key string, opts GetOptions) (io.ReadCloser, ObjectInfo, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	object, ok := b.objects[key]
+	if !ok {
+		return nil, ObjectInfo{}, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	info := object.info(key)
+	info.ContentEncoding = object.encoding
+	info.Metadata = maps.Clone(object.metadata)
+	if opts.IfNoneMatch != "" && opts.IfNoneMatch == info.ETag {
+		return nil, info, fmt.Errorf("%w: %s", ErrNotModified, key)
+	}
+	return io.NopCloser(bytes.NewReader(bytes.Clone(object.data))), info, nil
+}
+
+// Put stores the contents of reader under key.
+func (b *MemoryBackend) Put(ctx context.Context, key string, reader io.ReadSeeker, size int64, opts PutOptions) error {
+	if _, err := cleanKey(key); err != nil {
+		return err
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.objects[key] = memoryObject{
+		data:         data,
+		lastModified: time.Now(),
+		encoding:     opts.ContentEncoding,
+		metadata:     maps.Clone(opts.Metadata),
+	}
+	return nil
+}
+
+// List returns the objects whose key starts with prefix.
+func (b *MemoryBackend) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var objects []ObjectInfo
+	for key, object := range b.objects {
+		if strings.HasPrefix(key, prefix) {
+			objects = append(objects, object.info(key))
+		}
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+	return objects, nil
+}
+
+// Stat describes the object stored under key.
+func (b *MemoryBackend) Stat(ctx context.Context, key string) (ObjectInfo, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	object, ok := b.objects[key]
+	if !ok {
+		return ObjectInfo{}, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	info := object.info(key)
+	info.ContentEncoding = object.encoding
+	info.Metadata = maps.Clone(object.metadata)
+	return info, nil
+}
+
+// Delete removes the object stored under key.
+func (b *MemoryBackend) Delete(ctx context.Context, key string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.objects[key]; !ok {
+		return fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	delete(b.objects, key)
+	return nil
+}
+
+// info uses the MD5 of the contents as the ETag, as S3 does for single-part
+// uploads.
+func (o memoryObject) info(key string) ObjectInfo {
+	sum := md5.Sum(o.data)
+	return ObjectInfo{
+		Key:          key,
+		Size:         int64(len(o.data)),
+		LastModified: o.lastModified,
+		ETag:         `"` + hex.EncodeToString(sum[:]) + `"`,
+	}
+}
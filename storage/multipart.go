@@ -0,0 +1,219 @@
+package storage
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+)
+
+const (
+	// DefaultPartSize is the multipart threshold and part size when
+	// S3Client.PartSize is zero.
+	DefaultPartSize = 16 << 20
+	// MinPartSize is the smallest part S3 accepts other than the last one.
+	MinPartSize = 5 << 20
+	// DefaultConcurrency is the number of parts uploaded at once when
+	// S3Client.Concurrency is zero.
+	DefaultConcurrency = 4
+
+	// maxParts is the most parts S3 accepts in one multipart upload.
+	maxParts = 10000
+
+	unsignedPayload = "UNSIGNED-PAYLOAD"
+)
+
+func (c *S3Client) partSize() int64 {
+	if c.PartSize > 0 {
+		return c.PartSize
+	}
+	return DefaultPartSize
+}
+
+func (c *S3Client) concurrency() int {
+	if c.Concurrency > 0 {
+		return c.Concurrency
+	}
+	return DefaultConcurrency
+}
+
+type completedPart struct {
+	PartNumber int
+	ETag       string
+}
+
+type completeMultipartUpload struct {
+	XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+	Parts   []completedPart `xml:"Part"`
+}
+
+// putMultipart uploads reader in parts, several at a time. Parts are read
+// sequentially into a fixed pool of buffers, so memory use is bounded by
+// Concurrency parts and the source is read only once. The upload is aborted if
+// any part fails.
+func (c *S3Client) putMultipart(ctx context.Context, bucket, key string, reader io.Reader, size int64, opts PutOptions) (err error) {
+	partSize := max(c.partSize(), (size+maxParts-1)/maxParts)
+	partCount := int((size + partSize - 1) / partSize)
+
+	uploadID, err := c.createMultipartUpload(ctx, bucket, key, opts)
+	if err != nil {
+		return fmt.Errorf("failed to start multipart upload: %w", err)
+	}
+	defer func() {
+		if err == nil {
+			return
+		}
+		// Abort even if ctx was cancelled, so S3 does not keep billing for
+		// the uploaded parts.
+		if abortErr := c.abortMultipartUpload(context.WithoutCancel(ctx), bucket, key, uploadID); abortErr != nil {
+			log.Printf("Failed to abort multipart upload of %s/%s: %v", bucket, key, abortErr)
+		}
+	}()
+
+	uploadCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	buffers := make(chan []byte, c.concurrency())
+	for range c.concurrency() {
+		buffers <- nil
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	fail := func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if firstErr == nil {
+			firstErr = err
+			cancel()
+		}
+	}
+
+	parts := make([]completedPart, partCount)
+	for i := range partCount {
+		var buf []byte
+		select {
+		case buf = <-buffers:
+		case <-uploadCtx.Done():
+		}
+		if uploadCtx.Err() != nil {
+			break
+		}
+
+		n := min(partSize, size-int64(i)*partSize)
+		if int64(cap(buf)) < n {
+			buf = make([]byte, partSize)
+		}
+		buf = buf[:n]
+		if _, err := io.ReadFull(reader, buf); err != nil {
+			fail(fmt.Errorf("failed to read part %d: %w", i+1, err))
+			break
+		}
+
+		wg.Add(1)
+		go func(number int, part []byte) {
+			defer wg.Done()
+			defer func() { buffers <- part }()
+
+			etag, err := c.uploadPart(uploadCtx, bucket, key, uploadID, number, part)
+			if err != nil {
+				fail(fmt.Errorf("failed to upload part %d: %w", number, err))
+				return
+			}
+			parts[number-1] = completedPart{PartNumber: number, ETag: etag}
+		}(i+1, buf)
+	}
+	wg.Wait()
+
+	if firstErr == nil {
+		firstErr = ctx.Err()
+	}
+	if firstErr != nil {
+		return firstErr
+	}
+
+	if err := c.completeMultipartUpload(ctx, bucket, key, uploadID, parts); err != nil {
+		return fmt.Errorf("failed to complete multipart upload: %w", err)
+	}
+	return nil
+}
+
+func (c *S3Client) createMultipartUpload(ctx context.Context, bucket, key string, opts PutOptions) (string, error) {
+	resp, err := c.do(ctx, http.MethodPost, bucket, key, url.Values{"uploads": {""}}, nil, objectHeaders(opts))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		UploadID string `xml:"UploadId"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode upload ID: %w", err)
+	}
+	if result.UploadID == "" {
+		return "", fmt.Errorf("S3 returned no upload ID")
+	}
+	return result.UploadID, nil
+}
+
+func (c *S3Client) uploadPart(ctx context.Context, bucket, key, uploadID string, number int, part []byte) (string, error) {
+	query := url.Values{"partNumber": {strconv.Itoa(number)}, "uploadId": {uploadID}}
+	resp, err := c.do(ctx, http.MethodPut, bucket, key, query, part, nil)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+
+	etag := resp.Header.Get("ETag")
+	if etag == "" {
+		return "", fmt.Errorf("S3 returned no ETag")
+	}
+	return etag, nil
+}
+
+// completeMultipartUpload assembles the parts. S3 can report a failure in the
+// body of a 200 response once it has started sending it, so the body is
+// checked for an Error element.
+func (c *S3Client) completeMultipartUpload(ctx context.Context, bucket, key, uploadID string, parts []completedPart) error {
+	body, err := xml.Marshal(completeMultipartUpload{Parts: parts})
+	if err != nil {
+		return err
+	}
+	headers := http.Header{"Content-Type": {"application/xml"}}
+	resp, err := c.do(ctx, http.MethodPost, bucket, key, url.Values{"uploadId": {uploadID}}, body, headers)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		XMLName xml.Name
+		Code    string
+		Message string
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("failed to decode completion result: %w", err)
+	}
+	if result.XMLName.Local == "Error" {
+		return fmt.Errorf("S3 error %s: %s", result.Code, result.Message)
+	}
+	return nil
+}
+
+func (c *S3Client) abortMultipartUpload(ctx context.Context, bucket, key, uploadID string) error {
+	resp, err := c.do(ctx, http.MethodDelete, bucket, key, url.Values{"uploadId": {uploadID}}, nil, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"strings"
+)
+
+// S3Backend stores objects in an S3-compatible bucket, optionally under a key
+// prefix.
+type S3Backend struct {
+	client *S3Client
+	bucket string
+	prefix string
+}
+
+// NewS3Backend returns a backend for bucket. A non-empty prefix is treated as
+// a directory: "snapshots" stores key "a.db" at "snapshots/a.db".
+func NewS3Backend(client *S3Client, bucket, prefix string) *S3Backend {
+	prefix = strings.Trim(prefix, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+	return &S3Backend{client: client, bucket: bucket, prefix: prefix}
+}
+
+func (b *S3Backend) objectKey(key string) (string, error) {
+	cleaned, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return b.prefix + cleaned, nil
+}
+
+// Get downloads the object stored under key.
+func (b *S3Backend) Get(ctx context.Context, key string, opts GetOptions) (io.ReadCloser, ObjectInfo, error) {
+	objectKey, err := b.objectKey(key)
+	if err != nil {
+		return nil, ObjectInfo{}, err
+	}
+	body, info, err := b.client.GetObject(ctx, b.bucket, objectKey, opts)
+	info.Key = key
+	return body, info, err
+}
+
+// Put uploads reader to key.
+func (b *S3Backend) Put(ctx context.Context, key string, reader io.ReadSeeker, size int64, opts PutOptions) error {
+	objectKey, err := b.objectKey(key)
+	if err != nil {
+		return err
+	}
+	return b.client.PutObject(ctx, b.bucket, objectKey, reader, size, opts)
+}
+
+// List returns the objects under the backend's prefix whose key starts with
+// prefix. S3 already lists keys in order.
+func (b *S3Backend) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	result, err := b.client.ListObjectsV2(ctx, b.bucket, b.prefix+prefix, "")
+	if err != nil {
+		return nil, err
+	}
+	objects := result.Objects
+	for i := range objects {
+		objects[i].Key = strings.TrimPrefix(objects[i].Key, b.prefix)
+	}
+	return objects, nil
+}
+
+// Stat describes the object stored under key.
+func (b *S3Backend) Stat(ctx context.Context, key string) (ObjectInfo, error) {
+	objectKey, err := b.objectKey(key)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	info, err := b.client.HeadObject(ctx, b.bucket, objectKey)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	info.Key = key
+	return info, nil
+}
+
+// Delete removes the object stored under key. S3 deletes succeed for missing
+// keys, so the object is checked first to report ErrNotFound like the other
+// backends.
+func (b *S3Backend) Delete(ctx context.Context, key string) error {
+	objectKey, err := b.objectKey(key)
+	if err != nil {
+		return err
+	}
+	if _, err := b.client.HeadObject(ctx, b.bucket, objectKey); err != nil {
+		return err
+	}
+	return b.client.DeleteObject(ctx, b.bucket, objectKey)
+}
@@ -1,10 +1,14 @@
 package storage
 
 import (
+	"bytes"
 	"context"
 	"crypto/hmac"
+	"crypto/md5"
 	"crypto/sha256"
+	"encoding/base64"
 	"encoding/hex"
+	"encoding/xml"
 	"fmt"
 	"io"
 	"net/http"
@@ -14,12 +18,26 @@ import (
 	"time"
 )
 
+// metadataHeaderPrefix starts the headers S3 stores user metadata in.
+const metadataHeaderPrefix = "x-amz-meta-"
+
 // S3Client is a lightweight S3-compatible client using only standard library
 type S3Client struct {
 	AccessKeyID     string
 	SecretAccessKey string
 	Region          string
 	EndpointURL     string
+	// PartSize is the largest object PutObject sends in one request, and the
+	// size of each part of larger uploads. S3 rejects parts under 5 MiB other
+	// than the last. Zero means DefaultPartSize.
+	PartSize int64
+	// Concurrency is the number of parts uploaded at once. Zero means
+	// DefaultConcurrency.
+	Concurrency int
+	// UnsignedPayload signs requests with UNSIGNED-PAYLOAD instead of a hash
+	// of the body, so uploads are read once. TLS still protects the body in
+	// transit.
+	UnsignedPayload bool
 	client          *http.Client
 }
 
@@ -30,95 +48,347 @@ func NewS3Client(accessKeyID, secretAccessKey, region, endpointURL string) *S3Cl
 		SecretAccessKey: secretAccessKey,
 		Region:          region,
 		EndpointURL:     endpointURL,
-		client:          &http.Client{Timeout: 5 * time.Minute},
+		client:          newHTTPClient(),
 	}
 }
 
-// GetObject downloads an object from S3 and returns the response body
-func (c *S3Client) GetObject(ctx context.Context, bucket, key string) (io.ReadCloser, error) {
-	objectURL, err := buildObjectURL(c.EndpointURL, bucket, key)
+// responseHeaderTimeout bounds how long a request waits for S3 to start
+// responding once its body has been sent.
+const responseHeaderTimeout = time.Minute
+
+// newHTTPClient returns a client without an overall timeout, which would also
+// cut off slow part uploads and long GetObject body streams. Only the wait for
+// response headers is bounded; callers bound the rest with ctx.
+func newHTTPClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = responseHeaderTimeout
+	return &http.Client{Transport: transport}
+}
+
+// GetObject downloads an object from S3 and returns the response body along
+// with the object's ETag, size and metadata. A set opts.IfNoneMatch is sent as
+// If-None-Match, and a 304 response returns ErrNotModified.
+func (c *S3Client) GetObject(ctx context.Context, bucket, key string, opts GetOptions) (io.ReadCloser, ObjectInfo, error) {
+	var headers http.Header
+	if opts.IfNoneMatch != "" {
+		headers = http.Header{"If-None-Match": {opts.IfNoneMatch}}
+	}
+	resp, err := c.do(ctx, http.MethodGet, bucket, key, nil, nil, headers)
 	if err != nil {
-		return nil, fmt.Errorf("failed to build request URL: %w", err)
+		return nil, ObjectInfo{}, err
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "GET", objectURL, nil)
+	info, err := objectInfoFromResponse(key, resp)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		resp.Body.Close()
+		return nil, ObjectInfo{}, err
 	}
+	return resp.Body, info, nil
+}
 
-	// Sign the request
-	if err := c.signRequest(req, bucket, key, ""); err != nil {
-		return nil, fmt.Errorf("failed to sign request: %w", err)
+// objectInfoFromResponse describes an object from the headers of a GET or HEAD
+// response.
+func objectInfoFromResponse(key string, resp *http.Response) (ObjectInfo, error) {
+	info := ObjectInfo{
+		Key:             key,
+		Size:            resp.ContentLength,
+		ETag:            resp.Header.Get("ETag"),
+		ContentEncoding: resp.Header.Get("Content-Encoding"),
 	}
-
-	// Execute request
-	resp, err := c.client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+	if lastModified := resp.Header.Get("Last-Modified"); lastModified != "" {
+		var err error
+		if info.LastModified, err = http.ParseTime(lastModified); err != nil {
+			return ObjectInfo{}, fmt.Errorf("invalid Last-Modified header %q: %w", lastModified, err)
+		}
 	}
-
-	if resp.StatusCode == http.StatusNotFound {
-		resp.Body.Close()
-		return nil, fmt.Errorf("object not found: %s/%s", bucket, key)
+	for name, values := range resp.Header {
+		if suffix, ok := strings.CutPrefix(strings.ToLower(name), metadataHeaderPrefix); ok && len(values) > 0 {
+			if info.Metadata == nil {
+				info.Metadata = make(map[string]string)
+			}
+			info.Metadata[suffix] = values[0]
+		}
 	}
+	return info, nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		resp.Body.Close()
-		return nil, fmt.Errorf("S3 error %d: %s", resp.StatusCode, string(body))
+// objectHeaders returns the headers that store opts with an object: the
+// Content-Type, Content-Encoding and x-amz-meta-* metadata.
+func objectHeaders(opts PutOptions) http.Header {
+	headers := make(http.Header, len(opts.Metadata)+2)
+	headers.Set("Content-Type", "application/octet-stream")
+	if opts.ContentEncoding != "" {
+		headers.Set("Content-Encoding", opts.ContentEncoding)
 	}
-
-	return resp.Body, nil
+	for name, value := range opts.Metadata {
+		headers.Set(metadataHeaderPrefix+strings.ToLower(name), value)
+	}
+	return headers
 }
 
-// PutObject uploads an object to S3 from a file
-func (c *S3Client) PutObject(ctx context.Context, bucket, key string, reader io.ReadSeeker, size int64) error {
+// PutObject uploads size bytes from reader to S3, storing opts.ContentEncoding
+// and opts.Metadata with it. Objects larger than PartSize are sent as a multipart
+// upload; smaller ones in a single request, hashed first unless
+// UnsignedPayload is set.
+func (c *S3Client) PutObject(ctx context.Context, bucket, key string, reader io.ReadSeeker, size int64, opts PutOptions) error {
+	if _, err := reader.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to rewind reader: %w", err)
+	}
+	if size > c.partSize() {
+		return c.putMultipart(ctx, bucket, key, reader, size, opts)
+	}
+
 	objectURL, err := buildObjectURL(c.EndpointURL, bucket, key)
 	if err != nil {
 		return fmt.Errorf("failed to build request URL: %w", err)
 	}
 
+	payloadHash := unsignedPayload
+	if !c.UnsignedPayload {
+		hash := sha256.New()
+		if _, err := io.Copy(hash, reader); err != nil {
+			return fmt.Errorf("failed to hash payload: %w", err)
+		}
+		payloadHash = hex.EncodeToString(hash.Sum(nil))
+		if _, err := reader.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("failed to rewind reader: %w", err)
+		}
+	}
+
 	req, err := http.NewRequestWithContext(ctx, "PUT", objectURL, reader)
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
-
 	req.ContentLength = size
-	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header = objectHeaders(opts)
 
-	// Calculate payload hash
-	if _, err := reader.Seek(0, 0); err != nil {
-		return fmt.Errorf("failed to rewind reader: %w", err)
+	resp, err := c.send(req, bucket, key, payloadHash)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// ListResult is the combined result of every ListObjectsV2 page.
+type ListResult struct {
+	Objects []ObjectInfo
+	// CommonPrefixes holds the keys rolled up by a delimiter, each ending in
+	// the delimiter.
+	CommonPrefixes []string
+}
+
+// listBucketResult is a ListObjectsV2 response page.
+type listBucketResult struct {
+	IsTruncated           bool
+	NextContinuationToken string
+	Contents              []struct {
+		Key          string
+		Size         int64
+		LastModified time.Time
+		ETag         string
 	}
-	payloadHash := sha256.New()
-	if _, err := io.Copy(payloadHash, reader); err != nil {
-		return fmt.Errorf("failed to hash payload: %w", err)
+	CommonPrefixes []struct {
+		Prefix string
 	}
-	payloadHashStr := hex.EncodeToString(payloadHash.Sum(nil))
+}
 
-	// Reset reader
-	if _, err := reader.Seek(0, 0); err != nil {
-		return fmt.Errorf("failed to rewind reader: %w", err)
+// ListObjectsV2 lists the objects in bucket whose keys start with prefix,
+// following continuation tokens until every page has been read. A non-empty
+// delimiter groups keys that contain it after the prefix into CommonPrefixes.
+func (c *S3Client) ListObjectsV2(ctx context.Context, bucket, prefix, delimiter string) (ListResult, error) {
+	var result ListResult
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}}
+		if prefix != "" {
+			query.Set("prefix", prefix)
+		}
+		if delimiter != "" {
+			query.Set("delimiter", delimiter)
+		}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		resp, err := c.do(ctx, http.MethodGet, bucket, "", query, nil, nil)
+		if err != nil {
+			return ListResult{}, err
+		}
+		var page listBucketResult
+		err = xml.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return ListResult{}, fmt.Errorf("failed to decode object listing: %w", err)
+		}
+
+		for _, object := range page.Contents {
+			result.Objects = append(result.Objects, ObjectInfo{
+				Key:          object.Key,
+				Size:         object.Size,
+				LastModified: object.LastModified,
+				ETag:         object.ETag,
+			})
+		}
+		for _, common := range page.CommonPrefixes {
+			result.CommonPrefixes = append(result.CommonPrefixes, common.Prefix)
+		}
+
+		if !page.IsTruncated {
+			return result, nil
+		}
+		if page.NextContinuationToken == "" {
+			return ListResult{}, fmt.Errorf("truncated object listing has no continuation token")
+		}
+		token = page.NextContinuationToken
 	}
+}
+
+// HeadObject describes an object without downloading it.
+func (c *S3Client) HeadObject(ctx context.Context, bucket, key string) (ObjectInfo, error) {
+	resp, err := c.do(ctx, http.MethodHead, bucket, key, nil, nil, nil)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	resp.Body.Close()
+	return objectInfoFromResponse(key, resp)
+}
 
-	// Sign the request
-	if err := c.signRequest(req, bucket, key, payloadHashStr); err != nil {
-		return fmt.Errorf("failed to sign request: %w", err)
+// DeleteObject deletes an object. S3 reports success whether or not the key
+// existed.
+func (c *S3Client) DeleteObject(ctx context.Context, bucket, key string) error {
+	resp, err := c.do(ctx, http.MethodDelete, bucket, key, nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// maxDeleteKeys is the most keys S3 accepts in one DeleteObjects request.
+const maxDeleteKeys = 1000
+
+type deleteRequest struct {
+	XMLName xml.Name `xml:"Delete"`
+	Quiet   bool     `xml:"Quiet"`
+	Objects []struct {
+		Key string `xml:"Key"`
+	} `xml:"Object"`
+}
+
+type deleteResult struct {
+	Errors []struct {
+		Key     string
+		Code    string
+		Message string
+	} `xml:"Error"`
+}
+
+// DeleteObjects deletes keys in batches of up to 1000, returning an error that
+// names every key S3 failed to delete.
+func (c *S3Client) DeleteObjects(ctx context.Context, bucket string, keys []string) error {
+	for start := 0; start < len(keys); start += maxDeleteKeys {
+		batch := keys[start:min(start+maxDeleteKeys, len(keys))]
+
+		request := deleteRequest{Quiet: true}
+		for _, key := range batch {
+			request.Objects = append(request.Objects, struct {
+				Key string `xml:"Key"`
+			}{key})
+		}
+		body, err := xml.Marshal(request)
+		if err != nil {
+			return err
+		}
+
+		// S3 requires a Content-MD5 header on multi-object deletes.
+		sum := md5.Sum(body)
+		headers := http.Header{
+			"Content-Type": {"application/xml"},
+			"Content-Md5":  {base64.StdEncoding.EncodeToString(sum[:])},
+		}
+		resp, err := c.do(ctx, http.MethodPost, bucket, "", url.Values{"delete": {""}}, body, headers)
+		if err != nil {
+			return err
+		}
+		var result deleteResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("failed to decode delete result: %w", err)
+		}
+
+		if len(result.Errors) > 0 {
+			failures := make([]string, 0, len(result.Errors))
+			for _, failure := range result.Errors {
+				failures = append(failures, fmt.Sprintf("%s (%s: %s)", failure.Key, failure.Code, failure.Message))
+			}
+			return fmt.Errorf("failed to delete %d objects: %s", len(failures), strings.Join(failures, ", "))
+		}
+	}
+	return nil
+}
+
+// do sends a request for key in bucket, or for the bucket itself when key is
+// empty, with an in-memory body.
+func (c *S3Client) do(ctx context.Context, method, bucket, key string, query url.Values, body []byte, headers http.Header) (*http.Response, error) {
+	requestURL, err := buildObjectURL(c.EndpointURL, bucket, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request URL: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, requestURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.URL.RawQuery = canonicalQueryString(query.Encode())
+	req.ContentLength = int64(len(body))
+	if body == nil {
+		req.Body = http.NoBody
+	}
+	for name, values := range headers {
+		req.Header[name] = values
+	}
+
+	payloadHash := ""
+	if body != nil {
+		payloadHash = unsignedPayload
+		if !c.UnsignedPayload {
+			sum := sha256.Sum256(body)
+			payloadHash = hex.EncodeToString(sum[:])
+		}
+	}
+	return c.send(req, bucket, key, payloadHash)
+}
+
+// send signs and executes req. A 404 for an object is returned as ErrNotFound
+// and any other non-2xx status as an error; otherwise the caller closes the
+// response body.
+func (c *S3Client) send(req *http.Request, bucket, key, payloadHash string) (*http.Response, error) {
+	if err := c.signRequest(req, bucket, key, payloadHash); err != nil {
+		return nil, fmt.Errorf("failed to sign request: %w", err)
 	}
 
-	// Execute request
 	resp, err := c.client.Do(req)
 	if err != nil {
-		return fmt.Errorf("request failed: %w", err)
+		return nil, fmt.Errorf("request failed: %w", err)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+	if resp.StatusCode == http.StatusNotModified {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w: %s/%s", ErrNotModified, bucket, key)
+	}
+	if resp.StatusCode == http.StatusNotFound && key != "" {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w: %s/%s", ErrNotFound, bucket, key)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("S3 error %d: %s", resp.StatusCode, string(body))
+		resp.Body.Close()
+		return nil, fmt.Errorf("S3 error %d: %s", resp.StatusCode, string(body))
 	}
 
-	return nil
+	return resp, nil
 }
 
 // signRequest signs an HTTP request using AWS Signature Version 4
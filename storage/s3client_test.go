@@ -1,12 +1,29 @@
 package storage
 
 import (
+	"bytes"
 	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 type roundTripFunc func(*http.Request) (*http.Response, error)
@@ -83,7 +100,7 @@ func TestS3ClientGetObject(t *testing.T) {
 	}
 
 	// Test GetObject
-	resp, err := client.GetObject(context.Background(), "test-bucket", "test-key.db")
+	resp, _, err := client.GetObject(context.Background(), "test-bucket", "test-key.db", GetOptions{})
 	if err != nil {
 		t.Fatalf("GetObject failed: %v", err)
 	}
@@ -109,7 +126,7 @@ func TestS3ClientGetObjectNotFound(t *testing.T) {
 		}),
 	}
 
-	_, err := client.GetObject(context.Background(), "test-bucket", "missing.db")
+	_, _, err := client.GetObject(context.Background(), "test-bucket", "missing.db", GetOptions{})
 	if err == nil {
 		t.Error("Expected error for 404 response, got nil")
 	}
@@ -173,7 +190,7 @@ func TestS3ClientPutObject(t *testing.T) {
 	}
 
 	// Test PutObject
-	err = client.PutObject(context.Background(), "test-bucket", "test-upload.db", file, fileInfo.Size())
+	err = client.PutObject(context.Background(), "test-bucket", "test-upload.db", file, fileInfo.Size(), PutOptions{})
 	if err != nil {
 		t.Fatalf("PutObject failed: %v", err)
 	}
@@ -198,7 +215,7 @@ func TestS3ClientPutObjectMissingFile(t *testing.T) {
 
 	// If we got here, the file unexpectedly exists
 	fileInfo, _ := file.Stat()
-	err = client.PutObject(context.Background(), "test-bucket", "test.db", file, fileInfo.Size())
+	err = client.PutObject(context.Background(), "test-bucket", "test.db", file, fileInfo.Size(), PutOptions{})
 	if err == nil {
 		t.Error("Expected test to fail on non-existent file")
 	}
@@ -268,3 +285,581 @@ func TestCanonicalQueryString(t *testing.T) {
 		t.Fatalf("canonical query mismatch: got %q want %q", got, want)
 	}
 }
+
+// fakeS3 is an in-memory, path-style S3 endpoint that checks each request's
+// SigV4 signature before serving it.
+type fakeS3 struct {
+	t        *testing.T
+	client   *S3Client
+	pageSize int
+	objects  map[string][]byte
+	metadata map[string]http.Header
+	modified time.Time
+	requests []string
+
+	mu       sync.Mutex
+	uploads  map[string]map[int][]byte
+	aborted  []string
+	failPart int
+	inFlight atomic.Int32
+	maxParts atomic.Int32
+}
+
+func newFakeS3(t *testing.T) (*fakeS3, *S3Client) {
+	fake := &fakeS3{
+		t:        t,
+		pageSize: 2,
+		objects:  make(map[string][]byte),
+		metadata: make(map[string]http.Header),
+		uploads:  make(map[string]map[int][]byte),
+		modified: time.Date(2025, 6, 1, 14, 0, 0, 0, time.UTC),
+	}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	fake.client = NewS3Client("test-access-key", "test-secret-key", "us-east-1", server.URL)
+	return fake, fake.client
+}
+
+func (f *fakeS3) etag(key string) string {
+	sum := md5.Sum(f.objects[key])
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	if err := f.verifySignature(r, body); err != nil {
+		f.t.Errorf("%s %s: %v", r.Method, r.URL, err)
+		http.Error(w, "SignatureDoesNotMatch", http.StatusForbidden)
+		return
+	}
+
+	query := r.URL.Query()
+	if query.Has("partNumber") {
+		// Hold each part briefly outside the lock so concurrent uploads
+		// overlap.
+		inFlight := f.inFlight.Add(1)
+		defer f.inFlight.Add(-1)
+		for {
+			seen := f.maxParts.Load()
+			if inFlight <= seen || f.maxParts.CompareAndSwap(seen, inFlight) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, r.Method+" "+r.URL.RequestURI())
+
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != "test-bucket" {
+		http.Error(w, "NoSuchBucket", http.StatusNotFound)
+		return
+	}
+
+	switch {
+	case key != "" && (query.Has("uploads") || query.Has("uploadId")):
+		f.multipart(w, r, key, body)
+	case key == "" && r.Method == http.MethodGet && query.Get("list-type") == "2":
+		f.list(w, query)
+	case key == "" && r.Method == http.MethodPost && query.Has("delete"):
+		f.deleteObjects(w, r, body)
+	case r.Method == http.MethodPut:
+		f.objects[key] = body
+		f.metadata[key] = metadataFromRequest(r)
+		w.Header().Set("ETag", f.etag(key))
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		data, ok := f.objects[key]
+		if !ok {
+			http.Error(w, "NoSuchKey", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("ETag", f.etag(key))
+		if r.Header.Get("If-None-Match") == f.etag(key) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		for name, values := range f.metadata[key] {
+			w.Header()[name] = values
+		}
+		w.Header().Set("Last-Modified", f.modified.Format(http.TimeFormat))
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		if r.Method == http.MethodGet {
+			w.Write(data)
+		}
+	case r.Method == http.MethodDelete:
+		delete(f.objects, key)
+		delete(f.metadata, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "NotImplemented", http.StatusNotImplemented)
+	}
+}
+
+// metadataFromRequest returns the headers of r that S3 stores with an object.
+func metadataFromRequest(r *http.Request) http.Header {
+	metadata := make(http.Header)
+	for name, values := range r.Header {
+		if strings.HasPrefix(strings.ToLower(name), metadataHeaderPrefix) || name == "Content-Encoding" {
+			metadata[name] = values
+		}
+	}
+	return metadata
+}
+
+// list serves ListObjectsV2, using the last key of a page as its continuation
+// token.
+func (f *fakeS3) list(w http.ResponseWriter, query url.Values) {
+	prefix, delimiter := query.Get("prefix"), query.Get("delimiter")
+	keys := make([]string, 0, len(f.objects))
+	for key := range f.objects {
+		if strings.HasPrefix(key, prefix) && key > query.Get("continuation-token") {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var result listBucketResult
+	seenPrefixes := make(map[string]bool)
+	for i, key := range keys {
+		if i == f.pageSize {
+			result.IsTruncated = true
+			result.NextContinuationToken = keys[i-1]
+			break
+		}
+		if delimiter != "" {
+			if before, _, found := strings.Cut(strings.TrimPrefix(key, prefix), delimiter); found {
+				common := prefix + before + delimiter
+				if !seenPrefixes[common] {
+					seenPrefixes[common] = true
+					result.CommonPrefixes = append(result.CommonPrefixes, struct{ Prefix string }{common})
+				}
+				continue
+			}
+		}
+		result.Contents = append(result.Contents, struct {
+			Key          string
+			Size         int64
+			LastModified time.Time
+			ETag         string
+		}{key, int64(len(f.objects[key])), f.modified, f.etag(key)})
+	}
+
+	w.Header().Set("Content-Type", "application/xml")
+	xml.NewEncoder(w).Encode(struct {
+		XMLName xml.Name `xml:"ListBucketResult"`
+		listBucketResult
+	}{listBucketResult: result})
+}
+
+func (f *fakeS3) deleteObjects(w http.ResponseWriter, r *http.Request, body []byte) {
+	sum := md5.Sum(body)
+	if r.Header.Get("Content-MD5") != base64.StdEncoding.EncodeToString(sum[:]) {
+		http.Error(w, "InvalidDigest", http.StatusBadRequest)
+		return
+	}
+	var request deleteRequest
+	if err := xml.Unmarshal(body, &request); err != nil {
+		http.Error(w, "MalformedXML", http.StatusBadRequest)
+		return
+	}
+
+	var result deleteResult
+	for _, object := range request.Objects {
+		if strings.HasPrefix(object.Key, "locked/") {
+			result.Errors = append(result.Errors, struct {
+				Key     string
+				Code    string
+				Message string
+			}{object.Key, "AccessDenied", "Access Denied"})
+			continue
+		}
+		delete(f.objects, object.Key)
+	}
+	xml.NewEncoder(w).Encode(struct {
+		XMLName xml.Name `xml:"DeleteResult"`
+		deleteResult
+	}{deleteResult: result})
+}
+
+// multipart serves the multipart upload calls. Completing an upload checks
+// that every part is listed in order with the ETag it was given.
+func (f *fakeS3) multipart(w http.ResponseWriter, r *http.Request, key string, body []byte) {
+	query := r.URL.Query()
+	uploadID := query.Get("uploadId")
+	switch {
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		uploadID = fmt.Sprintf("upload-%d", len(f.uploads)+len(f.aborted)+1)
+		f.uploads[uploadID] = make(map[int][]byte)
+		f.metadata[uploadID] = metadataFromRequest(r)
+		fmt.Fprintf(w, "<InitiateMultipartUploadResult><Key>%s</Key><UploadId>%s</UploadId></InitiateMultipartUploadResult>", key, uploadID)
+		return
+	case f.uploads[uploadID] == nil:
+		http.Error(w, "NoSuchUpload", http.StatusNotFound)
+		return
+	}
+
+	parts := f.uploads[uploadID]
+	switch r.Method {
+	case http.MethodPut:
+		number, _ := strconv.Atoi(query.Get("partNumber"))
+		if number == f.failPart {
+			http.Error(w, "InternalError", http.StatusInternalServerError)
+			return
+		}
+		parts[number] = body
+		sum := md5.Sum(body)
+		w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:])+`"`)
+	case http.MethodPost:
+		var request completeMultipartUpload
+		if err := xml.Unmarshal(body, &request); err != nil || len(request.Parts) != len(parts) {
+			http.Error(w, "InvalidPart", http.StatusBadRequest)
+			return
+		}
+		var object []byte
+		for i, part := range request.Parts {
+			sum := md5.Sum(parts[part.PartNumber])
+			if part.PartNumber != i+1 || part.ETag != `"`+hex.EncodeToString(sum[:])+`"` {
+				// S3 reports some completion failures in a 200 response.
+				fmt.Fprint(w, "<Error><Code>InvalidPartOrder</Code><Message>parts out of order</Message></Error>")
+				return
+			}
+			object = append(object, parts[part.PartNumber]...)
+		}
+		f.objects[key] = object
+		f.metadata[key] = f.metadata[uploadID]
+		delete(f.uploads, uploadID)
+		fmt.Fprintf(w, "<CompleteMultipartUploadResult><Key>%s</Key></CompleteMultipartUploadResult>", key)
+	case http.MethodDelete:
+		delete(f.uploads, uploadID)
+		f.aborted = append(f.aborted, uploadID)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// verifySignature recomputes the SigV4 signature from the request as received.
+func (f *fakeS3) verifySignature(r *http.Request, body []byte) error {
+	auth := r.Header.Get("Authorization")
+	var credential, signedHeaders, signature string
+	for _, part := range strings.Split(strings.TrimPrefix(auth, "AWS4-HMAC-SHA256 "), ", ") {
+		name, value, _ := strings.Cut(part, "=")
+		switch name {
+		case "Credential":
+			credential = value
+		case "SignedHeaders":
+			signedHeaders = value
+		case "Signature":
+			signature = value
+		}
+	}
+	if credential == "" || signedHeaders == "" || signature == "" {
+		return fmt.Errorf("malformed Authorization header %q", auth)
+	}
+
+	payloadHash := r.Header.Get("X-Amz-Content-Sha256")
+	if sum := sha256.Sum256(body); payloadHash != hex.EncodeToString(sum[:]) && payloadHash != unsignedPayload {
+		return fmt.Errorf("payload hash %s does not match the body", payloadHash)
+	}
+
+	var headers []string
+	for _, name := range strings.Split(signedHeaders, ";") {
+		value := r.Header.Get(name)
+		if name == "host" {
+			value = r.Host
+		}
+		headers = append(headers, name+":"+strings.TrimSpace(value))
+	}
+
+	canonicalRequest := strings.Join([]string{
+		r.Method,
+		r.URL.EscapedPath(),
+		canonicalQueryString(r.URL.RawQuery),
+		strings.Join(headers, "\n") + "\n",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	timeStamp := r.Header.Get("X-Amz-Date")
+	dateStamp := timeStamp[:8]
+	hashed := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		timeStamp,
+		fmt.Sprintf("%s/%s/s3/aws4_request", dateStamp, f.client.Region),
+		hex.EncodeToString(hashed[:]),
+	}, "\n")
+	if want := f.client.calculateSignature(dateStamp, stringToSign); signature != want {
+		return fmt.Errorf("signature mismatch for canonical request:\n%s", canonicalRequest)
+	}
+	return nil
+}
+
+func TestS3ClientListObjectsV2(t *testing.T) {
+	fake, client := newFakeS3(t)
+	for _, key := range []string{
+		"snapshots/2025/06/01T1400Z.db.zst",
+		"snapshots/2025/06/02T1400Z.db.zst",
+		"snapshots/2025/07/01T1400Z.db.zst",
+		"snapshots/latest",
+		"raw/2025/06/01/1400.csv.gz",
+	} {
+		fake.objects[key] = []byte(key)
+	}
+	ctx := context.Background()
+
+	result, err := client.ListObjectsV2(ctx, "test-bucket", "snapshots/", "")
+	if err != nil {
+		t.Fatalf("ListObjectsV2 returned error: %v", err)
+	}
+	var keys []string
+	for _, object := range result.Objects {
+		keys = append(keys, object.Key)
+	}
+	want := []string{
+		"snapshots/2025/06/01T1400Z.db.zst",
+		"snapshots/2025/06/02T1400Z.db.zst",
+		"snapshots/2025/07/01T1400Z.db.zst",
+		"snapshots/latest",
+	}
+	if !reflect.DeepEqual(keys, want) {
+		t.Errorf("unexpected keys %v", keys)
+	}
+	first := result.Objects[0]
+	if first.Size != int64(len(first.Key)) || first.ETag != fake.etag(first.Key) || !first.LastModified.Equal(fake.modified) {
+		t.Errorf("unexpected object info %+v", first)
+	}
+	if len(fake.requests) != 2 {
+		t.Errorf("expected two pages, got requests %v", fake.requests)
+	}
+
+	result, err = client.ListObjectsV2(ctx, "test-bucket", "snapshots/2025/", "/")
+	if err != nil {
+		t.Fatalf("ListObjectsV2 returned error: %v", err)
+	}
+	if len(result.Objects) != 0 || !reflect.DeepEqual(result.CommonPrefixes, []string{"snapshots/2025/06/", "snapshots/2025/07/"}) {
+		t.Errorf("unexpected delimited listing %+v", result)
+	}
+
+	if _, err := client.ListObjectsV2(ctx, "other-bucket", "", ""); err == nil {
+		t.Error("expected an error listing a missing bucket")
+	}
+}
+
+func TestS3ClientHeadObject(t *testing.T) {
+	fake, client := newFakeS3(t)
+	fake.objects["folder/a b.db"] = []byte("database")
+
+	info, err := client.HeadObject(context.Background(), "test-bucket", "folder/a b.db")
+	if err != nil {
+		t.Fatalf("HeadObject returned error: %v", err)
+	}
+	want := ObjectInfo{Key: "folder/a b.db", Size: 8, LastModified: fake.modified, ETag: fake.etag("folder/a b.db")}
+	if !reflect.DeepEqual(info, want) {
+		t.Errorf("expected %+v, got %+v", want, info)
+	}
+
+	if _, err := client.HeadObject(context.Background(), "test-bucket", "missing.db"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestS3ClientDeleteObjects(t *testing.T) {
+	fake, client := newFakeS3(t)
+	ctx := context.Background()
+	for _, key := range []string{"a.db", "b.db", "c.db", "locked/d.db"} {
+		fake.objects[key] = []byte(key)
+	}
+
+	if err := client.DeleteObject(ctx, "test-bucket", "a.db"); err != nil {
+		t.Fatalf("DeleteObject returned error: %v", err)
+	}
+	if err := client.DeleteObject(ctx, "test-bucket", "a.db"); err != nil {
+		t.Errorf("expected deleting a missing key to succeed, got %v", err)
+	}
+
+	if err := client.DeleteObjects(ctx, "test-bucket", []string{"b.db", "c.db"}); err != nil {
+		t.Fatalf("DeleteObjects returned error: %v", err)
+	}
+	if len(fake.objects) != 1 {
+		t.Errorf("expected only the locked object to remain, got %d objects", len(fake.objects))
+	}
+
+	err := client.DeleteObjects(ctx, "test-bucket", []string{"locked/d.db"})
+	if err == nil || !strings.Contains(err.Error(), "locked/d.db (AccessDenied") {
+		t.Errorf("expected the failed key to be reported, got %v", err)
+	}
+}
+
+func TestS3Backend(t *testing.T) {
+	fake, client := newFakeS3(t)
+	fake.objects["outside.db"] = []byte("not under the prefix")
+	testBackend(t, NewS3Backend(client, "test-bucket", "history"))
+
+	if _, ok := fake.objects["history/oaamonitor.db"]; !ok {
+		t.Error("expected keys to be stored under the backend prefix")
+	}
+	if _, ok := fake.objects["outside.db"]; !ok {
+		t.Error("expected objects outside the prefix to be left alone")
+	}
+}
+
+func TestS3ClientPutObjectMultipart(t *testing.T) {
+	for _, unsigned := range []bool{false, true} {
+		fake, client := newFakeS3(t)
+		client.PartSize = 1024
+		client.Concurrency = 3
+		client.UnsignedPayload = unsigned
+
+		data := make([]byte, 5*1024+100)
+		for i := range data {
+			data[i] = byte(i * 7)
+		}
+		if err := client.PutObject(context.Background(), "test-bucket", "snapshots/big.db.zst", bytes.NewReader(data), int64(len(data)), PutOptions{}); err != nil {
+			t.Fatalf("PutObject (unsigned=%v) returned error: %v", unsigned, err)
+		}
+
+		if !bytes.Equal(fake.objects["snapshots/big.db.zst"], data) {
+			t.Errorf("unsigned=%v: assembled object does not match the upload", unsigned)
+		}
+		parts := 0
+		for _, request := range fake.requests {
+			if strings.Contains(request, "partNumber=") {
+				parts++
+			}
+		}
+		if parts != 6 {
+			t.Errorf("unsigned=%v: expected 6 parts, got requests %v", unsigned, fake.requests)
+		}
+		if peak := fake.maxParts.Load(); peak < 2 || peak > 3 {
+			t.Errorf("unsigned=%v: expected 2-3 parts in flight at once, got %d", unsigned, peak)
+		}
+	}
+}
+
+func TestS3ClientPutObjectMultipartAbort(t *testing.T) {
+	fake, client := newFakeS3(t)
+	client.PartSize = 1024
+
+	data := bytes.Repeat([]byte("x"), 4*1024)
+	err := client.PutObject(context.Background(), "test-bucket", "big.db", bytes.NewReader(data), int64(len(data)), PutOptions{})
+	if err != nil {
+		t.Fatalf("PutObject returned error: %v", err)
+	}
+
+	fake.failPart = 3
+	err = client.PutObject(context.Background(), "test-bucket", "broken.db", bytes.NewReader(data), int64(len(data)), PutOptions{})
+	if err == nil || !strings.Contains(err.Error(), "part 3") {
+		t.Fatalf("expected part 3 to fail, got %v", err)
+	}
+	if _, ok := fake.objects["broken.db"]; ok {
+		t.Error("expected no object from a failed upload")
+	}
+	if len(fake.aborted) != 1 || len(fake.uploads) != 0 {
+		t.Errorf("expected the failed upload to be aborted, got aborted=%v open=%d", fake.aborted, len(fake.uploads))
+	}
+}
+
+func TestS3ClientPutObjectUnsignedPayload(t *testing.T) {
+	fake, client := newFakeS3(t)
+	client.UnsignedPayload = true
+
+	var hashes []string
+	client.client.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		hashes = append(hashes, req.Header.Get("X-Amz-Content-Sha256"))
+		return http.DefaultTransport.RoundTrip(req)
+	})
+	if err := client.PutObject(context.Background(), "test-bucket", "small.db", strings.NewReader("database"), 8, PutOptions{}); err != nil {
+		t.Fatalf("PutObject returned error: %v", err)
+	}
+	if string(fake.objects["small.db"]) != "database" {
+		t.Errorf("unexpected object %q", fake.objects["small.db"])
+	}
+	if len(hashes) != 1 || hashes[0] != unsignedPayload {
+		t.Errorf("expected a single unsigned request, got %v", hashes)
+	}
+}
+
+func TestS3ClientGetObjectConditional(t *testing.T) {
+	fake, client := newFakeS3(t)
+	ctx := context.Background()
+	metadata := map[string]string{"sha256": "abc123"}
+	if err := client.PutObject(ctx, "test-bucket", "oaamonitor.db", strings.NewReader("database"), 8, PutOptions{Metadata: metadata}); err != nil {
+		t.Fatalf("PutObject returned error: %v", err)
+	}
+
+	body, info, err := client.GetObject(ctx, "test-bucket", "oaamonitor.db", GetOptions{})
+	if err != nil {
+		t.Fatalf("GetObject returned error: %v", err)
+	}
+	body.Close()
+	if info.ETag != fake.etag("oaamonitor.db") || info.Size != 8 || !reflect.DeepEqual(info.Metadata, metadata) {
+		t.Errorf("unexpected object info %+v", info)
+	}
+
+	if _, _, err := client.GetObject(ctx, "test-bucket", "oaamonitor.db", GetOptions{IfNoneMatch: info.ETag}); !errors.Is(err, ErrNotModified) {
+		t.Errorf("expected ErrNotModified for the current ETag, got %v", err)
+	}
+	body, _, err = client.GetObject(ctx, "test-bucket", "oaamonitor.db", GetOptions{IfNoneMatch: `"stale"`})
+	if err != nil {
+		t.Fatalf("GetObject with a stale ETag returned error: %v", err)
+	}
+	body.Close()
+}
+
+func TestS3ClientPutObjectMultipartMetadata(t *testing.T) {
+	fake, client := newFakeS3(t)
+	client.PartSize = 1024
+	data := bytes.Repeat([]byte("x"), 3*1024)
+	metadata := map[string]string{"sha256": "abc123"}
+	if err := client.PutObject(context.Background(), "test-bucket", "big.db", bytes.NewReader(data), int64(len(data)), PutOptions{Metadata: metadata}); err != nil {
+		t.Fatalf("PutObject returned error: %v", err)
+	}
+	info, err := client.HeadObject(context.Background(), "test-bucket", "big.db")
+	if err != nil {
+		t.Fatalf("HeadObject returned error: %v", err)
+	}
+	if !reflect.DeepEqual(info.Metadata, metadata) {
+		t.Errorf("expected metadata %v on the assembled object, got %v", metadata, info.Metadata)
+	}
+	if len(fake.objects["big.db"]) != len(data) {
+		t.Errorf("unexpected object size %d", len(fake.objects["big.db"]))
+	}
+}
+
+func TestS3BackendCompressedSnapshots(t *testing.T) {
+	_, client := newFakeS3(t)
+	backend := NewS3Backend(client, "test-bucket", "")
+	ctx := context.Background()
+	dir := t.TempDir()
+	source := filepath.Join(dir, "source.db")
+	contents := bytes.Repeat([]byte("SQLite format 3\x00"), 100)
+	if err := os.WriteFile(source, contents, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	at := time.Date(2025, 6, 1, 14, 0, 0, 0, time.UTC)
+	for _, compression := range []Compression{CompressionZstd, CompressionGzip, CompressionNone} {
+		at = at.Add(time.Hour)
+		key, err := UploadDatabase(ctx, backend, source, at, compression)
+		if err != nil {
+			t.Fatalf("UploadDatabase(%s) returned error: %v", compression, err)
+		}
+		info, err := backend.Stat(ctx, key)
+		if err != nil {
+			t.Fatalf("Stat returned error: %v", err)
+		}
+		if info.ContentEncoding != compression.ContentEncoding() || info.Metadata["uncompressed-size"] != strconv.Itoa(len(contents)) {
+			t.Errorf("%s: unexpected object info %+v", compression, info)
+		}
+
+		// gzip responses are decoded by the HTTP client on the way, zstd
+		// ones by DownloadDatabase.
+		target := filepath.Join(dir, string(compression)+".db")
+		if err := DownloadDatabase(ctx, backend, target); err != nil {
+			t.Fatalf("DownloadDatabase(%s) returned error: %v", compression, err)
+		}
+		if data, _ := os.ReadFile(target); !bytes.Equal(data, contents) {
+			t.Errorf("%s: downloaded database does not match the upload", compression)
+		}
+	}
+}
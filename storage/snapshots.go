@@ -0,0 +1,376 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// SnapshotPrefix is the key prefix every uploaded database version is
+	// stored under.
+	SnapshotPrefix = "snapshots/"
+	// LatestSnapshotKey holds the key of the most recently uploaded snapshot.
+	LatestSnapshotKey = SnapshotPrefix + "latest"
+
+	// sha256MetadataKey names the metadata holding the hex SHA-256 of the
+	// uncompressed database, and sizeMetadataKey its size in bytes.
+	sha256MetadataKey = "sha256"
+	sizeMetadataKey   = "uncompressed-size"
+
+	snapshotLayout = "2006/01/02T1504Z"
+)
+
+// Snapshot is one database version in object storage.
+type Snapshot struct {
+	Key  string
+	Time time.Time
+	Size int64
+}
+
+// SnapshotKey returns the key a database uploaded at t with compression is
+// stored under, such as snapshots/2025/06/01T1400Z.db.zst. Keys use UTC so
+// they sort by time.
+func SnapshotKey(t time.Time, compression Compression) string {
+	return SnapshotPrefix + t.UTC().Format(snapshotLayout) + ".db" + compression.Extension()
+}
+
+// parseSnapshotKey reports the upload time encoded in a snapshot key.
+func parseSnapshotKey(key string) (time.Time, bool) {
+	name, ok := strings.CutPrefix(key, SnapshotPrefix)
+	if !ok {
+		return time.Time{}, false
+	}
+	for _, compression := range []Compression{CompressionZstd, CompressionGzip} {
+		name = strings.TrimSuffix(name, compression.Extension())
+	}
+	name, ok = strings.CutSuffix(name, ".db")
+	if !ok {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(snapshotLayout, name)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// ListSnapshots returns every snapshot in backend, oldest first.
+func ListSnapshots(ctx context.Context, backend Backend) ([]Snapshot, error) {
+	objects, err := backend.List(ctx, SnapshotPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshots []Snapshot
+	for _, object := range objects {
+		t, ok := parseSnapshotKey(object.Key)
+		if !ok {
+			continue
+		}
+		snapshots = append(snapshots, Snapshot{Key: object.Key, Time: t, Size: object.Size})
+	}
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].Time.Before(snapshots[j].Time) })
+	return snapshots, nil
+}
+
+// latestSnapshot returns the key the latest pointer names.
+func latestSnapshot(ctx context.Context, backend Backend) (string, error) {
+	resp, _, err := backend.Get(ctx, LatestSnapshotKey, GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	defer resp.Close()
+
+	data, err := io.ReadAll(resp)
+	if err != nil {
+		return "", err
+	}
+	key := strings.TrimSpace(string(data))
+	if _, ok := parseSnapshotKey(key); !ok {
+		return "", fmt.Errorf("%s names an invalid snapshot %q", LatestSnapshotKey, key)
+	}
+	return key, nil
+}
+
+// DownloadDatabase replaces the database at dbPath with the latest snapshot in
+// backend. Buckets written before snapshots existed fall back to the single
+// DatabaseKey object.
+func DownloadDatabase(ctx context.Context, backend Backend, dbPath string) error {
+	key, err := latestSnapshot(ctx, backend)
+	if errors.Is(err, ErrNotFound) {
+		log.Printf("No %s pointer found; downloading %s", LatestSnapshotKey, DatabaseKey)
+		return downloadObject(ctx, backend, DatabaseKey, dbPath)
+	}
+	if err != nil {
+		log.Printf("Failed to read latest snapshot pointer: %v", err)
+		return err
+	}
+	return downloadObject(ctx, backend, key, dbPath)
+}
+
+// DownloadDatabaseAt replaces the database at dbPath with the last snapshot
+// uploaded on or before date, a UTC calendar day.
+func DownloadDatabaseAt(ctx context.Context, backend Backend, dbPath string, date time.Time) (Snapshot, error) {
+	snapshots, err := ListSnapshots(ctx, backend)
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+
+	end := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, 1)
+	i := sort.Search(len(snapshots), func(i int) bool { return !snapshots[i].Time.Before(end) })
+	if i == 0 {
+		return Snapshot{}, fmt.Errorf("%w: no snapshot on or before %s", ErrNotFound, date.Format("2006-01-02"))
+	}
+
+	snapshot := snapshots[i-1]
+	return snapshot, downloadObject(ctx, backend, snapshot.Key, dbPath)
+}
+
+// downloadObject writes the object at key to dbPath through a temporary file,
+// decompressing gzip and zstd snapshots on the way. The object's ETag is recorded in
+// a file next to dbPath, and a later download of an object that still has
+// that ETag leaves dbPath alone unless it has changed locally. When the object carries a sha256 metadata value,
+// the database is checked against it before it replaces dbPath.
+func downloadObject(ctx context.Context, backend Backend, key, dbPath string) error {
+	opts := GetOptions{IfNoneMatch: readETag(dbPath)}
+
+	resp, info, err := backend.Get(ctx, key, opts)
+	if errors.Is(err, ErrNotModified) {
+		log.Printf("%s is unchanged since the last download; keeping %s", key, dbPath)
+		return nil
+	}
+	if err != nil {
+		log.Printf("Failed to download database file: %v", err)
+		return err
+	}
+	defer resp.Close()
+
+	body, err := decompressingReader(resp)
+	if err != nil {
+		log.Printf("Failed to read database file: %v", err)
+		return err
+	}
+	defer body.Close()
+
+	tmpPath := dbPath + ".tmp"
+	defer os.Remove(tmpPath)
+
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		log.Printf("Failed to create temporary database file: %v", err)
+		return err
+	}
+
+	hash := sha256.New()
+	if _, err = io.Copy(io.MultiWriter(file, hash), body); err != nil {
+		file.Close()
+		log.Printf("Failed to save database file: %v", err)
+		return err
+	}
+	if err = file.Sync(); err != nil {
+		file.Close()
+		log.Printf("Failed to sync database file: %v", err)
+		return err
+	}
+	if err = file.Close(); err != nil {
+		log.Printf("Failed to close database file: %v", err)
+		return err
+	}
+
+	if want, ok := info.Metadata[sha256MetadataKey]; ok {
+		if got := hex.EncodeToString(hash.Sum(nil)); got != want {
+			return fmt.Errorf("downloaded %s has SHA-256 %s, but it was uploaded with %s", key, got, want)
+		}
+	} else {
+		log.Printf("%s has no %s metadata; skipping checksum verification", key, sha256MetadataKey)
+	}
+
+	// Drop the old ETag before the rename so a failed write below can only
+	// cause a redundant download, never a skipped one.
+	if err := os.Remove(etagPath(dbPath)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if err := os.Rename(tmpPath, dbPath); err != nil {
+		return err
+	}
+	if info.ETag != "" {
+		if err := writeETag(dbPath, info.ETag); err != nil {
+			log.Printf("Failed to record ETag for %s: %v", dbPath, err)
+		}
+	}
+	return nil
+}
+
+// downloadRecord is stored next to a downloaded database. The size and
+// modification time detect local changes since the download, which make the
+// ETag meaningless.
+type downloadRecord struct {
+	ETag     string `json:"etag"`
+	Size     int64  `json:"size"`
+	Modified int64  `json:"modified_unix_nano"`
+}
+
+// etagPath returns the file that records the ETag dbPath was downloaded with.
+func etagPath(dbPath string) string {
+	return dbPath + ".etag.json"
+}
+
+// readETag returns the ETag dbPath was downloaded with, or "" when there is no
+// record or the file has changed since.
+func readETag(dbPath string) string {
+	info, err := os.Stat(dbPath)
+	if err != nil {
+		return ""
+	}
+	data, err := os.ReadFile(etagPath(dbPath))
+	if err != nil {
+		return ""
+	}
+	var record downloadRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return ""
+	}
+	if record.Size != info.Size() || record.Modified != info.ModTime().UnixNano() {
+		return ""
+	}
+	return record.ETag
+}
+
+// writeETag records the ETag dbPath was just downloaded with.
+func writeETag(dbPath, etag string) error {
+	info, err := os.Stat(dbPath)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(downloadRecord{ETag: etag, Size: info.Size(), Modified: info.ModTime().UnixNano()})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(etagPath(dbPath), data, 0o644)
+}
+
+// UploadDatabase compresses the SQLite database at dbPath into a new snapshot
+// keyed by at, then points LatestSnapshotKey at it. The compression is stored
+// as the object's content encoding, and the SHA-256 and size of the
+// uncompressed database as metadata for downloads to verify. Earlier snapshots
+// are left in place for PruneSnapshots. It returns the new snapshot's key.
+func UploadDatabase(ctx context.Context, backend Backend, dbPath string, at time.Time, compression Compression) (string, error) {
+	dir, err := os.MkdirTemp("", "oaamonitor-snapshot")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+
+	compressedPath := filepath.Join(dir, "oaamonitor.db"+compression.Extension())
+	compressed, err := CompressFile(dbPath, compressedPath, compression)
+	if err != nil {
+		log.Printf("Failed to compress database file: %v", err)
+		return "", err
+	}
+	if compression != CompressionNone {
+		log.Printf("Compressed database with %s from %d to %d bytes", compression, compressed.SourceSize, compressed.Size)
+	}
+
+	file, err := os.Open(compressedPath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	key := SnapshotKey(at, compression)
+	opts := PutOptions{
+		ContentEncoding: compression.ContentEncoding(),
+		Metadata: map[string]string{
+			sha256MetadataKey: compressed.SHA256,
+			sizeMetadataKey:   strconv.FormatInt(compressed.SourceSize, 10),
+		},
+	}
+	if err := backend.Put(ctx, key, file, compressed.Size, opts); err != nil {
+		log.Printf("Failed to upload database file: %v", err)
+		return "", err
+	}
+
+	// The pointer is written last so a failed upload never leaves it naming a
+	// missing or partial snapshot.
+	if err := backend.Put(ctx, LatestSnapshotKey, strings.NewReader(key), int64(len(key)), PutOptions{}); err != nil {
+		log.Printf("Failed to update latest snapshot pointer: %v", err)
+		return "", err
+	}
+
+	return key, nil
+}
+
+// recentSnapshotWindow is how far back every snapshot is kept, so uploads made
+// earlier in the day survive as rollback points.
+const recentSnapshotWindow = 24 * time.Hour
+
+// ExpiredSnapshots applies the retention policy to snapshots: every snapshot
+// from the last recentSnapshotWindow is kept, then the last snapshot of each UTC
+// day for the dailyDays days before today, and the last snapshot of each ISO
+// week before that. The snapshot named latest is always kept. It returns the
+// snapshots to delete, oldest first.
+func ExpiredSnapshots(snapshots []Snapshot, latest string, now time.Time, dailyDays int) []Snapshot {
+	today := now.UTC()
+	recent := today.Add(-recentSnapshotWindow)
+	cutoff := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, -dailyDays)
+
+	// Walk newest first so the first snapshot seen in a period is the one kept.
+	sorted := append([]Snapshot(nil), snapshots...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Time.After(sorted[j].Time) })
+
+	kept := make(map[string]bool)
+	var expired []Snapshot
+	for _, snapshot := range sorted {
+		t := snapshot.Time.UTC()
+		period := t.Format("day 2006-01-02")
+		if t.Before(cutoff) {
+			year, week := t.ISOWeek()
+			period = fmt.Sprintf("week %d-%02d", year, week)
+		}
+		if kept[period] && snapshot.Key != latest && t.Before(recent) {
+			expired = append(expired, snapshot)
+			continue
+		}
+		kept[period] = true
+	}
+
+	sort.Slice(expired, func(i, j int) bool { return expired[i].Time.Before(expired[j].Time) })
+	return expired
+}
+
+// PruneSnapshots deletes the snapshots ExpiredSnapshots selects, or only
+// reports them when dryRun is set.
+func PruneSnapshots(ctx context.Context, backend Backend, now time.Time, dailyDays int, dryRun bool) ([]Snapshot, error) {
+	snapshots, err := ListSnapshots(ctx, backend)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+	latest, err := latestSnapshot(ctx, backend)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+
+	expired := ExpiredSnapshots(snapshots, latest, now, dailyDays)
+	if dryRun {
+		return expired, nil
+	}
+	for i, snapshot := range expired {
+		if err := backend.Delete(ctx, snapshot.Key); err != nil && !errors.Is(err, ErrNotFound) {
+			return expired[:i], fmt.Errorf("failed to delete %s: %w", snapshot.Key, err)
+		}
+	}
+	return expired, nil
+}
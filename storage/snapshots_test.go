@@ -0,0 +1,307 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestSnapshotKey(t *testing.T) {
+	at := time.Date(2025, 6, 1, 10, 0, 0, 0, time.FixedZone("EDT", -4*60*60))
+	key := SnapshotKey(at, CompressionZstd)
+	if key != "snapshots/2025/06/01T1400Z.db.zst" {
+		t.Errorf("unexpected key %q", key)
+	}
+	parsed, ok := parseSnapshotKey(key)
+	if !ok || !parsed.Equal(at) {
+		t.Errorf("parseSnapshotKey(%q) = %v, %v", key, parsed, ok)
+	}
+	for _, invalid := range []string{LatestSnapshotKey, "oaamonitor.db", "snapshots/2025/06/01.db.zst"} {
+		if _, ok := parseSnapshotKey(invalid); ok {
+			t.Errorf("parseSnapshotKey(%q): expected no match", invalid)
+		}
+	}
+}
+
+func TestDatabaseRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	backend := NewMemoryBackend()
+	dir := t.TempDir()
+
+	versions := []struct {
+		at       time.Time
+		contents string
+	}{
+		{time.Date(2025, 6, 1, 14, 0, 0, 0, time.UTC), "SQLite format 3\x00 june 1"},
+		{time.Date(2025, 6, 2, 14, 0, 0, 0, time.UTC), "SQLite format 3\x00 june 2"},
+	}
+	for _, version := range versions {
+		source := filepath.Join(dir, "source.db")
+		if err := os.WriteFile(source, []byte(version.contents), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		key, err := UploadDatabase(ctx, backend, source, version.at, CompressionZstd)
+		if err != nil {
+			t.Fatalf("UploadDatabase returned error: %v", err)
+		}
+		if key != SnapshotKey(version.at, CompressionZstd) {
+			t.Errorf("expected key %s, got %s", SnapshotKey(version.at, CompressionZstd), key)
+		}
+	}
+
+	snapshots, err := ListSnapshots(ctx, backend)
+	if err != nil {
+		t.Fatalf("ListSnapshots returned error: %v", err)
+	}
+	if len(snapshots) != 2 || !snapshots[0].Time.Equal(versions[0].at) {
+		t.Fatalf("unexpected snapshots %+v", snapshots)
+	}
+
+	target := filepath.Join(dir, "downloaded.db")
+	if err := DownloadDatabase(ctx, backend, target); err != nil {
+		t.Fatalf("DownloadDatabase returned error: %v", err)
+	}
+	if data, _ := os.ReadFile(target); string(data) != versions[1].contents {
+		t.Errorf("expected the latest snapshot, got %q", data)
+	}
+
+	snapshot, err := DownloadDatabaseAt(ctx, backend, target, time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("DownloadDatabaseAt returned error: %v", err)
+	}
+	if snapshot.Key != SnapshotKey(versions[0].at, CompressionZstd) {
+		t.Errorf("expected the June 1 snapshot, got %s", snapshot.Key)
+	}
+	if data, _ := os.ReadFile(target); string(data) != versions[0].contents {
+		t.Errorf("expected the June 1 contents, got %q", data)
+	}
+
+	_, err = DownloadDatabaseAt(ctx, backend, target, time.Date(2025, 5, 31, 0, 0, 0, 0, time.UTC))
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound before the first snapshot, got %v", err)
+	}
+}
+
+func TestDownloadDatabase_LegacyObject(t *testing.T) {
+	ctx := context.Background()
+	backend := NewMemoryBackend()
+	contents := []byte("SQLite format 3\x00")
+	if err := backend.Put(ctx, DatabaseKey, bytes.NewReader(contents), int64(len(contents)), PutOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	target := filepath.Join(t.TempDir(), "downloaded.db")
+	if err := DownloadDatabase(ctx, backend, target); err != nil {
+		t.Fatalf("DownloadDatabase returned error: %v", err)
+	}
+	if data, _ := os.ReadFile(target); !bytes.Equal(data, contents) {
+		t.Errorf("unexpected downloaded contents %q", data)
+	}
+}
+
+func TestExpiredSnapshots(t *testing.T) {
+	now := time.Date(2025, 8, 1, 12, 0, 0, 0, time.UTC)
+	var snapshots []Snapshot
+	add := func(at time.Time) {
+		snapshots = append(snapshots, Snapshot{Key: SnapshotKey(at, CompressionZstd), Time: at})
+	}
+	// Two uploads a day from June 1 through July 31.
+	for day := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC); day.Month() < time.August; day = day.AddDate(0, 0, 1) {
+		add(day.Add(10 * time.Hour))
+		add(day.Add(22 * time.Hour))
+	}
+	// Three uploads so far today, all inside the recent window.
+	add(now.Add(-10 * time.Hour))
+	add(now.Add(-4 * time.Hour))
+	add(now.Add(-time.Hour))
+	latest := snapshots[len(snapshots)-1].Key
+
+	expired := ExpiredSnapshots(snapshots, latest, now, 30)
+	deleted := make(map[string]bool)
+	for _, snapshot := range expired {
+		deleted[snapshot.Key] = true
+	}
+
+	var kept []string
+	for _, snapshot := range snapshots {
+		if !deleted[snapshot.Key] {
+			kept = append(kept, strings.TrimPrefix(snapshot.Key, SnapshotPrefix))
+		}
+	}
+
+	// Before July 2 only the last upload of each ISO week survives; after it,
+	// the last upload of each day, and every upload of the last 24 hours.
+	want := []string{
+		"2025/06/01T2200Z.db.zst", // week 22 ends on Sunday June 1
+		"2025/06/08T2200Z.db.zst",
+		"2025/06/15T2200Z.db.zst",
+		"2025/06/22T2200Z.db.zst",
+		"2025/06/29T2200Z.db.zst",
+		"2025/07/01T2200Z.db.zst", // the rest of week 27 before the cutoff
+	}
+	for day := 2; day <= 31; day++ {
+		want = append(want, time.Date(2025, 7, day, 22, 0, 0, 0, time.UTC).Format(snapshotLayout)+".db.zst")
+	}
+	want = append(want, "2025/08/01T0200Z.db.zst", "2025/08/01T0800Z.db.zst", "2025/08/01T1100Z.db.zst")
+	if !reflect.DeepEqual(kept, want) {
+		t.Errorf("unexpected snapshots kept:\n got %v\nwant %v", kept, want)
+	}
+
+	// The latest pointer protects its snapshot even when a newer one exists.
+	pinned := snapshots[len(snapshots)-2].Key
+	for _, snapshot := range ExpiredSnapshots(snapshots, pinned, now, 30) {
+		if snapshot.Key == pinned {
+			t.Errorf("expected the latest snapshot %s to be kept", pinned)
+		}
+	}
+}
+
+func TestPruneSnapshots(t *testing.T) {
+	ctx := context.Background()
+	backend := NewMemoryBackend()
+	source := filepath.Join(t.TempDir(), "source.db")
+	if err := os.WriteFile(source, []byte("SQLite format 3\x00"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, at := range []time.Time{
+		time.Date(2025, 6, 2, 14, 0, 0, 0, time.UTC),
+		time.Date(2025, 6, 3, 14, 0, 0, 0, time.UTC),
+		time.Date(2025, 8, 1, 14, 0, 0, 0, time.UTC),
+	} {
+		if _, err := UploadDatabase(ctx, backend, source, at, CompressionZstd); err != nil {
+			t.Fatal(err)
+		}
+	}
+	now := time.Date(2025, 8, 2, 0, 0, 0, 0, time.UTC)
+
+	expired, err := PruneSnapshots(ctx, backend, now, 30, true)
+	if err != nil {
+		t.Fatalf("PruneSnapshots returned error: %v", err)
+	}
+	if len(expired) != 1 || expired[0].Key != "snapshots/2025/06/02T1400Z.db.zst" {
+		t.Fatalf("unexpected expired snapshots %+v", expired)
+	}
+	if snapshots, _ := ListSnapshots(ctx, backend); len(snapshots) != 3 {
+		t.Errorf("expected a dry run to keep every snapshot, got %d", len(snapshots))
+	}
+
+	if _, err := PruneSnapshots(ctx, backend, now, 30, false); err != nil {
+		t.Fatalf("PruneSnapshots returned error: %v", err)
+	}
+	snapshots, _ := ListSnapshots(ctx, backend)
+	if len(snapshots) != 2 || snapshots[0].Key != "snapshots/2025/06/03T1400Z.db.zst" {
+		t.Errorf("unexpected snapshots after pruning %+v", snapshots)
+	}
+	if _, err := backend.Stat(ctx, LatestSnapshotKey); err != nil {
+		t.Errorf("expected the latest pointer to survive pruning: %v", err)
+	}
+}
+
+// countingBackend counts the downloads that returned a body.
+type countingBackend struct {
+	Backend
+	downloads int
+}
+
+func (b *countingBackend) Get(ctx context.Context, key string, opts GetOptions) (io.ReadCloser, ObjectInfo, error) {
+	body, info, err := b.Backend.Get(ctx, key, opts)
+	if err == nil && key != LatestSnapshotKey {
+		b.downloads++
+	}
+	return body, info, err
+}
+
+func TestDownloadDatabase_SkipsUnchanged(t *testing.T) {
+	ctx := context.Background()
+	backend := &countingBackend{Backend: NewMemoryBackend()}
+	dir := t.TempDir()
+	source := filepath.Join(dir, "source.db")
+	target := filepath.Join(dir, "downloaded.db")
+
+	upload := func(contents string, at time.Time) {
+		t.Helper()
+		if err := os.WriteFile(source, []byte(contents), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := UploadDatabase(ctx, backend, source, at, CompressionZstd); err != nil {
+			t.Fatalf("UploadDatabase returned error: %v", err)
+		}
+	}
+	download := func(wantDownloads int, wantContents string) {
+		t.Helper()
+		if err := DownloadDatabase(ctx, backend, target); err != nil {
+			t.Fatalf("DownloadDatabase returned error: %v", err)
+		}
+		if backend.downloads != wantDownloads {
+			t.Errorf("expected %d downloads, got %d", wantDownloads, backend.downloads)
+		}
+		if data, _ := os.ReadFile(target); string(data) != wantContents {
+			t.Errorf("expected %q, got %q", wantContents, data)
+		}
+	}
+
+	upload("SQLite format 3\x00 v1", time.Date(2025, 6, 1, 14, 0, 0, 0, time.UTC))
+	download(1, "SQLite format 3\x00 v1")
+	download(1, "SQLite format 3\x00 v1")
+
+	// A local change invalidates the recorded ETag.
+	if err := os.WriteFile(target, []byte("SQLite format 3\x00 local edit"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	download(2, "SQLite format 3\x00 v1")
+
+	upload("SQLite format 3\x00 v2", time.Date(2025, 6, 2, 14, 0, 0, 0, time.UTC))
+	download(3, "SQLite format 3\x00 v2")
+}
+
+func TestDownloadDatabase_ChecksumMismatch(t *testing.T) {
+	ctx := context.Background()
+	backend := NewMemoryBackend()
+	dir := t.TempDir()
+	source := filepath.Join(dir, "source.db")
+	if err := os.WriteFile(source, []byte("SQLite format 3\x00 uploaded"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	key, err := UploadDatabase(ctx, backend, source, time.Date(2025, 6, 1, 14, 0, 0, 0, time.UTC), CompressionZstd)
+	if err != nil {
+		t.Fatalf("UploadDatabase returned error: %v", err)
+	}
+
+	// Replace the snapshot with different contents under the old checksum.
+	reader, info, err := backend.Get(ctx, key, GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader.Close()
+	var tampered bytes.Buffer
+	encoder, _ := zstd.NewWriter(&tampered)
+	encoder.Write([]byte("SQLite format 3\x00 tampered"))
+	encoder.Close()
+	if err := backend.Put(ctx, key, bytes.NewReader(tampered.Bytes()), int64(tampered.Len()), PutOptions{Metadata: info.Metadata}); err != nil {
+		t.Fatal(err)
+	}
+
+	target := filepath.Join(dir, "downloaded.db")
+	if err := os.WriteFile(target, []byte("SQLite format 3\x00 previous"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	err = DownloadDatabase(ctx, backend, target)
+	if err == nil || !strings.Contains(err.Error(), "SHA-256") {
+		t.Fatalf("expected a checksum error, got %v", err)
+	}
+	if data, _ := os.ReadFile(target); string(data) != "SQLite format 3\x00 previous" {
+		t.Errorf("expected the previous database to be kept, got %q", data)
+	}
+	if _, err := os.Stat(target + ".tmp"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected the temporary file to be removed, got %v", err)
+	}
+}
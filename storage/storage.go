@@ -6,6 +6,8 @@ import (
 	"io"
 	"log"
 	"os"
+
+	"github.com/benfb/oaamonitor/config"
 )
 
 // getS3Client creates a new S3 client from environment variables
@@ -27,76 +29,26 @@ func getS3Client() (*S3Client, error) {
 		endpoint = "https://s3.amazonaws.com"
 	}
 
-	return NewS3Client(accessKeyID, secretAccessKey, region, endpoint), nil
-}
-
-func DownloadDatabase(ctx context.Context, dbPath string) error {
-	client, err := getS3Client()
-	if err != nil {
-		log.Printf("Failed to create S3 client: %v", err)
-		return err
-	}
-
-	// Download the SQLite database file
-	resp, err := client.GetObject(ctx, "oaamonitor", "oaamonitor.db")
-	if err != nil {
-		log.Printf("Failed to download database file: %v", err)
-		return err
-	}
-	defer resp.Close()
-
-	tmpPath := dbPath + ".tmp"
-	defer os.Remove(tmpPath)
-
-	file, err := os.Create(tmpPath)
-	if err != nil {
-		log.Printf("Failed to create temporary database file: %v", err)
-		return err
-	}
-
-	if _, err = io.Copy(file, resp); err != nil {
-		file.Close()
-		log.Printf("Failed to save database file: %v", err)
-		return err
-	}
-	if err = file.Sync(); err != nil {
-		file.Close()
-		log.Printf("Failed to sync database file: %v", err)
-		return err
-	}
-	if err = file.Close(); err != nil {
-		log.Printf("Failed to close database file: %v", err)
-		return err
+	partSize := int64(config.GetEnvValue("S3_PART_SIZE_MB", DefaultPartSize>>20)) << 20
+	if partSize < MinPartSize {
+		return nil, fmt.Errorf("S3_PART_SIZE_MB must be at least %d, the smallest part S3 accepts", MinPartSize>>20)
 	}
 
-	return os.Rename(tmpPath, dbPath)
+	client := NewS3Client(accessKeyID, secretAccessKey, region, endpoint)
+	client.PartSize = partSize
+	client.Concurrency = config.GetEnvValue("S3_UPLOAD_CONCURRENCY", DefaultConcurrency)
+	client.UnsignedPayload = config.GetEnvValue("S3_UNSIGNED_PAYLOAD", false)
+	return client, nil
 }
 
-// UploadDatabase uploads the SQLite database to object storage
-func UploadDatabase(ctx context.Context, dbPath string) error {
-	client, err := getS3Client()
-	if err != nil {
-		log.Printf("Failed to create S3 client: %v", err)
-		return err
-	}
-
-	// Open the SQLite database file
-	file, err := os.Open(dbPath)
-	if err != nil {
-		log.Printf("Failed to open database file: %v", err)
-		return err
-	}
-	defer file.Close()
-
-	fileInfo, err := file.Stat()
-	if err != nil {
-		log.Printf("Failed to get file info: %v", err)
-		return err
-	}
+// DatabaseKey is the key the SQLite database was stored under, uncompressed,
+// before uploads were kept as dated snapshots.
+const DatabaseKey = "oaamonitor.db"
 
-	err = client.PutObject(ctx, "oaamonitor", "oaamonitor.db", file, fileInfo.Size())
-	if err != nil {
-		log.Printf("Failed to upload database file: %v", err)
+// UploadRawCSV uploads a compressed Baseball Savant CSV to backend under key.
+func UploadRawCSV(ctx context.Context, backend Backend, key string, reader io.ReadSeeker, size int64) error {
+	if err := backend.Put(ctx, key, reader, size, PutOptions{}); err != nil {
+		log.Printf("Failed to upload raw CSV: %v", err)
 		return err
 	}
 
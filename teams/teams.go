@@ -0,0 +1,209 @@
+// Package teams is the registry of MLB clubs: their IDs, names, abbreviations,
+// leagues, colors and every name Baseball Savant has used for them.
+package teams
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+//go:embed teams.json
+var embeddedRegistry []byte
+
+// Colors are a club's primary and secondary colors as CSS hex values.
+type Colors struct {
+	Primary   string `json:"primary"`
+	Secondary string `json:"secondary"`
+}
+
+// Team is one era of a club. A club that was renamed or relocated has one entry
+// per era, all sharing its MLBAM ID. ValidFrom and ValidTo are inclusive seasons;
+// zero leaves that end open.
+type Team struct {
+	ID           int      `json:"id"`
+	Abbreviation string   `json:"abbreviation"`
+	Name         string   `json:"name"`
+	ShortName    string   `json:"short_name"`
+	League       string   `json:"league"`
+	Division     string   `json:"division"`
+	Colors       Colors   `json:"colors"`
+	ValidFrom    int      `json:"valid_from,omitempty"`
+	ValidTo      int      `json:"valid_to,omitempty"`
+	Aliases      []string `json:"aliases,omitempty"`
+}
+
+// ActiveIn reports whether this era covers season.
+func (t Team) ActiveIn(season int) bool {
+	return (t.ValidFrom == 0 || season >= t.ValidFrom) && (t.ValidTo == 0 || season <= t.ValidTo)
+}
+
+// Registry looks teams up by ID or by any of their names.
+type Registry struct {
+	teams  []Team
+	byID   map[int][]int
+	byName map[string]int
+}
+
+var (
+	defaultOnce     sync.Once
+	defaultRegistry *Registry
+)
+
+// Default returns the registry embedded in the binary.
+func Default() *Registry {
+	defaultOnce.Do(func() {
+		registry, err := Parse(strings.NewReader(string(embeddedRegistry)))
+		if err != nil {
+			panic(fmt.Sprintf("teams: invalid embedded registry: %v", err))
+		}
+		defaultRegistry = registry
+	})
+	return defaultRegistry
+}
+
+// Load reads a registry from a JSON file that replaces the embedded one. An
+// empty path returns Default.
+func Load(path string) (*Registry, error) {
+	if path == "" {
+		return Default(), nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open team registry: %v", err)
+	}
+	defer file.Close()
+
+	registry, err := Parse(file)
+	if err != nil {
+		return nil, fmt.Errorf("invalid team registry %s: %v", path, err)
+	}
+	return registry, nil
+}
+
+// Parse reads a registry from a JSON array of teams.
+func Parse(r io.Reader) (*Registry, error) {
+	var teams []Team
+	if err := json.NewDecoder(r).Decode(&teams); err != nil {
+		return nil, err
+	}
+
+	registry := &Registry{
+		teams:  teams,
+		byID:   make(map[int][]int),
+		byName: make(map[string]int),
+	}
+	for i, team := range teams {
+		if team.ID <= 0 || team.Abbreviation == "" || team.ShortName == "" {
+			return nil, fmt.Errorf("team %d (%q) needs an id, abbreviation and short_name", i, team.Name)
+		}
+		if team.ValidFrom != 0 && team.ValidTo != 0 && team.ValidFrom > team.ValidTo {
+			return nil, fmt.Errorf("team %d (%s) is valid from %d to %d", team.ID, team.ShortName, team.ValidFrom, team.ValidTo)
+		}
+		registry.byID[team.ID] = append(registry.byID[team.ID], i)
+
+		names := append([]string{team.Abbreviation, team.Name, team.ShortName}, team.Aliases...)
+		for _, name := range names {
+			key := nameKey(name)
+			if key == "" {
+				continue
+			}
+			if other, ok := registry.byName[key]; ok && teams[other].ID != team.ID {
+				return nil, fmt.Errorf("%q names both team %d and team %d", name, teams[other].ID, team.ID)
+			}
+			if _, ok := registry.byName[key]; !ok {
+				registry.byName[key] = i
+			}
+		}
+	}
+	return registry, nil
+}
+
+// Resolve finds the team a leaderboard name refers to in season. Names are
+// matched ignoring case, spacing and punctuation, so "Blue Jays", "blue+jays"
+// and "bluejays" are the same club.
+func (r *Registry) Resolve(name string, season int) (Team, bool) {
+	i, ok := r.byName[nameKey(name)]
+	if !ok {
+		return Team{}, false
+	}
+	return r.Team(r.teams[i].ID, season)
+}
+
+// Team returns the era of a club that covers season, falling back to its most
+// recent era.
+func (r *Registry) Team(id, season int) (Team, bool) {
+	for _, i := range r.byID[id] {
+		if r.teams[i].ActiveIn(season) {
+			return r.teams[i], true
+		}
+	}
+	return r.Current(id)
+}
+
+// Current returns the most recent era of a club.
+func (r *Registry) Current(id int) (Team, bool) {
+	eras := r.byID[id]
+	if len(eras) == 0 {
+		return Team{}, false
+	}
+	current := r.teams[eras[0]]
+	for _, i := range eras[1:] {
+		if team := r.teams[i]; current.ValidTo != 0 && (team.ValidTo == 0 || team.ValidTo > current.ValidTo) {
+			current = team
+		}
+	}
+	return current, true
+}
+
+// Teams returns the current era of every club, ordered by short name.
+func (r *Registry) Teams() []Team {
+	teams := make([]Team, 0, len(r.byID))
+	for id := range r.byID {
+		team, _ := r.Current(id)
+		teams = append(teams, team)
+	}
+	sort.Slice(teams, func(i, j int) bool { return teams[i].ShortName < teams[j].ShortName })
+	return teams
+}
+
+// Slug returns the stable URL path segment for a club, taken from the short name
+// of its current era, or "" for an unknown ID.
+func (r *Registry) Slug(id int) string {
+	team, ok := r.Current(id)
+	if !ok {
+		return ""
+	}
+	return slugify(team.ShortName)
+}
+
+func slugify(name string) string {
+	slug := strings.ToLower(name)
+	slug = strings.ReplaceAll(slug, "'", "")
+	slug = strings.ReplaceAll(slug, ".", "")
+	slug = strings.ReplaceAll(slug, "&", "and")
+	slug = strings.ReplaceAll(slug, "+", "-")
+	slug = strings.ReplaceAll(slug, " ", "-")
+	for strings.Contains(slug, "--") {
+		slug = strings.ReplaceAll(slug, "--", "-")
+	}
+	return strings.Trim(slug, "-")
+}
+
+// nameKey folds a team name to lowercase letters and digits.
+func nameKey(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
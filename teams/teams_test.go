@@ -0,0 +1,93 @@
+package teams
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	registry := Default()
+
+	tests := []struct {
+		name         string
+		season       int
+		id           int
+		abbreviation string
+	}{
+		{"Blue Jays", 2024, 141, "TOR"},
+		{"blue+jays", 2024, 141, "TOR"},
+		{"Diamondbacks", 2024, 109, "ARI"},
+		{"D-backs", 2024, 109, "ARI"},
+		{"Athletics", 2024, 133, "OAK"},
+		{"Athletics", 2025, 133, "ATH"},
+		{"Indians", 2019, 114, "CLE"},
+		{"Indians", 2023, 114, "CLE"},
+	}
+	for _, tt := range tests {
+		team, ok := registry.Resolve(tt.name, tt.season)
+		if !ok || team.ID != tt.id || team.Abbreviation != tt.abbreviation {
+			t.Errorf("Resolve(%q, %d) = %+v, %v; expected team %d (%s)", tt.name, tt.season, team, ok, tt.id, tt.abbreviation)
+		}
+	}
+
+	if _, ok := registry.Resolve("Expos", 2024); ok {
+		t.Error("expected an unknown team not to resolve")
+	}
+}
+
+func TestEras(t *testing.T) {
+	registry := Default()
+
+	indians, _ := registry.Team(114, 2021)
+	guardians, _ := registry.Team(114, 2022)
+	if indians.ShortName != "Indians" || guardians.ShortName != "Guardians" {
+		t.Errorf("expected Indians through 2021 and Guardians from 2022, got %q and %q", indians.ShortName, guardians.ShortName)
+	}
+
+	current, _ := registry.Current(133)
+	if current.Abbreviation != "ATH" {
+		t.Errorf("expected the Athletics' current abbreviation to be ATH, got %q", current.Abbreviation)
+	}
+
+	if slug := registry.Slug(114); slug != "guardians" {
+		t.Errorf("expected slug guardians, got %q", slug)
+	}
+	if slug := registry.Slug(109); slug != "d-backs" {
+		t.Errorf("expected slug d-backs, got %q", slug)
+	}
+
+	if got := len(registry.Teams()); got != 30 {
+		t.Errorf("expected 30 current teams, got %d", got)
+	}
+}
+
+func TestLoadOverride(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "teams.json")
+	content := `[{"id": 1, "abbreviation": "XPO", "name": "Montreal Expos", "short_name": "Expos", "league": "NL", "division": "East"}]`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	registry, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if team, ok := registry.Resolve("expos", 1994); !ok || team.ID != 1 {
+		t.Errorf("expected the override to resolve Expos, got %+v, %v", team, ok)
+	}
+	if _, ok := registry.Resolve("Yankees", 1994); ok {
+		t.Error("expected the override to replace the embedded registry")
+	}
+}
+
+func TestParseRejectsAmbiguousNames(t *testing.T) {
+	_, err := Parse(strings.NewReader(`[
+		{"id": 1, "abbreviation": "AAA", "short_name": "Sox"},
+		{"id": 2, "abbreviation": "BBB", "short_name": "Sox"}
+	]`))
+	if err == nil || !strings.Contains(err.Error(), "names both team") {
+		t.Fatalf("expected an ambiguous name error, got %v", err)
+	}
+}